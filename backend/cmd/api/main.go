@@ -4,7 +4,10 @@ import (
 	"log"
 	"os"
 	"os/signal"
+	"strconv"
+	"strings"
 	"syscall"
+	"time"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/middleware/cors"
@@ -12,8 +15,10 @@ import (
 
 	"github.com/dcorreal/coordinador/internal/database"
 	"github.com/dcorreal/coordinador/internal/handlers"
+	"github.com/dcorreal/coordinador/internal/models"
 	"github.com/dcorreal/coordinador/internal/repositories"
 	"github.com/dcorreal/coordinador/internal/services"
+	"github.com/dcorreal/coordinador/internal/shared"
 )
 
 func main() {
@@ -28,13 +33,52 @@ func main() {
 	// Dependency injection: Repository -> Service -> Handler
 	studentRepo := repositories.NewStudentRepository(db)
 	catalogRepo := repositories.NewCatalogRepository(db)
-	studentService := services.NewStudentService(studentRepo)
-	studentImportService := services.NewStudentImportService(studentService, studentRepo, catalogRepo)
-	studentHandler := handlers.NewStudentHandler(studentService, studentImportService)
+	enrollmentRepo := repositories.NewEnrollmentRepository(db)
+	// Accepted date formats, e.g. DATE_FORMATS="YYYY-MM-DD,DD/MM/YYYY"
+	dateParser, err := shared.NewDateParser(strings.Split(getEnv("DATE_FORMATS", ""), ",")...)
+	if err != nil {
+		log.Fatalf("Invalid DATE_FORMATS: %v", err)
+	}
+
+	// Catalogs whose unknown names are reported instead of created, e.g. CATALOG_NO_AUTO_CREATE="countries,cities"
+	resolverOpts := services.DefaultCatalogResolverOptions()
+	for _, name := range strings.Split(getEnv("CATALOG_NO_AUTO_CREATE", ""), ",") {
+		if strings.TrimSpace(name) == "" {
+			continue
+		}
+		catalog, err := models.ParseCatalog(name)
+		if err != nil {
+			log.Fatalf("Invalid CATALOG_NO_AUTO_CREATE: %v", err)
+		}
+		resolverOpts.NoAutoCreate = append(resolverOpts.NoAutoCreate, catalog)
+	}
+	// How long resolved catalog names are cached, e.g. CATALOG_CACHE_TTL=10m; 0 until invalidated
+	if ttl := getEnv("CATALOG_CACHE_TTL", ""); ttl != "" {
+		resolverOpts.CacheTTL, err = time.ParseDuration(ttl)
+		if err != nil || resolverOpts.CacheTTL < 0 {
+			log.Fatalf("Invalid CATALOG_CACHE_TTL: %q", ttl)
+		}
+	}
+	catalogResolver := services.NewCatalogResolver(catalogRepo, resolverOpts)
+
+	studentService := services.NewStudentService(studentRepo, dateParser)
+	studentImportService := services.NewStudentImportService(studentService, studentRepo, catalogRepo, enrollmentRepo, catalogResolver, dateParser)
+	studentExportService := services.NewStudentExportService(studentRepo)
+	studentHandler := handlers.NewStudentHandler(studentService, studentImportService, studentExportService)
+	catalogService := services.NewCatalogService(catalogRepo, catalogResolver)
+	catalogHandler := handlers.NewCatalogHandler(catalogService)
 
 	// Fiber app
+	// Large import files are streamed instead of buffered whole in memory
+	maxUploadMB, err := strconv.Atoi(getEnv("MAX_UPLOAD_MB", "100"))
+	if err != nil || maxUploadMB <= 0 {
+		log.Fatalf("Invalid MAX_UPLOAD_MB: %q", os.Getenv("MAX_UPLOAD_MB"))
+	}
+
 	app := fiber.New(fiber.Config{
-		AppName: "Coordinador API v0.1.0",
+		AppName:           "Coordinador API v0.1.0",
+		BodyLimit:         maxUploadMB * 1024 * 1024,
+		StreamRequestBody: true,
 	})
 
 	// Middlewares
@@ -58,6 +102,7 @@ func main() {
 	// API routes
 	api := app.Group("/api/v1")
 	studentHandler.RegisterRoutes(api)
+	catalogHandler.RegisterRoutes(api)
 
 	// Graceful shutdown
 	quit := make(chan os.Signal, 1)
@@ -55,6 +55,9 @@ func Connect(cfg Config) (*pgxpool.Pool, error) {
 	poolConfig.MinConns = 2
 	poolConfig.MaxConnLifetime = 30 * time.Minute
 	poolConfig.MaxConnIdleTime = 5 * time.Minute
+	// Calendar dates are handled as UTC in Go (shared.Today, DateParser), so
+	// CURRENT_DATE and date casts use the same day
+	poolConfig.ConnConfig.RuntimeParams["timezone"] = "UTC"
 
 	pool, err := pgxpool.NewWithConfig(ctx, poolConfig)
 	if err != nil {
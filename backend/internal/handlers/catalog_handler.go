@@ -0,0 +1,264 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+
+	"github.com/dcorreal/coordinador/internal/models"
+	"github.com/dcorreal/coordinador/internal/repositories"
+	"github.com/dcorreal/coordinador/internal/services"
+	"github.com/dcorreal/coordinador/internal/shared"
+)
+
+// maxCatalogPageSize caps the limit of catalog listings.
+const maxCatalogPageSize = 100
+
+// CatalogHandler handles HTTP requests for catalog endpoints.
+type CatalogHandler struct {
+	catalogService services.CatalogService
+}
+
+// NewCatalogHandler creates a new CatalogHandler.
+func NewCatalogHandler(catalogService services.CatalogService) *CatalogHandler {
+	return &CatalogHandler{catalogService: catalogService}
+}
+
+// RegisterRoutes registers all catalog routes on the given router group.
+func (h *CatalogHandler) RegisterRoutes(router fiber.Router) {
+	catalogs := router.Group("/catalogs/:catalog")
+
+	catalogs.Get("/", h.ListEntries)
+	catalogs.Get("/aliases", h.ListAliases)
+	catalogs.Post("/aliases", h.CreateAlias)
+	catalogs.Delete("/aliases/:id", h.DeleteAlias)
+	catalogs.Post("/merge", h.MergeEntries)
+	catalogs.Get("/:id", h.GetEntry)
+	catalogs.Put("/:id", h.UpdateEntry)
+	catalogs.Delete("/:id", h.DeleteEntry)
+}
+
+// ListEntries handles GET /api/v1/catalogs/:catalog
+//
+// Supports search, limit (1 to 100, default 20) and offset, plus country_id for cities and universities
+// and city_id for universities. With usage=true each entry includes its usage counts.
+func (h *CatalogHandler) ListEntries(c *fiber.Ctx) error {
+	catalog, err := models.ParseCatalog(c.Params("catalog"))
+	if err != nil {
+		return shared.ErrorResponse(c, fiber.StatusNotFound, "Catalog not found", err)
+	}
+
+	filters := repositories.CatalogFilters{}
+
+	if search := c.Query("search"); search != "" {
+		filters.Search = &search
+	}
+	if countryID := c.Query("country_id"); countryID != "" {
+		parsed, err := uuid.Parse(countryID)
+		if err != nil {
+			return shared.ErrorResponse(c, fiber.StatusBadRequest, "Invalid country ID", err)
+		}
+		filters.CountryID = &parsed
+	}
+	if cityID := c.Query("city_id"); cityID != "" {
+		parsed, err := uuid.Parse(cityID)
+		if err != nil {
+			return shared.ErrorResponse(c, fiber.StatusBadRequest, "Invalid city ID", err)
+		}
+		filters.CityID = &parsed
+	}
+
+	limit, err := strconv.Atoi(c.Query("limit", "20"))
+	if err != nil {
+		return shared.ErrorResponse(c, fiber.StatusBadRequest, "Invalid limit", err)
+	}
+	offset, err := strconv.Atoi(c.Query("offset", "0"))
+	if err != nil {
+		return shared.ErrorResponse(c, fiber.StatusBadRequest, "Invalid offset", err)
+	}
+	if offset < 0 {
+		return shared.ErrorResponse(c, fiber.StatusBadRequest, "Invalid offset", fmt.Errorf("offset must not be negative"))
+	}
+	// Selectors page through entries, so a request can't fetch a whole catalog
+	limit = min(max(limit, 1), maxCatalogPageSize)
+	filters.Limit = limit
+	filters.Offset = offset
+
+	withUsage := c.QueryBool("usage")
+
+	entries, total, err := h.catalogService.ListEntries(c.Context(), catalog, filters, withUsage)
+	if err != nil {
+		return shared.ErrorResponse(c, fiber.StatusBadRequest, "Failed to list catalog", err)
+	}
+
+	return shared.PaginatedResponse(c, fiber.StatusOK, "Catalog retrieved successfully", entries, total, limit, offset)
+}
+
+// GetEntry handles GET /api/v1/catalogs/:catalog/:id
+func (h *CatalogHandler) GetEntry(c *fiber.Ctx) error {
+	catalog, err := models.ParseCatalog(c.Params("catalog"))
+	if err != nil {
+		return shared.ErrorResponse(c, fiber.StatusNotFound, "Catalog not found", err)
+	}
+
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return shared.ErrorResponse(c, fiber.StatusBadRequest, "Invalid entry ID", err)
+	}
+
+	entry, err := h.catalogService.GetEntry(c.Context(), catalog, id)
+	if err != nil {
+		return shared.ErrorResponse(c, fiber.StatusNotFound, "Entry not found", err)
+	}
+
+	return shared.SuccessResponse(c, fiber.StatusOK, "Entry retrieved successfully", entry)
+}
+
+// ListAliases handles GET /api/v1/catalogs/:catalog/aliases
+func (h *CatalogHandler) ListAliases(c *fiber.Ctx) error {
+	catalog, err := models.ParseCatalog(c.Params("catalog"))
+	if err != nil {
+		return shared.ErrorResponse(c, fiber.StatusNotFound, "Catalog not found", err)
+	}
+
+	var entryID *uuid.UUID
+	if raw := c.Query("entry_id"); raw != "" {
+		parsed, err := uuid.Parse(raw)
+		if err != nil {
+			return shared.ErrorResponse(c, fiber.StatusBadRequest, "Invalid entry ID", err)
+		}
+		entryID = &parsed
+	}
+
+	aliases, err := h.catalogService.ListAliases(c.Context(), catalog, entryID)
+	if err != nil {
+		return shared.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to list aliases", err)
+	}
+
+	return shared.SuccessResponse(c, fiber.StatusOK, "Aliases retrieved successfully", aliases)
+}
+
+// CreateAlias handles POST /api/v1/catalogs/:catalog/aliases
+func (h *CatalogHandler) CreateAlias(c *fiber.Ctx) error {
+	catalog, err := models.ParseCatalog(c.Params("catalog"))
+	if err != nil {
+		return shared.ErrorResponse(c, fiber.StatusNotFound, "Catalog not found", err)
+	}
+
+	var req models.CreateCatalogAliasRequest
+	if err := c.BodyParser(&req); err != nil {
+		return shared.ErrorResponse(c, fiber.StatusBadRequest, "Invalid request body", err)
+	}
+
+	alias, err := h.catalogService.CreateAlias(c.Context(), catalog, &req)
+	if err != nil {
+		return shared.ErrorResponse(c, fiber.StatusBadRequest, "Failed to create alias", err)
+	}
+
+	return shared.SuccessResponse(c, fiber.StatusCreated, "Alias created successfully", alias)
+}
+
+// DeleteAlias handles DELETE /api/v1/catalogs/:catalog/aliases/:id
+func (h *CatalogHandler) DeleteAlias(c *fiber.Ctx) error {
+	catalog, err := models.ParseCatalog(c.Params("catalog"))
+	if err != nil {
+		return shared.ErrorResponse(c, fiber.StatusNotFound, "Catalog not found", err)
+	}
+
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return shared.ErrorResponse(c, fiber.StatusBadRequest, "Invalid alias ID", err)
+	}
+
+	if err := h.catalogService.DeleteAlias(c.Context(), catalog, id); err != nil {
+		return shared.ErrorResponse(c, fiber.StatusNotFound, "Failed to delete alias", err)
+	}
+
+	return shared.SuccessResponse(c, fiber.StatusOK, "Alias deleted successfully", nil)
+}
+
+// MergeEntries handles POST /api/v1/catalogs/:catalog/merge
+//
+// Merges duplicate entries into a canonical one: references are re-pointed, the
+// duplicate names become aliases and the duplicates are deleted.
+func (h *CatalogHandler) MergeEntries(c *fiber.Ctx) error {
+	catalog, err := models.ParseCatalog(c.Params("catalog"))
+	if err != nil {
+		return shared.ErrorResponse(c, fiber.StatusNotFound, "Catalog not found", err)
+	}
+
+	var req models.MergeCatalogEntriesRequest
+	if err := c.BodyParser(&req); err != nil {
+		return shared.ErrorResponse(c, fiber.StatusBadRequest, "Invalid request body", err)
+	}
+
+	result, err := h.catalogService.MergeEntries(c.Context(), catalog, &req)
+	if err != nil {
+		return shared.ErrorResponse(c, fiber.StatusBadRequest, "Failed to merge entries", err)
+	}
+
+	return shared.SuccessResponse(c, fiber.StatusOK, "Entries merged successfully", result)
+}
+
+// UpdateEntry handles PUT /api/v1/catalogs/:catalog/:id
+func (h *CatalogHandler) UpdateEntry(c *fiber.Ctx) error {
+	catalog, err := models.ParseCatalog(c.Params("catalog"))
+	if err != nil {
+		return shared.ErrorResponse(c, fiber.StatusNotFound, "Catalog not found", err)
+	}
+
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return shared.ErrorResponse(c, fiber.StatusBadRequest, "Invalid entry ID", err)
+	}
+
+	var req models.UpdateCatalogEntryRequest
+	if err := c.BodyParser(&req); err != nil {
+		return shared.ErrorResponse(c, fiber.StatusBadRequest, "Invalid request body", err)
+	}
+
+	entry, err := h.catalogService.UpdateEntry(c.Context(), catalog, id, &req)
+	if err != nil {
+		return shared.ErrorResponse(c, fiber.StatusBadRequest, "Failed to update entry", err)
+	}
+
+	return shared.SuccessResponse(c, fiber.StatusOK, "Entry updated successfully", entry)
+}
+
+// DeleteEntry handles DELETE /api/v1/catalogs/:catalog/:id
+//
+// An entry in use is only deleted with reassign_to, the ID of the entry that takes
+// over its references.
+func (h *CatalogHandler) DeleteEntry(c *fiber.Ctx) error {
+	catalog, err := models.ParseCatalog(c.Params("catalog"))
+	if err != nil {
+		return shared.ErrorResponse(c, fiber.StatusNotFound, "Catalog not found", err)
+	}
+
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return shared.ErrorResponse(c, fiber.StatusBadRequest, "Invalid entry ID", err)
+	}
+
+	var reassignTo *uuid.UUID
+	if raw := c.Query("reassign_to"); raw != "" {
+		parsed, err := uuid.Parse(raw)
+		if err != nil {
+			return shared.ErrorResponse(c, fiber.StatusBadRequest, "Invalid reassign_to ID", err)
+		}
+		reassignTo = &parsed
+	}
+
+	if err := h.catalogService.DeleteEntry(c.Context(), catalog, id, reassignTo); err != nil {
+		var inUse *services.CatalogEntryInUseError
+		if errors.As(err, &inUse) {
+			return shared.ErrorResponse(c, fiber.StatusConflict, "Entry is in use", err)
+		}
+		return shared.ErrorResponse(c, fiber.StatusBadRequest, "Failed to delete entry", err)
+	}
+
+	return shared.SuccessResponse(c, fiber.StatusOK, "Entry deleted successfully", nil)
+}
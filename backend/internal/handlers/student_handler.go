@@ -1,11 +1,18 @@
 package handlers
 
 import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"log"
+	"mime/multipart"
 	"path/filepath"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/google/uuid"
@@ -16,17 +23,22 @@ import (
 	"github.com/dcorreal/coordinador/internal/shared"
 )
 
+// maxStudentPageSize caps the limit of student listings.
+const maxStudentPageSize = 100
+
 // StudentHandler handles HTTP requests for student endpoints.
 type StudentHandler struct {
 	studentService       services.StudentService
 	studentImportService services.StudentImportService
+	studentExportService services.StudentExportService
 }
 
 // NewStudentHandler creates a new StudentHandler.
-func NewStudentHandler(studentService services.StudentService, studentImportService services.StudentImportService) *StudentHandler {
+func NewStudentHandler(studentService services.StudentService, studentImportService services.StudentImportService, studentExportService services.StudentExportService) *StudentHandler {
 	return &StudentHandler{
 		studentService:       studentService,
 		studentImportService: studentImportService,
+		studentExportService: studentExportService,
 	}
 }
 
@@ -36,10 +48,16 @@ func (h *StudentHandler) RegisterRoutes(router fiber.Router) {
 
 	students.Post("/", h.CreateStudent)
 	students.Post("/import", h.ImportStudents)
+	students.Post("/import/sheets", h.ListImportSheets)
+	students.Post("/import/error-report", h.ImportErrorReport)
 	students.Get("/", h.ListStudents)
+	students.Get("/import/template", h.ImportTemplate)
+	students.Get("/export", h.ExportStudents)
 	students.Get("/:id", h.GetStudent)
 	students.Put("/:id", h.UpdateStudent)
 	students.Delete("/:id", h.DeleteStudent)
+	students.Post("/:id/status", h.ChangeStudentStatus)
+	students.Get("/:id/status-history", h.ListStatusHistory)
 }
 
 // CreateStudent handles POST /api/v1/students
@@ -61,13 +79,21 @@ func (h *StudentHandler) CreateStudent(c *fiber.Ctx) error {
 }
 
 // GetStudent handles GET /api/v1/students/:id
+//
+// expand=catalogs,enrollments embeds the referenced catalog entries and the
+// enrollment summary.
 func (h *StudentHandler) GetStudent(c *fiber.Ctx) error {
 	id, err := uuid.Parse(c.Params("id"))
 	if err != nil {
 		return shared.ErrorResponse(c, fiber.StatusBadRequest, "Invalid student ID", err)
 	}
 
-	student, err := h.studentService.GetStudent(c.Context(), id)
+	expand, err := services.ParseStudentExpand(c.Query("expand"))
+	if err != nil {
+		return shared.ErrorResponse(c, fiber.StatusBadRequest, "Invalid expand", err)
+	}
+
+	student, err := h.studentService.GetStudent(c.Context(), id, expand)
 	if err != nil {
 		return shared.ErrorResponse(c, fiber.StatusNotFound, "Student not found", err)
 	}
@@ -76,36 +102,61 @@ func (h *StudentHandler) GetStudent(c *fiber.Ctx) error {
 }
 
 // ListStudents handles GET /api/v1/students
+//
+// Filters are described in studentFilters. Pages are followed with cursor, the
+// next_cursor of the previous page; offset is still accepted. include_total
+// controls whether the total is counted.
 func (h *StudentHandler) ListStudents(c *fiber.Ctx) error {
-	filters := repositories.StudentFilters{}
+	filters, err := studentFilters(c)
+	if err != nil {
+		return shared.ErrorResponse(c, fiber.StatusBadRequest, "Invalid filters", err)
+	}
 
-	if status := c.Query("status"); status != "" {
-		filters.Status = &status
+	limit, err := strconv.Atoi(c.Query("limit", "20"))
+	if err != nil {
+		return shared.ErrorResponse(c, fiber.StatusBadRequest, "Invalid limit", err)
 	}
-	if cohort := c.Query("cohort"); cohort != "" {
-		filters.Cohort = &cohort
+	offset, err := strconv.Atoi(c.Query("offset", "0"))
+	if err != nil {
+		return shared.ErrorResponse(c, fiber.StatusBadRequest, "Invalid offset", err)
 	}
-	if search := c.Query("search"); search != "" {
-		filters.Search = &search
+	if offset < 0 {
+		return shared.ErrorResponse(c, fiber.StatusBadRequest, "Invalid offset", fmt.Errorf("offset must not be negative"))
 	}
-	if countryID := c.Query("residence_country_id"); countryID != "" {
-		parsed, err := uuid.Parse(countryID)
-		if err == nil {
-			filters.ResidenceCountryID = &parsed
+	// Pages are bounded, so a request can't fetch every student at once
+	limit = min(max(limit, 1), maxStudentPageSize)
+	filters.Limit = limit
+	filters.Offset = offset
+
+	cursor := c.Query("cursor")
+	if cursor != "" {
+		after, err := repositories.DecodeStudentCursor(cursor)
+		if err != nil {
+			return shared.ErrorResponse(c, fiber.StatusBadRequest, "Invalid cursor", err)
 		}
+		filters.After = after
+		filters.Offset = 0
+		offset = 0
+	}
+	if err := filters.Validate(); err != nil {
+		return shared.ErrorResponse(c, fiber.StatusBadRequest, "Invalid filters", err)
 	}
 
-	limit, _ := strconv.Atoi(c.Query("limit", "20"))
-	offset, _ := strconv.Atoi(c.Query("offset", "0"))
-	filters.Limit = limit
-	filters.Offset = offset
+	// The total is counted for the first page and skipped when following a cursor
+	withTotal := c.QueryBool("include_total", cursor == "")
 
-	students, total, err := h.studentService.ListStudents(c.Context(), filters)
+	page, err := h.studentService.ListStudents(c.Context(), filters, withTotal)
 	if err != nil {
 		return shared.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to list students", err)
 	}
 
-	return shared.PaginatedResponse(c, fiber.StatusOK, "Students retrieved successfully", students, total, limit, offset)
+	return shared.SuccessResponse(c, fiber.StatusOK, "Students retrieved successfully", shared.PaginatedData{
+		Items:      page.Students,
+		Total:      page.Total,
+		Limit:      limit,
+		Offset:     offset,
+		NextCursor: page.NextCursor,
+	})
 }
 
 // UpdateStudent handles PUT /api/v1/students/:id
@@ -148,43 +199,371 @@ func (h *StudentHandler) DeleteStudent(c *fiber.Ctx) error {
 	return shared.SuccessResponse(c, fiber.StatusOK, "Student deleted successfully", nil)
 }
 
+// ChangeStudentStatus handles POST /api/v1/students/:id/status
+//
+// Moves the student to another status with an optional reason and effective
+// date, and records the change in the status history.
+func (h *StudentHandler) ChangeStudentStatus(c *fiber.Ctx) error {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return shared.ErrorResponse(c, fiber.StatusBadRequest, "Invalid student ID", err)
+	}
+
+	var req models.ChangeStudentStatusRequest
+	if err := c.BodyParser(&req); err != nil {
+		return shared.ErrorResponse(c, fiber.StatusBadRequest, "Invalid request body", err)
+	}
+
+	// TODO: Get authenticated user from context once auth is implemented
+	var changedBy *uuid.UUID // nil until auth is implemented
+
+	student, err := h.studentService.ChangeStudentStatus(c.Context(), id, &req, changedBy)
+	if err != nil {
+		var transition *services.StudentStatusTransitionError
+		if errors.As(err, &transition) {
+			return shared.ErrorResponse(c, fiber.StatusConflict, "Status change not allowed", err)
+		}
+		return shared.ErrorResponse(c, fiber.StatusBadRequest, "Failed to change student status", err)
+	}
+
+	return shared.SuccessResponse(c, fiber.StatusOK, "Student status changed successfully", student)
+}
+
+// ListStatusHistory handles GET /api/v1/students/:id/status-history
+func (h *StudentHandler) ListStatusHistory(c *fiber.Ctx) error {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return shared.ErrorResponse(c, fiber.StatusBadRequest, "Invalid student ID", err)
+	}
+
+	history, err := h.studentService.ListStatusHistory(c.Context(), id)
+	if err != nil {
+		return shared.ErrorResponse(c, fiber.StatusNotFound, "Student not found", err)
+	}
+
+	return shared.SuccessResponse(c, fiber.StatusOK, "Status history retrieved successfully", history)
+}
+
 // ImportStudents handles POST /api/v1/students/import
+//
+// CSV files are streamed, so their size is only bounded by MAX_UPLOAD_MB. XLSX
+// workbooks are read into memory whole and are capped at services.MaxXLSXImportSize.
 func (h *StudentHandler) ImportStudents(c *fiber.Ctx) error {
 	fileHeader, err := c.FormFile("file")
 	if err != nil {
 		return shared.ErrorResponse(c, fiber.StatusBadRequest, "File is required", fmt.Errorf("missing 'file' field in multipart form"))
 	}
 
-	ext := strings.ToLower(filepath.Ext(fileHeader.Filename))
-	var format string
-	switch ext {
-	case ".csv":
-		format = "csv"
-	case ".xlsx":
-		format = "xlsx"
-	default:
-		return shared.ErrorResponse(c, fiber.StatusBadRequest, "Unsupported file format", fmt.Errorf("expected .csv or .xlsx, got %s", ext))
+	format, err := importFormat(fileHeader.Filename)
+	if err != nil {
+		return shared.ErrorResponse(c, fiber.StatusBadRequest, "Unsupported file format", err)
+	}
+	if err := checkImportSize(fileHeader, format); err != nil {
+		return shared.ErrorResponse(c, fiber.StatusRequestEntityTooLarge, "File too large", err)
 	}
 
 	file, err := fileHeader.Open()
 	if err != nil {
-		return shared.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to open file", err)
+		return shared.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to read file", err)
 	}
 	defer file.Close()
 
-	fileData, err := io.ReadAll(file)
-	if err != nil {
-		return shared.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to read file", err)
+	// Optional sheet selection for xlsx workbooks, one "sheets" field per sheet name
+	opts := services.ImportOptions{CSV: importCSVOptions(c)}
+	if form, err := c.MultipartForm(); err == nil {
+		opts.Sheets = form.Value["sheets"]
 	}
+	// create_similar=true creates catalog names reported as likely typos on a previous attempt
+	opts.CreateSimilar, _ = strconv.ParseBool(c.FormValue("create_similar"))
 
 	// TODO: Get authenticated user from context once auth is implemented
 	var createdBy *uuid.UUID
 
-	result, err := h.studentImportService.ImportFromFile(c.Context(), fileData, format, createdBy)
+	result, err := h.studentImportService.ImportFromFile(c.Context(), file, format, opts, createdBy)
+	if err != nil && result != nil {
+		// The import stopped partway; the result tells which rows were saved
+		errMsg := err.Error()
+		return c.Status(fiber.StatusInternalServerError).JSON(shared.APIResponse{
+			Success: false,
+			Message: fmt.Sprintf("Import stopped: %d created before the error", result.Created),
+			Data:    result,
+			Error:   &errMsg,
+		})
+	}
 	if err != nil {
 		return shared.ErrorResponse(c, fiber.StatusBadRequest, "Import failed", err)
 	}
 
-	message := fmt.Sprintf("Import completed: %d created, %d errors", result.Created, len(result.Errors))
+	message := fmt.Sprintf("Import completed: %d created, %d errors, %d warnings", result.Created, len(result.Errors), len(result.Warnings))
 	return shared.SuccessResponse(c, fiber.StatusOK, message, result)
 }
+
+// ListImportSheets handles POST /api/v1/students/import/sheets
+//
+// Returns the sheets of the uploaded file with their detected kind, so the caller
+// can choose which ones to import.
+func (h *StudentHandler) ListImportSheets(c *fiber.Ctx) error {
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		return shared.ErrorResponse(c, fiber.StatusBadRequest, "File is required", fmt.Errorf("missing 'file' field in multipart form"))
+	}
+
+	format, err := importFormat(fileHeader.Filename)
+	if err != nil {
+		return shared.ErrorResponse(c, fiber.StatusBadRequest, "Unsupported file format", err)
+	}
+	if err := checkImportSize(fileHeader, format); err != nil {
+		return shared.ErrorResponse(c, fiber.StatusRequestEntityTooLarge, "File too large", err)
+	}
+
+	file, err := fileHeader.Open()
+	if err != nil {
+		return shared.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to read file", err)
+	}
+	defer file.Close()
+
+	sheets, err := h.studentImportService.ListSheets(file, format, importCSVOptions(c))
+	if err != nil {
+		return shared.ErrorResponse(c, fiber.StatusBadRequest, "Failed to read sheets", err)
+	}
+
+	return shared.SuccessResponse(c, fiber.StatusOK, "Sheets retrieved successfully", sheets)
+}
+
+// ImportErrorReport handles POST /api/v1/students/import/error-report
+//
+// Expects the original upload in the "file" field and the import errors as a
+// JSON array in the "errors" field. Responds with the failed rows only, plus an
+// "errors" column, in the same format as the uploaded file.
+func (h *StudentHandler) ImportErrorReport(c *fiber.Ctx) error {
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		return shared.ErrorResponse(c, fiber.StatusBadRequest, "File is required", fmt.Errorf("missing 'file' field in multipart form"))
+	}
+
+	format, err := importFormat(fileHeader.Filename)
+	if err != nil {
+		return shared.ErrorResponse(c, fiber.StatusBadRequest, "Unsupported file format", err)
+	}
+	if err := checkImportSize(fileHeader, format); err != nil {
+		return shared.ErrorResponse(c, fiber.StatusRequestEntityTooLarge, "File too large", err)
+	}
+
+	var rowErrors []models.ImportRowError
+	if err := json.Unmarshal([]byte(c.FormValue("errors")), &rowErrors); err != nil {
+		return shared.ErrorResponse(c, fiber.StatusBadRequest, "Invalid errors field", fmt.Errorf("expected a JSON array of import errors: %w", err))
+	}
+
+	file, err := fileHeader.Open()
+	if err != nil {
+		return shared.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to read file", err)
+	}
+	defer file.Close()
+
+	report, err := h.studentImportService.BuildErrorReport(file, format, importCSVOptions(c), rowErrors)
+	if err != nil {
+		return shared.ErrorResponse(c, fiber.StatusBadRequest, "Failed to build error report", err)
+	}
+
+	base := strings.TrimSuffix(filepath.Base(fileHeader.Filename), filepath.Ext(fileHeader.Filename))
+	contentType := contentTypeFor(format)
+	if format == "csv" {
+		// The report keeps the upload's encoding, which is not necessarily UTF-8
+		contentType = "text/csv"
+	}
+	c.Set(fiber.HeaderContentType, contentType)
+	c.Set(fiber.HeaderContentDisposition, fmt.Sprintf("attachment; filename=%q", base+"_errores."+format))
+	return c.Status(fiber.StatusOK).Send(report)
+}
+
+// ImportTemplate handles GET /api/v1/students/import/template?format=xlsx|csv
+func (h *StudentHandler) ImportTemplate(c *fiber.Ctx) error {
+	format := strings.ToLower(c.Query("format", "xlsx"))
+	if format != "csv" && format != "xlsx" {
+		return shared.ErrorResponse(c, fiber.StatusBadRequest, "Unsupported file format", fmt.Errorf("expected csv or xlsx, got %s", format))
+	}
+
+	template, err := h.studentImportService.BuildTemplate(format)
+	if err != nil {
+		return shared.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to build import template", err)
+	}
+
+	c.Set(fiber.HeaderContentType, contentTypeFor(format))
+	c.Set(fiber.HeaderContentDisposition, fmt.Sprintf("attachment; filename=%q", "plantilla_estudiantes."+format))
+	return c.Status(fiber.StatusOK).Send(template)
+}
+
+// ExportStudents handles GET /api/v1/students/export
+//
+// Accepts format (csv or xlsx, default csv) and the filters and sort of ListStudents.
+// The file is streamed as students are read, so an error midway truncates it.
+func (h *StudentHandler) ExportStudents(c *fiber.Ctx) error {
+	format := strings.ToLower(c.Query("format", "csv"))
+	if format != "csv" && format != "xlsx" {
+		return shared.ErrorResponse(c, fiber.StatusBadRequest, "Unsupported file format", fmt.Errorf("expected csv or xlsx, got %s", format))
+	}
+
+	filters, err := studentFilters(c)
+	if err == nil {
+		err = filters.Validate()
+	}
+	if err != nil {
+		return shared.ErrorResponse(c, fiber.StatusBadRequest, "Invalid filters", err)
+	}
+
+	c.Set(fiber.HeaderContentType, contentTypeFor(format))
+	c.Set(fiber.HeaderContentDisposition, fmt.Sprintf("attachment; filename=%q", "estudiantes."+format))
+	c.Status(fiber.StatusOK)
+	// The request context ends when the handler returns, before the body is
+	// written, so the export gets its own deadline
+	c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+		ctx, cancel := context.WithTimeout(context.Background(), studentExportTimeout)
+		defer cancel()
+		if err := h.studentExportService.Export(ctx, &cancelOnErrorWriter{w: w, cancel: cancel}, format, filters); err != nil {
+			log.Printf("student export: %v", err)
+		}
+	})
+	return nil
+}
+
+// studentExportTimeout bounds how long an export may keep its query open.
+const studentExportTimeout = 10 * time.Minute
+
+// cancelOnErrorWriter cancels the export when writing to the client fails, e.g.
+// after a disconnect, so the query stops instead of running to the end.
+type cancelOnErrorWriter struct {
+	w      io.Writer
+	cancel context.CancelFunc
+}
+
+func (cw *cancelOnErrorWriter) Write(p []byte) (int, error) {
+	n, err := cw.w.Write(p)
+	if err != nil {
+		cw.cancel()
+	}
+	return n, err
+}
+
+// studentFilters reads the student list filters from the query string:
+// status (comma-separated), cohort, search, gender, sort, the catalog IDs
+// nationality_country_id, residence_country_id, residence_city_id, company_id,
+// profession_id, job_title_category_id and university_id, the YYYY-MM-DD ranges
+// enrollment_from/to and graduation_from/to, and min_age/max_age.
+func studentFilters(c *fiber.Ctx) (repositories.StudentFilters, error) {
+	filters := repositories.StudentFilters{}
+
+	if status := c.Query("status"); status != "" {
+		for _, s := range strings.Split(status, ",") {
+			if s = strings.TrimSpace(s); s != "" {
+				filters.Statuses = append(filters.Statuses, s)
+			}
+		}
+	}
+	if cohort := c.Query("cohort"); cohort != "" {
+		filters.Cohort = &cohort
+	}
+	if search := strings.TrimSpace(c.Query("search")); search != "" {
+		filters.Search = &search
+	}
+	if gender := c.Query("gender"); gender != "" {
+		gender = strings.ToUpper(gender)
+		filters.Gender = &gender
+	}
+
+	// Searches are sorted by relevance unless another sort is given
+	sortParam := c.Query("sort")
+	if sortParam == "" && filters.Search != nil {
+		sortParam = repositories.StudentSortRelevance
+	}
+	sort, err := repositories.ParseStudentSort(sortParam)
+	if err != nil {
+		return filters, err
+	}
+	filters.Sort = sort
+
+	for param, dst := range map[string]**uuid.UUID{
+		"nationality_country_id": &filters.NationalityCountryID,
+		"residence_country_id":   &filters.ResidenceCountryID,
+		"residence_city_id":      &filters.ResidenceCityID,
+		"company_id":             &filters.CompanyID,
+		"profession_id":          &filters.ProfessionID,
+		"job_title_category_id":  &filters.JobTitleCategoryID,
+		"university_id":          &filters.UniversityID,
+	} {
+		if raw := c.Query(param); raw != "" {
+			parsed, err := uuid.Parse(raw)
+			if err != nil {
+				return filters, fmt.Errorf("invalid %s: %w", param, err)
+			}
+			*dst = &parsed
+		}
+	}
+
+	for param, dst := range map[string]**time.Time{
+		"enrollment_from": &filters.EnrollmentFrom,
+		"enrollment_to":   &filters.EnrollmentTo,
+		"graduation_from": &filters.GraduationFrom,
+		"graduation_to":   &filters.GraduationTo,
+	} {
+		if raw := c.Query(param); raw != "" {
+			parsed, err := time.Parse(time.DateOnly, raw)
+			if err != nil {
+				return filters, fmt.Errorf("invalid %s %q, expected YYYY-MM-DD", param, raw)
+			}
+			*dst = &parsed
+		}
+	}
+
+	for param, dst := range map[string]**int{
+		"min_age": &filters.MinAge,
+		"max_age": &filters.MaxAge,
+	} {
+		if raw := c.Query(param); raw != "" {
+			parsed, err := strconv.Atoi(raw)
+			if err != nil {
+				return filters, fmt.Errorf("invalid %s %q", param, raw)
+			}
+			*dst = &parsed
+		}
+	}
+
+	return filters, nil
+}
+
+// importFormat derives the import format from the uploaded file name.
+func importFormat(filename string) (string, error) {
+	ext := strings.ToLower(filepath.Ext(filename))
+	switch ext {
+	case ".csv":
+		return "csv", nil
+	case ".xlsx":
+		return "xlsx", nil
+	default:
+		return "", fmt.Errorf("expected .csv or .xlsx, got %s", ext)
+	}
+}
+
+// checkImportSize rejects xlsx uploads above services.MaxXLSXImportSize before
+// they are read, since excelize loads the whole workbook into memory.
+func checkImportSize(fileHeader *multipart.FileHeader, format string) error {
+	if format == "xlsx" && fileHeader.Size > services.MaxXLSXImportSize {
+		return fmt.Errorf("xlsx files are limited to %d MB, split the workbook or upload it as csv", services.MaxXLSXImportSize>>20)
+	}
+	return nil
+}
+
+// importCSVOptions reads the optional "encoding" and "delimiter" form fields that
+// override the detected dialect of CSV uploads.
+func importCSVOptions(c *fiber.Ctx) services.CSVOptions {
+	return services.CSVOptions{
+		Encoding:  c.FormValue("encoding"),
+		Delimiter: c.FormValue("delimiter"),
+	}
+}
+
+func contentTypeFor(format string) string {
+	if format == "xlsx" {
+		return "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+	}
+	return "text/csv; charset=utf-8"
+}
@@ -0,0 +1,136 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Catalog identifies one of the catalog tables resolved by name.
+type Catalog string
+
+const (
+	CatalogCountries          Catalog = "countries"
+	CatalogCities             Catalog = "cities"
+	CatalogProfessions        Catalog = "professions"
+	CatalogJobTitleCategories Catalog = "job_title_categories"
+	CatalogCompanies          Catalog = "companies"
+	CatalogUniversities       Catalog = "universities"
+)
+
+// Catalogs lists every catalog.
+var Catalogs = []Catalog{
+	CatalogCountries,
+	CatalogCities,
+	CatalogProfessions,
+	CatalogJobTitleCategories,
+	CatalogCompanies,
+	CatalogUniversities,
+}
+
+// ParseCatalog validates a catalog name such as "countries". Words may be separated
+// by hyphens as in URLs ("job-title-categories").
+func ParseCatalog(name string) (Catalog, error) {
+	name = strings.ReplaceAll(strings.ToLower(strings.TrimSpace(name)), "-", "_")
+	for _, c := range Catalogs {
+		if string(c) == name {
+			return c, nil
+		}
+	}
+	return "", fmt.Errorf("unknown catalog %q", name)
+}
+
+// Singular returns the name of one entry of the catalog, for messages.
+func (c Catalog) Singular() string {
+	switch c {
+	case CatalogCountries:
+		return "country"
+	case CatalogCities:
+		return "city"
+	case CatalogProfessions:
+		return "profession"
+	case CatalogJobTitleCategories:
+		return "job title category"
+	case CatalogCompanies:
+		return "company"
+	case CatalogUniversities:
+		return "university"
+	default:
+		return string(c)
+	}
+}
+
+// CatalogEntry is an entry of any catalog, for listings and dropdowns. Fields that
+// don't apply to the catalog are omitted.
+type CatalogEntry struct {
+	ID          uuid.UUID  `json:"id"`
+	Name        string     `json:"name"`
+	Code        *string    `json:"code,omitempty"`        // countries: ISO 3166-1 alpha-3
+	CountryID   *uuid.UUID `json:"country_id,omitempty"`  // cities and universities
+	CityID      *uuid.UUID `json:"city_id,omitempty"`     // universities
+	Description *string    `json:"description,omitempty"` // job title categories
+
+	// Usage is only filled when requested
+	Usage *CatalogUsage `json:"usage,omitempty"`
+}
+
+// CatalogUsage counts the rows that reference a catalog entry, including those of
+// deleted students.
+type CatalogUsage struct {
+	Students     int `json:"students"`
+	Cities       int `json:"cities,omitempty"`       // countries
+	Universities int `json:"universities,omitempty"` // countries and cities
+}
+
+// InUse reports whether anything references the entry.
+func (u CatalogUsage) InUse() bool {
+	return u.Students > 0 || u.Cities > 0 || u.Universities > 0
+}
+
+// UpdateCatalogEntryRequest is the payload for renaming or describing a catalog
+// entry. Omitted fields are left unchanged.
+type UpdateCatalogEntryRequest struct {
+	Name        *string `json:"name"`
+	Description *string `json:"description"` // job title categories only
+}
+
+// CatalogMatch is a catalog entry whose name is similar to a looked-up name.
+type CatalogMatch struct {
+	ID         uuid.UUID `json:"id"`
+	Name       string    `json:"name"`
+	Similarity float64   `json:"similarity"`
+}
+
+// CatalogAlias maps to the catalog_aliases table: an alternative name that
+// resolves to a catalog entry, e.g. "EE.UU." for Estados Unidos.
+type CatalogAlias struct {
+	ID        uuid.UUID  `json:"id" db:"id"`
+	Catalog   Catalog    `json:"catalog" db:"catalog"`
+	EntryID   uuid.UUID  `json:"entry_id" db:"entry_id"`
+	CountryID *uuid.UUID `json:"country_id,omitempty" db:"country_id"`
+	Alias     string     `json:"alias" db:"alias"`
+	CreatedAt time.Time  `json:"created_at" db:"created_at"`
+}
+
+// CreateCatalogAliasRequest is the payload for adding an alias to a catalog entry.
+type CreateCatalogAliasRequest struct {
+	EntryID string `json:"entry_id"`
+	Alias   string `json:"alias"`
+}
+
+// MergeCatalogEntriesRequest is the payload for merging duplicate entries of a catalog.
+type MergeCatalogEntriesRequest struct {
+	CanonicalID  string   `json:"canonical_id"`
+	DuplicateIDs []string `json:"duplicate_ids"`
+}
+
+// CatalogMergeResult holds the outcome of merging duplicate catalog entries.
+type CatalogMergeResult struct {
+	CanonicalID uuid.UUID `json:"canonical_id"`
+	Merged      int       `json:"merged"`
+	RowsUpdated int64     `json:"rows_updated"`
+	// Aliases holds the merged names recorded as aliases of the canonical entry
+	Aliases []string `json:"aliases"`
+}
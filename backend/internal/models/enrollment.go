@@ -0,0 +1,45 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// EnrollmentStatus represents the status of a student in a scheduled course.
+type EnrollmentStatus string
+
+const (
+	EnrollmentStatusEnrolled  EnrollmentStatus = "enrolled"
+	EnrollmentStatusCompleted EnrollmentStatus = "completed"
+	EnrollmentStatusWithdrawn EnrollmentStatus = "withdrawn"
+	EnrollmentStatusFailed    EnrollmentStatus = "failed"
+)
+
+// Enrollment maps to the enrollments table.
+type Enrollment struct {
+	ID                uuid.UUID        `json:"id" db:"id"`
+	StudentID         uuid.UUID        `json:"student_id" db:"student_id"`
+	ScheduledCourseID uuid.UUID        `json:"scheduled_course_id" db:"scheduled_course_id"`
+	EnrolledAt        time.Time        `json:"enrolled_at" db:"enrolled_at"`
+	Status            EnrollmentStatus `json:"status" db:"status"`
+	FinalGrade        *float64         `json:"final_grade,omitempty" db:"final_grade"`
+	CreditsEarned     int              `json:"credits_earned" db:"credits_earned"`
+	Notes             *string          `json:"notes,omitempty" db:"notes"`
+
+	// Auditoria
+	CreatedAt time.Time  `json:"created_at" db:"created_at"`
+	CreatedBy *uuid.UUID `json:"created_by,omitempty" db:"created_by"`
+	UpdatedAt time.Time  `json:"updated_at" db:"updated_at"`
+}
+
+// EnrollmentSummary counts a student's enrollments by status.
+type EnrollmentSummary struct {
+	Total         int      `json:"total"`
+	Enrolled      int      `json:"enrolled"`
+	Completed     int      `json:"completed"`
+	Withdrawn     int      `json:"withdrawn"`
+	Failed        int      `json:"failed"`
+	CreditsEarned int      `json:"credits_earned"`
+	AverageGrade  *float64 `json:"average_grade,omitempty"` // of graded enrollments
+}
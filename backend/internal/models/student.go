@@ -16,6 +16,27 @@ const (
 	StudentStatusSuspended StudentStatus = "suspended"
 )
 
+// StudentStatusChange maps to the student_status_history table. It records a
+// transition between two statuses.
+type StudentStatusChange struct {
+	ID            uuid.UUID     `json:"id" db:"id"`
+	StudentID     uuid.UUID     `json:"student_id" db:"student_id"`
+	FromStatus    StudentStatus `json:"from_status" db:"from_status"`
+	ToStatus      StudentStatus `json:"to_status" db:"to_status"`
+	Reason        *string       `json:"reason,omitempty" db:"reason"`
+	EffectiveDate time.Time     `json:"effective_date" db:"effective_date"`
+	CreatedAt     time.Time     `json:"created_at" db:"created_at"`
+	CreatedBy     *uuid.UUID    `json:"created_by,omitempty" db:"created_by"`
+}
+
+// ChangeStudentStatusRequest is the DTO for changing a student's status.
+// EffectiveDate defaults to today.
+type ChangeStudentStatusRequest struct {
+	Status        string  `json:"status" validate:"required,oneof=active graduated withdrawn suspended"`
+	Reason        *string `json:"reason" validate:"omitempty,max=1000"`
+	EffectiveDate string  `json:"effective_date" validate:"omitempty"`
+}
+
 // Student maps to the students table.
 type Student struct {
 	ID              uuid.UUID     `json:"id" db:"id"`
@@ -47,6 +68,16 @@ type Student struct {
 	EnrollmentDate time.Time     `json:"enrollment_date" db:"enrollment_date"`
 	GraduationDate *time.Time    `json:"graduation_date,omitempty" db:"graduation_date"`
 
+	// Universidades de procedencia
+	Universities []StudentUniversity `json:"universities,omitempty" db:"-"`
+
+	// Relevance ranks the student in a search, only set when searching
+	Relevance *float64 `json:"relevance,omitempty" db:"-"`
+
+	// Related data, only filled when requested with expand
+	Catalogs          *StudentCatalogs   `json:"catalogs,omitempty" db:"-"`
+	EnrollmentSummary *EnrollmentSummary `json:"enrollment_summary,omitempty" db:"-"`
+
 	// Auditoria
 	CreatedAt time.Time  `json:"created_at" db:"created_at"`
 	CreatedBy *uuid.UUID `json:"created_by,omitempty" db:"created_by"`
@@ -56,6 +87,35 @@ type Student struct {
 	DeletedBy *uuid.UUID `json:"deleted_by,omitempty" db:"deleted_by"`
 }
 
+// StudentCatalogs holds the catalog entries a student references, so clients
+// can show their names without looking each ID up.
+type StudentCatalogs struct {
+	Nationality      *CatalogEntry `json:"nationality,omitempty"`
+	ResidenceCountry *CatalogEntry `json:"residence_country,omitempty"`
+	ResidenceCity    *CatalogEntry `json:"residence_city,omitempty"`
+	Company          *CatalogEntry `json:"company,omitempty"`
+	Profession       *CatalogEntry `json:"profession,omitempty"`
+	JobTitleCategory *CatalogEntry `json:"job_title_category,omitempty"`
+}
+
+// StudentUniversity maps to the student_universities table.
+type StudentUniversity struct {
+	ID             uuid.UUID `json:"id" db:"id"`
+	StudentID      uuid.UUID `json:"student_id" db:"student_id"`
+	UniversityID   uuid.UUID `json:"university_id" db:"university_id"`
+	UniversityName string    `json:"university_name,omitempty" db:"university_name"`
+	DegreeObtained *string   `json:"degree_obtained,omitempty" db:"degree_obtained"`
+	GraduationYear *int      `json:"graduation_year,omitempty" db:"graduation_year"`
+	CreatedAt      time.Time `json:"created_at" db:"created_at"`
+}
+
+// StudentUniversityRequest is the DTO for a university attended by a student.
+type StudentUniversityRequest struct {
+	UniversityID   string  `json:"university_id" validate:"required,uuid"`
+	DegreeObtained *string `json:"degree_obtained" validate:"omitempty,max=255"`
+	GraduationYear *int    `json:"graduation_year" validate:"omitempty,min=1950"`
+}
+
 // CreateStudentRequest is the DTO for creating a student.
 type CreateStudentRequest struct {
 	FirstNames           string   `json:"first_names" validate:"required,min=2,max=150"`
@@ -76,6 +136,10 @@ type CreateStudentRequest struct {
 	Status               string   `json:"status" validate:"required,oneof=active graduated withdrawn suspended"`
 	Cohort               string   `json:"cohort" validate:"required,max=10"`
 	EnrollmentDate       string   `json:"enrollment_date" validate:"required"`
+	// GraduationDate is required when Status is graduated and not allowed otherwise
+	GraduationDate string `json:"graduation_date" validate:"omitempty"`
+
+	Universities []StudentUniversityRequest `json:"universities" validate:"omitempty,dive"`
 }
 
 // UpdateStudentRequest is the DTO for updating a student. All fields are optional.
@@ -91,20 +155,88 @@ type UpdateStudentRequest struct {
 	JobTitleCategoryID *string  `json:"job_title_category_id" validate:"omitempty,uuid"`
 	ProfessionID       *string  `json:"profession_id" validate:"omitempty,uuid"`
 	StudentCode        *string  `json:"student_code" validate:"omitempty,len=9"`
-	Status             *string  `json:"status" validate:"omitempty,oneof=active graduated withdrawn suspended"`
+	// Status may only repeat the current status; it is changed with ChangeStudentStatusRequest
+	Status *string `json:"status" validate:"omitempty,oneof=active graduated withdrawn suspended"`
+
+	// Universities replaces the student's universities when present; an empty list removes them all
+	Universities []StudentUniversityRequest `json:"universities" validate:"omitempty,dive"`
 }
 
 // ImportRowError describes a validation or insertion error for a single row.
+// Sheet is empty for CSV files.
 type ImportRowError struct {
+	Sheet   string `json:"sheet,omitempty"`
 	Row     int    `json:"row"`
 	Field   string `json:"field"`
 	Value   string `json:"value"`
 	Message string `json:"message"`
+	// Suggestions holds similar catalog entries when a name could not be resolved
+	Suggestions []string `json:"suggestions,omitempty"`
 }
 
 // ImportResult holds the outcome of a bulk student import.
+// TotalRows counts the data rows of every processed sheet.
+// Errors are rows that were rejected; Warnings are problems on rows that were
+// imported anyway, such as a university that could not be linked to a created student.
 type ImportResult struct {
-	TotalRows int              `json:"total_rows"`
-	Created   int              `json:"created"`
-	Errors    []ImportRowError `json:"errors"`
+	TotalRows          int              `json:"total_rows"`
+	Created            int              `json:"created"`
+	UniversitiesLinked int              `json:"universities_linked"`
+	EnrollmentsCreated int              `json:"enrollments_created"`
+	Errors             []ImportRowError `json:"errors"`
+	Warnings           []ImportRowError `json:"warnings"`
+	// Fatal is set when the import stopped partway. Rows counted as created
+	// before it stay saved.
+	Fatal *ImportRowError `json:"fatal,omitempty"`
+}
+
+// ImportSheetKind identifies what a sheet of an import workbook contains.
+type ImportSheetKind string
+
+const (
+	ImportSheetStudents     ImportSheetKind = "students"
+	ImportSheetUniversities ImportSheetKind = "universities"
+	ImportSheetEnrollments  ImportSheetKind = "enrollments"
+	ImportSheetUnknown      ImportSheetKind = "unknown"
+)
+
+// ImportSheet describes a sheet available for import.
+type ImportSheet struct {
+	Name     string          `json:"name"`
+	Kind     ImportSheetKind `json:"kind"`
+	DataRows int             `json:"data_rows"`
+	Headers  []string        `json:"headers"`
+}
+
+// StudentExportRow is a student as exported, with catalogs by name so the file
+// can be edited and imported again.
+type StudentExportRow struct {
+	FirstNames       string
+	LastNames        string
+	DocumentID       *string
+	BirthDate        *time.Time
+	Gender           *string
+	Emails           []string
+	Phones           []string
+	Nationality      *string
+	ResidenceCountry *string
+	ResidenceCity    *string
+	Company          *string
+	JobTitleCategory *string
+	Profession       *string
+	StudentCode      *string
+	Status           string
+	Cohort           string
+	EnrollmentDate   time.Time
+	GraduationDate   *time.Time
+	Universities     []StudentExportUniversity
+}
+
+// StudentExportUniversity is a university of an exported student.
+type StudentExportUniversity struct {
+	Name           string  `json:"name"`
+	City           *string `json:"city"`
+	Country        string  `json:"country"`
+	Degree         *string `json:"degree"`
+	GraduationYear *int    `json:"graduation_year"`
 }
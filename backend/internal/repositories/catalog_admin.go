@@ -0,0 +1,175 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+
+	"github.com/dcorreal/coordinador/internal/models"
+)
+
+// GetUsage counts the students, cities and universities that reference an entry.
+func (r *catalogRepository) GetUsage(ctx context.Context, catalog models.Catalog, id uuid.UUID) (*models.CatalogUsage, error) {
+	usages, err := r.GetUsages(ctx, catalog, []uuid.UUID{id})
+	if err != nil {
+		return nil, err
+	}
+	return usages[id], nil
+}
+
+// GetUsages counts the references of several entries with one grouped query
+// per referencing table. Every id gets a usage, zero when nothing references it.
+func (r *catalogRepository) GetUsages(ctx context.Context, catalog models.Catalog, ids []uuid.UUID) (map[uuid.UUID]*models.CatalogUsage, error) {
+	if _, ok := catalogTables[catalog]; !ok {
+		return nil, fmt.Errorf("unknown catalog %q", catalog)
+	}
+
+	usages := make(map[uuid.UUID]*models.CatalogUsage, len(ids))
+	for _, id := range ids {
+		usages[id] = &models.CatalogUsage{}
+	}
+	if len(ids) == 0 {
+		return usages, nil
+	}
+
+	// A student referencing an entry from several columns is counted once
+	var studentColumns []string
+	for _, ref := range catalogReferences[catalog] {
+		if ref.table == "students" {
+			studentColumns = append(studentColumns, "s."+ref.column+" = e.id")
+		}
+	}
+	studentsQuery := "SELECT e.id, COUNT(*) FROM unnest($1::uuid[]) AS e(id) JOIN students s ON " +
+		strings.Join(studentColumns, " OR ") + " GROUP BY e.id"
+	if catalog == models.CatalogUniversities {
+		studentsQuery = "SELECT university_id, COUNT(*) FROM student_universities WHERE university_id = ANY($1) GROUP BY university_id"
+	}
+	err := r.countUsages(ctx, studentsQuery, ids, usages, func(u *models.CatalogUsage, n int) { u.Students = n })
+	if err != nil {
+		return nil, fmt.Errorf("failed to count students of %s: %w", catalog, err)
+	}
+
+	switch catalog {
+	case models.CatalogCountries:
+		err = r.countUsages(ctx, "SELECT country_id, COUNT(*) FROM cities WHERE country_id = ANY($1) GROUP BY country_id",
+			ids, usages, func(u *models.CatalogUsage, n int) { u.Cities = n })
+		if err == nil {
+			err = r.countUsages(ctx, "SELECT country_id, COUNT(*) FROM universities WHERE country_id = ANY($1) GROUP BY country_id",
+				ids, usages, func(u *models.CatalogUsage, n int) { u.Universities = n })
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to count usage of countries: %w", err)
+		}
+	case models.CatalogCities:
+		err = r.countUsages(ctx, "SELECT city_id, COUNT(*) FROM universities WHERE city_id = ANY($1) GROUP BY city_id",
+			ids, usages, func(u *models.CatalogUsage, n int) { u.Universities = n })
+		if err != nil {
+			return nil, fmt.Errorf("failed to count usage of cities: %w", err)
+		}
+	}
+	return usages, nil
+}
+
+// countUsages runs a query returning (id, count) rows and stores each count with set.
+func (r *catalogRepository) countUsages(ctx context.Context, query string, ids []uuid.UUID, usages map[uuid.UUID]*models.CatalogUsage, set func(*models.CatalogUsage, int)) error {
+	rows, err := r.db.Query(ctx, query, ids)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id uuid.UUID
+		var count int
+		if err := rows.Scan(&id, &count); err != nil {
+			return err
+		}
+		if usage, ok := usages[id]; ok {
+			set(usage, count)
+		}
+	}
+	return rows.Err()
+}
+
+// UpdateEntry renames an entry and, for job title categories, sets its description.
+func (r *catalogRepository) UpdateEntry(ctx context.Context, catalog models.Catalog, id uuid.UUID, req *models.UpdateCatalogEntryRequest) error {
+	t, ok := catalogTables[catalog]
+	if !ok {
+		return fmt.Errorf("unknown catalog %q", catalog)
+	}
+
+	query := fmt.Sprintf("UPDATE %s SET updated_at = NOW()", t.table)
+	args := []interface{}{}
+	argCount := 1
+
+	if req.Name != nil {
+		query += fmt.Sprintf(", name = $%d", argCount)
+		args = append(args, *req.Name)
+		argCount++
+	}
+
+	if req.Description != nil {
+		query += fmt.Sprintf(", description = NULLIF($%d, '')", argCount)
+		args = append(args, *req.Description)
+		argCount++
+	}
+
+	query += fmt.Sprintf(" WHERE id = $%d", argCount)
+	args = append(args, id)
+
+	tag, err := r.db.Exec(ctx, query, args...)
+	if err != nil {
+		return fmt.Errorf("failed to update %s: %w", catalog.Singular(), err)
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("%s not found", catalog.Singular())
+	}
+	return nil
+}
+
+// DeleteEntry deletes an entry and its aliases. When reassignTo is given, rows
+// referencing the entry are first re-pointed to that entry, as in a merge.
+// Otherwise the foreign keys make the deletion fail if the entry is in use.
+func (r *catalogRepository) DeleteEntry(ctx context.Context, catalog models.Catalog, id uuid.UUID, reassignTo *uuid.UUID) error {
+	t, ok := catalogTables[catalog]
+	if !ok {
+		return fmt.Errorf("unknown catalog %q", catalog)
+	}
+
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if reassignTo != nil {
+		entries, err := lockCatalogEntries(ctx, tx, catalog, []uuid.UUID{*reassignTo, id})
+		if err != nil {
+			return err
+		}
+		if err := checkSameCountry(catalog, entries, *reassignTo, []uuid.UUID{id}); err != nil {
+			return err
+		}
+		if _, err := mergeCatalogEntry(ctx, tx, catalog, entries[*reassignTo], id); err != nil {
+			return err
+		}
+	} else {
+		if _, err := tx.Exec(ctx, "DELETE FROM catalog_aliases WHERE catalog = $1 AND entry_id = $2", catalog, id); err != nil {
+			return fmt.Errorf("failed to delete aliases: %w", err)
+		}
+		tag, err := tx.Exec(ctx, fmt.Sprintf("DELETE FROM %s WHERE id = $1", t.table), id)
+		if err != nil {
+			return fmt.Errorf("failed to delete %s: %w", catalog.Singular(), err)
+		}
+		if tag.RowsAffected() == 0 {
+			return fmt.Errorf("%s not found", catalog.Singular())
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit %s deletion: %w", catalog.Singular(), err)
+	}
+	return nil
+}
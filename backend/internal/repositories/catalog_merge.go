@@ -0,0 +1,311 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+
+	"github.com/dcorreal/coordinador/internal/models"
+)
+
+// catalogReference is a column that points at a catalog entry.
+type catalogReference struct {
+	table  string
+	column string
+}
+
+// catalogReferences lists, per catalog, the columns re-pointed by a plain UPDATE on
+// merge. Cities and universities of a country and universities linked to students
+// have unique constraints and are handled separately.
+var catalogReferences = map[models.Catalog][]catalogReference{
+	models.CatalogCountries: {
+		{"students", "nationality_country_id"},
+		{"students", "residence_country_id"},
+	},
+	models.CatalogCities: {
+		{"universities", "city_id"},
+		{"students", "residence_city_id"},
+	},
+	models.CatalogProfessions:        {{"students", "profession_id"}},
+	models.CatalogJobTitleCategories: {{"students", "job_title_category_id"}},
+	models.CatalogCompanies:          {{"students", "company_id"}},
+}
+
+// MergeEntries merges duplicate entries of a catalog into a canonical entry in one
+// transaction: every row referencing a duplicate is re-pointed to the canonical
+// entry, the duplicates' names are recorded as aliases of it and the duplicates
+// are deleted. When merging countries, cities and universities that exist with the
+// same name in both countries are merged as well.
+func (r *catalogRepository) MergeEntries(ctx context.Context, catalog models.Catalog, canonicalID uuid.UUID, duplicateIDs []uuid.UUID) (*models.CatalogMergeResult, error) {
+	t, ok := catalogTables[catalog]
+	if !ok {
+		return nil, fmt.Errorf("unknown catalog %q", catalog)
+	}
+
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	entries, err := lockCatalogEntries(ctx, tx, catalog, append([]uuid.UUID{canonicalID}, duplicateIDs...))
+	if err != nil {
+		return nil, err
+	}
+	canonical := entries[canonicalID]
+	if err := checkSameCountry(catalog, entries, canonicalID, duplicateIDs); err != nil {
+		return nil, err
+	}
+
+	result := &models.CatalogMergeResult{
+		CanonicalID: canonicalID,
+		Aliases:     []string{},
+	}
+	for _, duplicateID := range duplicateIDs {
+		updated, err := mergeCatalogEntry(ctx, tx, catalog, canonical, duplicateID)
+		if err != nil {
+			return nil, err
+		}
+		result.Merged++
+		result.RowsUpdated += updated
+	}
+
+	// Record the merged names so future imports resolve them to the canonical entry
+	for _, duplicateID := range duplicateIDs {
+		name := entries[duplicateID].name
+		tag, err := tx.Exec(ctx, `
+			INSERT INTO catalog_aliases (catalog, entry_id, country_id, alias)
+			SELECT $1, $2::uuid, $3::uuid, $4
+			WHERE catalog_name_key($4) <> catalog_name_key($5)
+			ON CONFLICT DO NOTHING`,
+			catalog, canonicalID, canonical.countryID, name, canonical.name,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to record alias %q: %w", name, err)
+		}
+		if tag.RowsAffected() > 0 {
+			result.Aliases = append(result.Aliases, name)
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit %s merge: %w", t.table, err)
+	}
+	return result, nil
+}
+
+// catalogEntry is the part of a catalog row that merging needs.
+type catalogEntry struct {
+	id        uuid.UUID
+	name      string
+	countryID *uuid.UUID
+}
+
+// lockCatalogEntries loads and locks the given entries, failing if any is missing.
+func lockCatalogEntries(ctx context.Context, tx pgx.Tx, catalog models.Catalog, ids []uuid.UUID) (map[uuid.UUID]catalogEntry, error) {
+	t := catalogTables[catalog]
+	countryColumn := "NULL::uuid"
+	if t.countryScope {
+		countryColumn = "country_id"
+	}
+
+	rows, err := tx.Query(ctx,
+		fmt.Sprintf("SELECT id, name, %s FROM %s WHERE id = ANY($1) FOR UPDATE", countryColumn, t.table), ids,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to lock %s: %w", t.table, err)
+	}
+	defer rows.Close()
+
+	entries := make(map[uuid.UUID]catalogEntry, len(ids))
+	for rows.Next() {
+		var e catalogEntry
+		if err := rows.Scan(&e.id, &e.name, &e.countryID); err != nil {
+			return nil, fmt.Errorf("failed to scan %s: %w", t.table, err)
+		}
+		entries[e.id] = e
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for _, id := range ids {
+		if _, ok := entries[id]; !ok {
+			return nil, fmt.Errorf("%s %s not found", catalog.Singular(), id)
+		}
+	}
+	return entries, nil
+}
+
+// checkSameCountry fails if a duplicate city or university belongs to another
+// country than the canonical entry: merging them would leave students living in
+// a city outside their residence country.
+func checkSameCountry(catalog models.Catalog, entries map[uuid.UUID]catalogEntry, canonicalID uuid.UUID, duplicateIDs []uuid.UUID) error {
+	if !catalogTables[catalog].countryScope {
+		return nil
+	}
+	canonical := entries[canonicalID]
+	for _, id := range duplicateIDs {
+		duplicate := entries[id]
+		if !sameUUID(duplicate.countryID, canonical.countryID) {
+			return fmt.Errorf("%s %q belongs to another country than %q, only entries of the same country can be merged",
+				catalog.Singular(), duplicate.name, canonical.name)
+		}
+	}
+	return nil
+}
+
+func sameUUID(a, b *uuid.UUID) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+// mergeCatalogEntry re-points everything referencing duplicateID to canonical and
+// deletes the duplicate. It returns the number of re-pointed rows.
+func mergeCatalogEntry(ctx context.Context, tx pgx.Tx, catalog models.Catalog, canonical catalogEntry, duplicateID uuid.UUID) (int64, error) {
+	var updated int64
+
+	switch catalog {
+	case models.CatalogCountries:
+		// Cities and universities are unique by name within a country
+		for _, child := range []models.Catalog{models.CatalogCities, models.CatalogUniversities} {
+			n, err := mergeCountryChildren(ctx, tx, child, canonical.id, duplicateID)
+			if err != nil {
+				return 0, err
+			}
+			updated += n
+		}
+		// Aliases of cities and universities follow them to the canonical country
+		if _, err := tx.Exec(ctx, `
+			DELETE FROM catalog_aliases a
+			WHERE a.country_id = $1 AND EXISTS (
+				SELECT 1 FROM catalog_aliases b
+				WHERE b.catalog = a.catalog AND b.country_id = $2 AND catalog_name_key(b.alias) = catalog_name_key(a.alias)
+			)`, duplicateID, canonical.id,
+		); err != nil {
+			return 0, fmt.Errorf("failed to merge catalog_aliases: %w", err)
+		}
+		if _, err := tx.Exec(ctx, "UPDATE catalog_aliases SET country_id = $1 WHERE country_id = $2", canonical.id, duplicateID); err != nil {
+			return 0, fmt.Errorf("failed to merge catalog_aliases: %w", err)
+		}
+
+	case models.CatalogUniversities:
+		// A student linked to both keeps one link, filling in degree and year from the duplicate
+		if _, err := tx.Exec(ctx, `
+			UPDATE student_universities c SET
+				degree_obtained = COALESCE(c.degree_obtained, d.degree_obtained),
+				graduation_year = COALESCE(c.graduation_year, d.graduation_year)
+			FROM student_universities d
+			WHERE c.university_id = $1 AND d.university_id = $2 AND c.student_id = d.student_id`,
+			canonical.id, duplicateID,
+		); err != nil {
+			return 0, fmt.Errorf("failed to merge student_universities: %w", err)
+		}
+		if _, err := tx.Exec(ctx, `
+			DELETE FROM student_universities d
+			WHERE d.university_id = $2 AND EXISTS (
+				SELECT 1 FROM student_universities c WHERE c.university_id = $1 AND c.student_id = d.student_id
+			)`, canonical.id, duplicateID,
+		); err != nil {
+			return 0, fmt.Errorf("failed to merge student_universities: %w", err)
+		}
+		tag, err := tx.Exec(ctx, "UPDATE student_universities SET university_id = $1 WHERE university_id = $2", canonical.id, duplicateID)
+		if err != nil {
+			return 0, fmt.Errorf("failed to merge student_universities: %w", err)
+		}
+		updated += tag.RowsAffected()
+	}
+
+	for _, ref := range catalogReferences[catalog] {
+		tag, err := tx.Exec(ctx,
+			fmt.Sprintf("UPDATE %s SET %s = $1 WHERE %s = $2", ref.table, ref.column, ref.column),
+			canonical.id, duplicateID,
+		)
+		if err != nil {
+			return 0, fmt.Errorf("failed to merge %s.%s: %w", ref.table, ref.column, err)
+		}
+		updated += tag.RowsAffected()
+	}
+
+	// Aliases of the duplicate move to the canonical entry, unless it already has them
+	if _, err := tx.Exec(ctx, `
+		DELETE FROM catalog_aliases a
+		WHERE a.catalog = $1 AND a.entry_id = $2 AND EXISTS (
+			SELECT 1 FROM catalog_aliases b
+			WHERE b.catalog = a.catalog AND b.id <> a.id AND b.country_id IS NOT DISTINCT FROM $3
+				AND catalog_name_key(b.alias) = catalog_name_key(a.alias)
+		)`, catalog, duplicateID, canonical.countryID,
+	); err != nil {
+		return 0, fmt.Errorf("failed to merge catalog_aliases: %w", err)
+	}
+	if _, err := tx.Exec(ctx,
+		"UPDATE catalog_aliases SET entry_id = $1, country_id = $2 WHERE catalog = $3 AND entry_id = $4",
+		canonical.id, canonical.countryID, catalog, duplicateID,
+	); err != nil {
+		return 0, fmt.Errorf("failed to merge catalog_aliases: %w", err)
+	}
+
+	t := catalogTables[catalog]
+	if _, err := tx.Exec(ctx, fmt.Sprintf("DELETE FROM %s WHERE id = $1", t.table), duplicateID); err != nil {
+		return 0, fmt.Errorf("failed to delete merged %s: %w", catalog.Singular(), err)
+	}
+	return updated, nil
+}
+
+// mergeCountryChildren moves the cities or universities of a duplicate country to
+// the canonical country, merging those whose name already exists there.
+func mergeCountryChildren(ctx context.Context, tx pgx.Tx, child models.Catalog, canonicalCountryID, duplicateCountryID uuid.UUID) (int64, error) {
+	t := catalogTables[child]
+
+	rows, err := tx.Query(ctx, fmt.Sprintf(`
+		SELECT DISTINCT ON (d.id) d.id, c.id, c.name
+		FROM %[1]s d
+		JOIN %[1]s c ON c.country_id = $1 AND catalog_name_key(c.name) = catalog_name_key(d.name)
+		WHERE d.country_id = $2
+		ORDER BY d.id, c.created_at`, t.table),
+		canonicalCountryID, duplicateCountryID,
+	)
+	if err != nil {
+		return 0, fmt.Errorf("failed to find %s to merge: %w", t.table, err)
+	}
+	type pair struct {
+		duplicateID uuid.UUID
+		canonical   catalogEntry
+	}
+	var pairs []pair
+	for rows.Next() {
+		var p pair
+		if err := rows.Scan(&p.duplicateID, &p.canonical.id, &p.canonical.name); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("failed to scan %s: %w", t.table, err)
+		}
+		p.canonical.countryID = &canonicalCountryID
+		pairs = append(pairs, p)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	var updated int64
+	for _, p := range pairs {
+		n, err := mergeCatalogEntry(ctx, tx, child, p.canonical, p.duplicateID)
+		if err != nil {
+			return 0, err
+		}
+		updated += n
+	}
+
+	tag, err := tx.Exec(ctx,
+		fmt.Sprintf("UPDATE %s SET country_id = $1 WHERE country_id = $2", t.table),
+		canonicalCountryID, duplicateCountryID,
+	)
+	if err != nil {
+		return 0, fmt.Errorf("failed to move %s: %w", t.table, err)
+	}
+	return updated + tag.RowsAffected(), nil
+}
@@ -8,26 +8,61 @@ import (
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/dcorreal/coordinador/internal/models"
 )
 
-// CatalogRepository provides find-or-create access to catalog tables.
+// CatalogFilters holds the filters for listing catalog entries.
+type CatalogFilters struct {
+	Search    *string    // accent-insensitive search on name (and code for countries)
+	CountryID *uuid.UUID // cities and universities only
+	CityID    *uuid.UUID // universities only
+	Limit     int
+	Offset    int
+}
+
+// CatalogRepository provides find-or-create access to catalog tables. Names are
+// matched ignoring case, accents and surrounding spaces, and FindOrCreate methods
+// are safe to call concurrently for the same name.
 type CatalogRepository interface {
+	ListEntries(ctx context.Context, catalog models.Catalog, filters CatalogFilters) ([]models.CatalogEntry, error)
+	CountEntries(ctx context.Context, catalog models.Catalog, filters CatalogFilters) (int, error)
+	GetEntry(ctx context.Context, catalog models.Catalog, id uuid.UUID) (*models.CatalogEntry, error)
+	GetUsage(ctx context.Context, catalog models.Catalog, id uuid.UUID) (*models.CatalogUsage, error)
+	GetUsages(ctx context.Context, catalog models.Catalog, ids []uuid.UUID) (map[uuid.UUID]*models.CatalogUsage, error)
+	UpdateEntry(ctx context.Context, catalog models.Catalog, id uuid.UUID, req *models.UpdateCatalogEntryRequest) error
+	DeleteEntry(ctx context.Context, catalog models.Catalog, id uuid.UUID, reassignTo *uuid.UUID) error
+
 	FindCountryByName(ctx context.Context, name string) (uuid.UUID, error)
-	CreateCountry(ctx context.Context, name string) (uuid.UUID, error)
+	FindCountryByCode(ctx context.Context, code string) (uuid.UUID, error)
+	FindOrCreateCountry(ctx context.Context, code, name string) (uuid.UUID, error)
 
 	FindCityByName(ctx context.Context, name string, countryID uuid.UUID) (uuid.UUID, error)
-	CreateCity(ctx context.Context, name string, countryID uuid.UUID) (uuid.UUID, error)
+	FindOrCreateCity(ctx context.Context, name string, countryID uuid.UUID) (uuid.UUID, error)
 
 	FindProfessionByName(ctx context.Context, name string) (uuid.UUID, error)
-	CreateProfession(ctx context.Context, name string) (uuid.UUID, error)
+	FindOrCreateProfession(ctx context.Context, name string) (uuid.UUID, error)
 
 	FindJobTitleCategoryByName(ctx context.Context, name string) (uuid.UUID, error)
-	CreateJobTitleCategory(ctx context.Context, name string) (uuid.UUID, error)
+	FindOrCreateJobTitleCategory(ctx context.Context, name string) (uuid.UUID, error)
+
+	FindCompanyByName(ctx context.Context, name string) (uuid.UUID, error)
+	FindOrCreateCompany(ctx context.Context, name string) (uuid.UUID, error)
+	RefreshStudentsByCompany(ctx context.Context) error
 
 	FindUniversityByName(ctx context.Context, name string, countryID uuid.UUID) (uuid.UUID, error)
-	CreateUniversity(ctx context.Context, name string, cityID *uuid.UUID, countryID uuid.UUID) (uuid.UUID, error)
+	FindOrCreateUniversity(ctx context.Context, name string, cityID *uuid.UUID, countryID uuid.UUID) (uuid.UUID, error)
 
-	CreateStudentUniversity(ctx context.Context, studentID, universityID uuid.UUID) error
+	CreateStudentUniversity(ctx context.Context, link *models.StudentUniversity) error
+
+	FindSimilar(ctx context.Context, catalog models.Catalog, name string, countryID *uuid.UUID, minSimilarity float64, limit int) ([]models.CatalogMatch, error)
+
+	FindByAlias(ctx context.Context, catalog models.Catalog, alias string, countryID *uuid.UUID) (uuid.UUID, error)
+	ListAliases(ctx context.Context, catalog models.Catalog, entryID *uuid.UUID) ([]models.CatalogAlias, error)
+	CreateAlias(ctx context.Context, alias *models.CatalogAlias) error
+	DeleteAlias(ctx context.Context, catalog models.Catalog, id uuid.UUID) error
+
+	MergeEntries(ctx context.Context, catalog models.Catalog, canonicalID uuid.UUID, duplicateIDs []uuid.UUID) (*models.CatalogMergeResult, error)
 }
 
 type catalogRepository struct {
@@ -39,38 +74,164 @@ func NewCatalogRepository(db *pgxpool.Pool) CatalogRepository {
 	return &catalogRepository{db: db}
 }
 
-func (r *catalogRepository) FindCountryByName(ctx context.Context, name string) (uuid.UUID, error) {
-	var id uuid.UUID
+// catalogEntryColumns is the select list that reads a row of each catalog into a
+// models.CatalogEntry: id, name, code, country_id, city_id, description.
+var catalogEntryColumns = map[models.Catalog]string{
+	models.CatalogCountries:          "id, name, code, NULL::uuid, NULL::uuid, NULL::text",
+	models.CatalogCities:             "id, name, NULL::varchar, country_id, NULL::uuid, NULL::text",
+	models.CatalogProfessions:        "id, name, NULL::varchar, NULL::uuid, NULL::uuid, NULL::text",
+	models.CatalogJobTitleCategories: "id, name, NULL::varchar, NULL::uuid, NULL::uuid, description",
+	models.CatalogCompanies:          "id, name, NULL::varchar, NULL::uuid, NULL::uuid, NULL::text",
+	models.CatalogUniversities:       "id, name, NULL::varchar, country_id, city_id, NULL::text",
+}
+
+// catalogWhere builds the WHERE clause and arguments for the filters.
+func catalogWhere(catalog models.Catalog, filters CatalogFilters) (string, []interface{}) {
+	where := " WHERE TRUE"
+	args := []interface{}{}
+	argCount := 1
+
+	if filters.Search != nil {
+		search := strings.TrimSpace(*filters.Search)
+		where += fmt.Sprintf(" AND (catalog_name_key(name) LIKE '%%' || catalog_name_key($%d) || '%%'", argCount)
+		args = append(args, likeEscaper.Replace(search))
+		argCount++
+		if catalog == models.CatalogCountries {
+			where += fmt.Sprintf(" OR code = UPPER($%d)", argCount)
+			args = append(args, search)
+			argCount++
+		}
+		where += ")"
+	}
+
+	if filters.CountryID != nil {
+		where += fmt.Sprintf(" AND country_id = $%d", argCount)
+		args = append(args, *filters.CountryID)
+		argCount++
+	}
+
+	if filters.CityID != nil {
+		where += fmt.Sprintf(" AND city_id = $%d", argCount)
+		args = append(args, *filters.CityID)
+	}
+
+	return where, args
+}
+
+func (r *catalogRepository) ListEntries(ctx context.Context, catalog models.Catalog, filters CatalogFilters) ([]models.CatalogEntry, error) {
+	t, ok := catalogTables[catalog]
+	if !ok {
+		return nil, fmt.Errorf("unknown catalog %q", catalog)
+	}
+
+	where, args := catalogWhere(catalog, filters)
+	query := fmt.Sprintf("SELECT %s FROM %s%s ORDER BY name", catalogEntryColumns[catalog], t.table, where)
+	argCount := len(args) + 1
+
+	if filters.Limit > 0 {
+		query += fmt.Sprintf(" LIMIT $%d", argCount)
+		args = append(args, filters.Limit)
+		argCount++
+	}
+
+	if filters.Offset > 0 {
+		query += fmt.Sprintf(" OFFSET $%d", argCount)
+		args = append(args, filters.Offset)
+	}
+
+	rows, err := r.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list %s: %w", t.table, err)
+	}
+	defer rows.Close()
+
+	entries := []models.CatalogEntry{}
+	for rows.Next() {
+		var e models.CatalogEntry
+		if err := rows.Scan(&e.ID, &e.Name, &e.Code, &e.CountryID, &e.CityID, &e.Description); err != nil {
+			return nil, fmt.Errorf("failed to scan %s: %w", t.table, err)
+		}
+		entries = append(entries, e)
+	}
+	return entries, rows.Err()
+}
+
+func (r *catalogRepository) CountEntries(ctx context.Context, catalog models.Catalog, filters CatalogFilters) (int, error) {
+	t, ok := catalogTables[catalog]
+	if !ok {
+		return 0, fmt.Errorf("unknown catalog %q", catalog)
+	}
+
+	where, args := catalogWhere(catalog, filters)
+	var count int
+	if err := r.db.QueryRow(ctx, "SELECT COUNT(*) FROM "+t.table+where, args...).Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to count %s: %w", t.table, err)
+	}
+	return count, nil
+}
+
+func (r *catalogRepository) GetEntry(ctx context.Context, catalog models.Catalog, id uuid.UUID) (*models.CatalogEntry, error) {
+	t, ok := catalogTables[catalog]
+	if !ok {
+		return nil, fmt.Errorf("unknown catalog %q", catalog)
+	}
+
+	var e models.CatalogEntry
 	err := r.db.QueryRow(ctx,
-		"SELECT id FROM countries WHERE LOWER(unaccent(TRIM(name))) = LOWER(unaccent(TRIM($1)))", name,
-	).Scan(&id)
+		fmt.Sprintf("SELECT %s FROM %s WHERE id = $1", catalogEntryColumns[catalog], t.table), id,
+	).Scan(&e.ID, &e.Name, &e.Code, &e.CountryID, &e.CityID, &e.Description)
 	if err == pgx.ErrNoRows {
-		return uuid.Nil, nil
+		return nil, fmt.Errorf("%s not found", catalog.Singular())
 	}
-	return id, err
+	if err != nil {
+		return nil, fmt.Errorf("failed to get %s: %w", catalog.Singular(), err)
+	}
+	return &e, nil
 }
 
-func (r *catalogRepository) CreateCountry(ctx context.Context, name string) (uuid.UUID, error) {
-	// Generate a 3-letter code from the country name (uppercase, first 3 chars)
-	code := strings.ToUpper(strings.TrimSpace(name))
-	if len(code) > 3 {
-		code = code[:3]
+// findOrCreate returns the entry found by find or inserts it. Lookup and insert
+// run under a transaction-level advisory lock on the table and name key, so two
+// imports creating the same name don't both insert it even where migration 016
+// couldn't build the catalog_name_key index because of existing duplicates.
+func (r *catalogRepository) findOrCreate(ctx context.Context, catalog models.Catalog, name, insert string, args []interface{}, find func() (uuid.UUID, error)) (uuid.UUID, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("failed to begin transaction: %w", err)
 	}
+	defer tx.Rollback(ctx)
 
-	var id uuid.UUID
-	err := r.db.QueryRow(ctx,
-		"INSERT INTO countries (code, name) VALUES ($1, $2) RETURNING id", code, name,
-	).Scan(&id)
+	_, err = tx.Exec(ctx, "SELECT pg_advisory_xact_lock(hashtext($1 || '|' || catalog_name_key($2)))",
+		catalogTables[catalog].table, name)
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("failed to lock %s %q: %w", catalog.Singular(), name, err)
+	}
+
+	// Holding the lock, find sees any entry committed by a concurrent import
+	id, err := find()
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("failed to find %s %q: %w", catalog.Singular(), name, err)
+	}
+	if id != uuid.Nil {
+		return id, nil
+	}
+
+	err = tx.QueryRow(ctx, insert+" ON CONFLICT DO NOTHING RETURNING id", args...).Scan(&id)
+	if err == pgx.ErrNoRows {
+		return uuid.Nil, fmt.Errorf("failed to create %s %q: conflicts with an existing entry", catalog.Singular(), name)
+	}
 	if err != nil {
-		return uuid.Nil, fmt.Errorf("failed to create country %q: %w", name, err)
+		return uuid.Nil, fmt.Errorf("failed to create %s %q: %w", catalog.Singular(), name, err)
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return uuid.Nil, fmt.Errorf("failed to create %s %q: %w", catalog.Singular(), name, err)
 	}
 	return id, nil
 }
 
-func (r *catalogRepository) FindCityByName(ctx context.Context, name string, countryID uuid.UUID) (uuid.UUID, error) {
+func (r *catalogRepository) FindCountryByName(ctx context.Context, name string) (uuid.UUID, error) {
 	var id uuid.UUID
 	err := r.db.QueryRow(ctx,
-		"SELECT id FROM cities WHERE LOWER(unaccent(TRIM(name))) = LOWER(unaccent(TRIM($1))) AND country_id = $2", name, countryID,
+		"SELECT id FROM countries WHERE catalog_name_key(name) = catalog_name_key($1)", name,
 	).Scan(&id)
 	if err == pgx.ErrNoRows {
 		return uuid.Nil, nil
@@ -78,21 +239,35 @@ func (r *catalogRepository) FindCityByName(ctx context.Context, name string, cou
 	return id, err
 }
 
-func (r *catalogRepository) CreateCity(ctx context.Context, name string, countryID uuid.UUID) (uuid.UUID, error) {
+func (r *catalogRepository) FindCountryByCode(ctx context.Context, code string) (uuid.UUID, error) {
 	var id uuid.UUID
 	err := r.db.QueryRow(ctx,
-		"INSERT INTO cities (name, country_id) VALUES ($1, $2) RETURNING id", name, countryID,
+		"SELECT id FROM countries WHERE code = UPPER(TRIM($1))", code,
 	).Scan(&id)
-	if err != nil {
-		return uuid.Nil, fmt.Errorf("failed to create city %q: %w", name, err)
+	if err == pgx.ErrNoRows {
+		return uuid.Nil, nil
 	}
-	return id, nil
+	return id, err
 }
 
-func (r *catalogRepository) FindProfessionByName(ctx context.Context, name string) (uuid.UUID, error) {
+// FindOrCreateCountry returns the country with the code or name, creating it with
+// its ISO 3166-1 alpha-3 code if neither exists.
+func (r *catalogRepository) FindOrCreateCountry(ctx context.Context, code, name string) (uuid.UUID, error) {
+	return r.findOrCreate(ctx, models.CatalogCountries, name,
+		"INSERT INTO countries (code, name) VALUES ($1, $2)", []interface{}{code, name},
+		func() (uuid.UUID, error) {
+			id, err := r.FindCountryByCode(ctx, code)
+			if err != nil || id != uuid.Nil {
+				return id, err
+			}
+			return r.FindCountryByName(ctx, name)
+		})
+}
+
+func (r *catalogRepository) FindCityByName(ctx context.Context, name string, countryID uuid.UUID) (uuid.UUID, error) {
 	var id uuid.UUID
 	err := r.db.QueryRow(ctx,
-		"SELECT id FROM professions WHERE LOWER(unaccent(TRIM(name))) = LOWER(unaccent(TRIM($1)))", name,
+		"SELECT id FROM cities WHERE catalog_name_key(name) = catalog_name_key($1) AND country_id = $2", name, countryID,
 	).Scan(&id)
 	if err == pgx.ErrNoRows {
 		return uuid.Nil, nil
@@ -100,21 +275,33 @@ func (r *catalogRepository) FindProfessionByName(ctx context.Context, name strin
 	return id, err
 }
 
-func (r *catalogRepository) CreateProfession(ctx context.Context, name string) (uuid.UUID, error) {
+func (r *catalogRepository) FindOrCreateCity(ctx context.Context, name string, countryID uuid.UUID) (uuid.UUID, error) {
+	return r.findOrCreate(ctx, models.CatalogCities, name,
+		"INSERT INTO cities (name, country_id) VALUES ($1, $2)", []interface{}{name, countryID},
+		func() (uuid.UUID, error) { return r.FindCityByName(ctx, name, countryID) })
+}
+
+func (r *catalogRepository) FindProfessionByName(ctx context.Context, name string) (uuid.UUID, error) {
 	var id uuid.UUID
 	err := r.db.QueryRow(ctx,
-		"INSERT INTO professions (name) VALUES ($1) RETURNING id", name,
+		"SELECT id FROM professions WHERE catalog_name_key(name) = catalog_name_key($1)", name,
 	).Scan(&id)
-	if err != nil {
-		return uuid.Nil, fmt.Errorf("failed to create profession %q: %w", name, err)
+	if err == pgx.ErrNoRows {
+		return uuid.Nil, nil
 	}
-	return id, nil
+	return id, err
+}
+
+func (r *catalogRepository) FindOrCreateProfession(ctx context.Context, name string) (uuid.UUID, error) {
+	return r.findOrCreate(ctx, models.CatalogProfessions, name,
+		"INSERT INTO professions (name) VALUES ($1)", []interface{}{name},
+		func() (uuid.UUID, error) { return r.FindProfessionByName(ctx, name) })
 }
 
 func (r *catalogRepository) FindJobTitleCategoryByName(ctx context.Context, name string) (uuid.UUID, error) {
 	var id uuid.UUID
 	err := r.db.QueryRow(ctx,
-		"SELECT id FROM job_title_categories WHERE LOWER(unaccent(TRIM(name))) = LOWER(unaccent(TRIM($1)))", name,
+		"SELECT id FROM job_title_categories WHERE catalog_name_key(name) = catalog_name_key($1)", name,
 	).Scan(&id)
 	if err == pgx.ErrNoRows {
 		return uuid.Nil, nil
@@ -122,21 +309,41 @@ func (r *catalogRepository) FindJobTitleCategoryByName(ctx context.Context, name
 	return id, err
 }
 
-func (r *catalogRepository) CreateJobTitleCategory(ctx context.Context, name string) (uuid.UUID, error) {
+func (r *catalogRepository) FindOrCreateJobTitleCategory(ctx context.Context, name string) (uuid.UUID, error) {
+	return r.findOrCreate(ctx, models.CatalogJobTitleCategories, name,
+		"INSERT INTO job_title_categories (name) VALUES ($1)", []interface{}{name},
+		func() (uuid.UUID, error) { return r.FindJobTitleCategoryByName(ctx, name) })
+}
+
+func (r *catalogRepository) FindCompanyByName(ctx context.Context, name string) (uuid.UUID, error) {
 	var id uuid.UUID
 	err := r.db.QueryRow(ctx,
-		"INSERT INTO job_title_categories (name) VALUES ($1) RETURNING id", name,
+		"SELECT id FROM companies WHERE catalog_name_key(name) = catalog_name_key($1)", name,
 	).Scan(&id)
-	if err != nil {
-		return uuid.Nil, fmt.Errorf("failed to create job_title_category %q: %w", name, err)
+	if err == pgx.ErrNoRows {
+		return uuid.Nil, nil
 	}
-	return id, nil
+	return id, err
+}
+
+func (r *catalogRepository) FindOrCreateCompany(ctx context.Context, name string) (uuid.UUID, error) {
+	return r.findOrCreate(ctx, models.CatalogCompanies, name,
+		"INSERT INTO companies (name) VALUES ($1)", []interface{}{name},
+		func() (uuid.UUID, error) { return r.FindCompanyByName(ctx, name) })
+}
+
+// RefreshStudentsByCompany refreshes the students_by_company materialized view.
+func (r *catalogRepository) RefreshStudentsByCompany(ctx context.Context) error {
+	if _, err := r.db.Exec(ctx, "REFRESH MATERIALIZED VIEW CONCURRENTLY students_by_company"); err != nil {
+		return fmt.Errorf("failed to refresh students_by_company: %w", err)
+	}
+	return nil
 }
 
 func (r *catalogRepository) FindUniversityByName(ctx context.Context, name string, countryID uuid.UUID) (uuid.UUID, error) {
 	var id uuid.UUID
 	err := r.db.QueryRow(ctx,
-		"SELECT id FROM universities WHERE LOWER(unaccent(TRIM(name))) = LOWER(unaccent(TRIM($1))) AND country_id = $2", name, countryID,
+		"SELECT id FROM universities WHERE catalog_name_key(name) = catalog_name_key($1) AND country_id = $2", name, countryID,
 	).Scan(&id)
 	if err == pgx.ErrNoRows {
 		return uuid.Nil, nil
@@ -144,25 +351,159 @@ func (r *catalogRepository) FindUniversityByName(ctx context.Context, name strin
 	return id, err
 }
 
-func (r *catalogRepository) CreateUniversity(ctx context.Context, name string, cityID *uuid.UUID, countryID uuid.UUID) (uuid.UUID, error) {
+func (r *catalogRepository) FindOrCreateUniversity(ctx context.Context, name string, cityID *uuid.UUID, countryID uuid.UUID) (uuid.UUID, error) {
+	return r.findOrCreate(ctx, models.CatalogUniversities, name,
+		"INSERT INTO universities (name, city_id, country_id) VALUES ($1, $2, $3)", []interface{}{name, cityID, countryID},
+		func() (uuid.UUID, error) { return r.FindUniversityByName(ctx, name, countryID) })
+}
+
+// CreateStudentUniversity links a student to a university. If the link already
+// exists, a degree or graduation year given now fills in the stored one.
+func (r *catalogRepository) CreateStudentUniversity(ctx context.Context, link *models.StudentUniversity) error {
+	_, err := r.db.Exec(ctx, `
+		INSERT INTO student_universities (student_id, university_id, degree_obtained, graduation_year)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (student_id, university_id) DO UPDATE SET
+			degree_obtained = COALESCE(EXCLUDED.degree_obtained, student_universities.degree_obtained),
+			graduation_year = COALESCE(EXCLUDED.graduation_year, student_universities.graduation_year)`,
+		link.StudentID, link.UniversityID, link.DegreeObtained, link.GraduationYear,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create student_university: %w", err)
+	}
+	return nil
+}
+
+// catalogTables maps catalogs to their tables and whether entries belong to a country.
+var catalogTables = map[models.Catalog]struct {
+	table        string
+	countryScope bool
+}{
+	models.CatalogCountries:          {"countries", false},
+	models.CatalogCities:             {"cities", true},
+	models.CatalogProfessions:        {"professions", false},
+	models.CatalogJobTitleCategories: {"job_title_categories", false},
+	models.CatalogCompanies:          {"companies", false},
+	models.CatalogUniversities:       {"universities", true},
+}
+
+// FindSimilar returns the entries of a catalog whose names have a trigram similarity
+// of at least minSimilarity with name, most similar first. Entries of cities and
+// universities are limited to countryID when it is given.
+func (r *catalogRepository) FindSimilar(ctx context.Context, catalog models.Catalog, name string, countryID *uuid.UUID, minSimilarity float64, limit int) ([]models.CatalogMatch, error) {
+	t, ok := catalogTables[catalog]
+	if !ok {
+		return nil, fmt.Errorf("unknown catalog %q", catalog)
+	}
+
+	query := fmt.Sprintf(`
+		SELECT id, name, similarity(catalog_name_key(name), catalog_name_key($1)) AS score
+		FROM %s
+		WHERE similarity(catalog_name_key(name), catalog_name_key($1)) >= $2`, t.table)
+	args := []any{strings.TrimSpace(name), minSimilarity}
+	if t.countryScope && countryID != nil {
+		query += " AND country_id = $3"
+		args = append(args, *countryID)
+	}
+	query += fmt.Sprintf(" ORDER BY score DESC, name LIMIT %d", limit)
+
+	rows, err := r.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find similar %s: %w", catalog, err)
+	}
+	defer rows.Close()
+
+	matches := []models.CatalogMatch{}
+	for rows.Next() {
+		var m models.CatalogMatch
+		if err := rows.Scan(&m.ID, &m.Name, &m.Similarity); err != nil {
+			return nil, fmt.Errorf("failed to scan similar %s: %w", catalog, err)
+		}
+		matches = append(matches, m)
+	}
+	return matches, rows.Err()
+}
+
+// FindByAlias returns the entry of a catalog that has the given alias. Aliases of
+// cities and universities are limited to countryID when it is given.
+func (r *catalogRepository) FindByAlias(ctx context.Context, catalog models.Catalog, alias string, countryID *uuid.UUID) (uuid.UUID, error) {
+	query := "SELECT entry_id FROM catalog_aliases WHERE catalog = $1 AND catalog_name_key(alias) = catalog_name_key($2)"
+	args := []any{catalog, alias}
+	if catalogTables[catalog].countryScope && countryID != nil {
+		query += " AND country_id = $3"
+		args = append(args, *countryID)
+	}
+
 	var id uuid.UUID
-	err := r.db.QueryRow(ctx,
-		"INSERT INTO universities (name, city_id, country_id) VALUES ($1, $2, $3) RETURNING id",
-		name, cityID, countryID,
-	).Scan(&id)
+	// Without a country, a name may be an alias in several countries
+	err := r.db.QueryRow(ctx, query+" ORDER BY created_at LIMIT 1", args...).Scan(&id)
+	if err == pgx.ErrNoRows {
+		return uuid.Nil, nil
+	}
+	return id, err
+}
+
+// ListAliases returns the aliases of a catalog, optionally only those of one entry.
+func (r *catalogRepository) ListAliases(ctx context.Context, catalog models.Catalog, entryID *uuid.UUID) ([]models.CatalogAlias, error) {
+	query := "SELECT id, catalog, entry_id, country_id, alias, created_at FROM catalog_aliases WHERE catalog = $1"
+	args := []any{catalog}
+	if entryID != nil {
+		query += " AND entry_id = $2"
+		args = append(args, *entryID)
+	}
+	query += " ORDER BY alias"
+
+	rows, err := r.db.Query(ctx, query, args...)
 	if err != nil {
-		return uuid.Nil, fmt.Errorf("failed to create university %q: %w", name, err)
+		return nil, fmt.Errorf("failed to list %s aliases: %w", catalog, err)
 	}
-	return id, nil
+	defer rows.Close()
+
+	aliases := []models.CatalogAlias{}
+	for rows.Next() {
+		var a models.CatalogAlias
+		if err := rows.Scan(&a.ID, &a.Catalog, &a.EntryID, &a.CountryID, &a.Alias, &a.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan catalog alias: %w", err)
+		}
+		aliases = append(aliases, a)
+	}
+	return aliases, rows.Err()
 }
 
-func (r *catalogRepository) CreateStudentUniversity(ctx context.Context, studentID, universityID uuid.UUID) error {
-	_, err := r.db.Exec(ctx,
-		"INSERT INTO student_universities (student_id, university_id) VALUES ($1, $2) ON CONFLICT DO NOTHING",
-		studentID, universityID,
-	)
+// CreateAlias adds an alias to an existing catalog entry. The country of city and
+// university entries is copied to the alias so lookups can be scoped by country.
+func (r *catalogRepository) CreateAlias(ctx context.Context, alias *models.CatalogAlias) error {
+	t, ok := catalogTables[alias.Catalog]
+	if !ok {
+		return fmt.Errorf("unknown catalog %q", alias.Catalog)
+	}
+	countryColumn := "NULL::uuid"
+	if t.countryScope {
+		countryColumn = "country_id"
+	}
+
+	query := fmt.Sprintf(`
+		INSERT INTO catalog_aliases (catalog, entry_id, country_id, alias)
+		SELECT $1, id, %s, $3 FROM %s WHERE id = $2
+		RETURNING id, country_id, created_at`, countryColumn, t.table)
+	err := r.db.QueryRow(ctx, query, alias.Catalog, alias.EntryID, alias.Alias).
+		Scan(&alias.ID, &alias.CountryID, &alias.CreatedAt)
+	if err == pgx.ErrNoRows {
+		return fmt.Errorf("%s not found", alias.Catalog.Singular())
+	}
 	if err != nil {
-		return fmt.Errorf("failed to create student_university: %w", err)
+		return fmt.Errorf("failed to create alias %q: %w", alias.Alias, err)
+	}
+	return nil
+}
+
+func (r *catalogRepository) DeleteAlias(ctx context.Context, catalog models.Catalog, id uuid.UUID) error {
+	tag, err := r.db.Exec(ctx, "DELETE FROM catalog_aliases WHERE catalog = $1 AND id = $2", catalog, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete alias: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("alias not found")
 	}
 	return nil
 }
@@ -0,0 +1,83 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/dcorreal/coordinador/internal/models"
+)
+
+// EnrollmentRepository defines the data access interface for enrollments.
+type EnrollmentRepository interface {
+	FindScheduledCourse(ctx context.Context, courseCode, periodName string) (uuid.UUID, error)
+	Create(ctx context.Context, enrollment *models.Enrollment) (bool, error)
+}
+
+type enrollmentRepository struct {
+	db *pgxpool.Pool
+}
+
+// NewEnrollmentRepository creates a new EnrollmentRepository backed by pgxpool.
+func NewEnrollmentRepository(db *pgxpool.Pool) EnrollmentRepository {
+	return &enrollmentRepository{db: db}
+}
+
+// FindScheduledCourse returns the scheduled course for a course code in an academic period,
+// or uuid.Nil if the course is not offered in that period.
+func (r *enrollmentRepository) FindScheduledCourse(ctx context.Context, courseCode, periodName string) (uuid.UUID, error) {
+	query := `
+		SELECT sc.id
+		FROM scheduled_courses sc
+		JOIN courses c ON c.id = sc.course_id AND c.deleted_at IS NULL
+		JOIN academic_periods ap ON ap.id = sc.academic_period_id AND ap.deleted_at IS NULL
+		WHERE UPPER(TRIM(c.code)) = UPPER(TRIM($1))
+		  AND LOWER(TRIM(ap.name)) = LOWER(TRIM($2))
+		  AND sc.deleted_at IS NULL
+	`
+
+	var id uuid.UUID
+	err := r.db.QueryRow(ctx, query, courseCode, periodName).Scan(&id)
+	if err == pgx.ErrNoRows {
+		return uuid.Nil, nil
+	}
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("failed to find scheduled course: %w", err)
+	}
+	return id, nil
+}
+
+// Create inserts an enrollment. It returns false without error when the student
+// is already enrolled in the scheduled course.
+func (r *enrollmentRepository) Create(ctx context.Context, enrollment *models.Enrollment) (bool, error) {
+	query := `
+		INSERT INTO enrollments (
+			id, student_id, scheduled_course_id, status, final_grade, notes, created_by
+		) VALUES (
+			$1, $2, $3, $4, $5, $6, $7
+		)
+		ON CONFLICT (student_id, scheduled_course_id) DO NOTHING
+		RETURNING enrolled_at, credits_earned, created_at, updated_at
+	`
+
+	err := r.db.QueryRow(ctx, query,
+		enrollment.ID,
+		enrollment.StudentID,
+		enrollment.ScheduledCourseID,
+		enrollment.Status,
+		enrollment.FinalGrade,
+		enrollment.Notes,
+		enrollment.CreatedBy,
+	).Scan(&enrollment.EnrolledAt, &enrollment.CreditsEarned, &enrollment.CreatedAt, &enrollment.UpdatedAt)
+
+	if err == pgx.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to create enrollment: %w", err)
+	}
+	return true, nil
+}
@@ -0,0 +1,177 @@
+package mocks
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/dcorreal/coordinador/internal/models"
+	"github.com/dcorreal/coordinador/internal/repositories"
+)
+
+// CatalogRepository is a mock implementation of repositories.CatalogRepository.
+type CatalogRepository struct {
+	mock.Mock
+}
+
+func (m *CatalogRepository) ListEntries(ctx context.Context, catalog models.Catalog, filters repositories.CatalogFilters) ([]models.CatalogEntry, error) {
+	args := m.Called(ctx, catalog, filters)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.CatalogEntry), args.Error(1)
+}
+
+func (m *CatalogRepository) CountEntries(ctx context.Context, catalog models.Catalog, filters repositories.CatalogFilters) (int, error) {
+	args := m.Called(ctx, catalog, filters)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *CatalogRepository) GetEntry(ctx context.Context, catalog models.Catalog, id uuid.UUID) (*models.CatalogEntry, error) {
+	args := m.Called(ctx, catalog, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.CatalogEntry), args.Error(1)
+}
+
+func (m *CatalogRepository) GetUsage(ctx context.Context, catalog models.Catalog, id uuid.UUID) (*models.CatalogUsage, error) {
+	args := m.Called(ctx, catalog, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.CatalogUsage), args.Error(1)
+}
+
+func (m *CatalogRepository) GetUsages(ctx context.Context, catalog models.Catalog, ids []uuid.UUID) (map[uuid.UUID]*models.CatalogUsage, error) {
+	args := m.Called(ctx, catalog, ids)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(map[uuid.UUID]*models.CatalogUsage), args.Error(1)
+}
+
+func (m *CatalogRepository) UpdateEntry(ctx context.Context, catalog models.Catalog, id uuid.UUID, req *models.UpdateCatalogEntryRequest) error {
+	args := m.Called(ctx, catalog, id, req)
+	return args.Error(0)
+}
+
+func (m *CatalogRepository) DeleteEntry(ctx context.Context, catalog models.Catalog, id uuid.UUID, reassignTo *uuid.UUID) error {
+	args := m.Called(ctx, catalog, id, reassignTo)
+	return args.Error(0)
+}
+
+func (m *CatalogRepository) FindCountryByName(ctx context.Context, name string) (uuid.UUID, error) {
+	args := m.Called(ctx, name)
+	return args.Get(0).(uuid.UUID), args.Error(1)
+}
+
+func (m *CatalogRepository) FindCountryByCode(ctx context.Context, code string) (uuid.UUID, error) {
+	args := m.Called(ctx, code)
+	return args.Get(0).(uuid.UUID), args.Error(1)
+}
+
+func (m *CatalogRepository) FindOrCreateCountry(ctx context.Context, code, name string) (uuid.UUID, error) {
+	args := m.Called(ctx, code, name)
+	return args.Get(0).(uuid.UUID), args.Error(1)
+}
+
+func (m *CatalogRepository) FindCityByName(ctx context.Context, name string, countryID uuid.UUID) (uuid.UUID, error) {
+	args := m.Called(ctx, name, countryID)
+	return args.Get(0).(uuid.UUID), args.Error(1)
+}
+
+func (m *CatalogRepository) FindOrCreateCity(ctx context.Context, name string, countryID uuid.UUID) (uuid.UUID, error) {
+	args := m.Called(ctx, name, countryID)
+	return args.Get(0).(uuid.UUID), args.Error(1)
+}
+
+func (m *CatalogRepository) FindProfessionByName(ctx context.Context, name string) (uuid.UUID, error) {
+	args := m.Called(ctx, name)
+	return args.Get(0).(uuid.UUID), args.Error(1)
+}
+
+func (m *CatalogRepository) FindOrCreateProfession(ctx context.Context, name string) (uuid.UUID, error) {
+	args := m.Called(ctx, name)
+	return args.Get(0).(uuid.UUID), args.Error(1)
+}
+
+func (m *CatalogRepository) FindJobTitleCategoryByName(ctx context.Context, name string) (uuid.UUID, error) {
+	args := m.Called(ctx, name)
+	return args.Get(0).(uuid.UUID), args.Error(1)
+}
+
+func (m *CatalogRepository) FindOrCreateJobTitleCategory(ctx context.Context, name string) (uuid.UUID, error) {
+	args := m.Called(ctx, name)
+	return args.Get(0).(uuid.UUID), args.Error(1)
+}
+
+func (m *CatalogRepository) FindCompanyByName(ctx context.Context, name string) (uuid.UUID, error) {
+	args := m.Called(ctx, name)
+	return args.Get(0).(uuid.UUID), args.Error(1)
+}
+
+func (m *CatalogRepository) FindOrCreateCompany(ctx context.Context, name string) (uuid.UUID, error) {
+	args := m.Called(ctx, name)
+	return args.Get(0).(uuid.UUID), args.Error(1)
+}
+
+func (m *CatalogRepository) RefreshStudentsByCompany(ctx context.Context) error {
+	args := m.Called(ctx)
+	return args.Error(0)
+}
+
+func (m *CatalogRepository) FindUniversityByName(ctx context.Context, name string, countryID uuid.UUID) (uuid.UUID, error) {
+	args := m.Called(ctx, name, countryID)
+	return args.Get(0).(uuid.UUID), args.Error(1)
+}
+
+func (m *CatalogRepository) FindOrCreateUniversity(ctx context.Context, name string, cityID *uuid.UUID, countryID uuid.UUID) (uuid.UUID, error) {
+	args := m.Called(ctx, name, cityID, countryID)
+	return args.Get(0).(uuid.UUID), args.Error(1)
+}
+
+func (m *CatalogRepository) CreateStudentUniversity(ctx context.Context, link *models.StudentUniversity) error {
+	args := m.Called(ctx, link)
+	return args.Error(0)
+}
+
+func (m *CatalogRepository) FindSimilar(ctx context.Context, catalog models.Catalog, name string, countryID *uuid.UUID, minSimilarity float64, limit int) ([]models.CatalogMatch, error) {
+	args := m.Called(ctx, catalog, name, countryID, minSimilarity, limit)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.CatalogMatch), args.Error(1)
+}
+
+func (m *CatalogRepository) FindByAlias(ctx context.Context, catalog models.Catalog, alias string, countryID *uuid.UUID) (uuid.UUID, error) {
+	args := m.Called(ctx, catalog, alias, countryID)
+	return args.Get(0).(uuid.UUID), args.Error(1)
+}
+
+func (m *CatalogRepository) ListAliases(ctx context.Context, catalog models.Catalog, entryID *uuid.UUID) ([]models.CatalogAlias, error) {
+	args := m.Called(ctx, catalog, entryID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.CatalogAlias), args.Error(1)
+}
+
+func (m *CatalogRepository) CreateAlias(ctx context.Context, alias *models.CatalogAlias) error {
+	args := m.Called(ctx, alias)
+	return args.Error(0)
+}
+
+func (m *CatalogRepository) DeleteAlias(ctx context.Context, catalog models.Catalog, id uuid.UUID) error {
+	args := m.Called(ctx, catalog, id)
+	return args.Error(0)
+}
+
+func (m *CatalogRepository) MergeEntries(ctx context.Context, catalog models.Catalog, canonicalID uuid.UUID, duplicateIDs []uuid.UUID) (*models.CatalogMergeResult, error) {
+	args := m.Called(ctx, catalog, canonicalID, duplicateIDs)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.CatalogMergeResult), args.Error(1)
+}
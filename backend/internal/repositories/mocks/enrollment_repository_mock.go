@@ -0,0 +1,25 @@
+package mocks
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/dcorreal/coordinador/internal/models"
+)
+
+// EnrollmentRepository is a mock implementation of repositories.EnrollmentRepository.
+type EnrollmentRepository struct {
+	mock.Mock
+}
+
+func (m *EnrollmentRepository) FindScheduledCourse(ctx context.Context, courseCode, periodName string) (uuid.UUID, error) {
+	args := m.Called(ctx, courseCode, periodName)
+	return args.Get(0).(uuid.UUID), args.Error(1)
+}
+
+func (m *EnrollmentRepository) Create(ctx context.Context, enrollment *models.Enrollment) (bool, error) {
+	args := m.Called(ctx, enrollment)
+	return args.Bool(0), args.Error(1)
+}
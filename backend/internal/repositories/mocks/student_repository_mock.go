@@ -2,6 +2,7 @@ package mocks
 
 import (
 	"context"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/stretchr/testify/mock"
@@ -20,6 +21,17 @@ func (m *StudentRepository) Create(ctx context.Context, student *models.Student)
 	return args.Error(0)
 }
 
+func (m *StudentRepository) CreateBatch(ctx context.Context, students []*models.Student) ([]error, error) {
+	args := m.Called(ctx, students)
+	if err := args.Error(1); err != nil {
+		return nil, err
+	}
+	if args.Get(0) == nil {
+		return make([]error, len(students)), nil
+	}
+	return args.Get(0).([]error), nil
+}
+
 func (m *StudentRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.Student, error) {
 	args := m.Called(ctx, id)
 	if args.Get(0) == nil {
@@ -28,6 +40,14 @@ func (m *StudentRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.
 	return args.Get(0).(*models.Student), args.Error(1)
 }
 
+func (m *StudentRepository) GetByStudentCode(ctx context.Context, code string) (*models.Student, error) {
+	args := m.Called(ctx, code)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Student), args.Error(1)
+}
+
 func (m *StudentRepository) List(ctx context.Context, filters repositories.StudentFilters) ([]*models.Student, error) {
 	args := m.Called(ctx, filters)
 	if args.Get(0) == nil {
@@ -50,3 +70,74 @@ func (m *StudentRepository) Count(ctx context.Context, filters repositories.Stud
 	args := m.Called(ctx, filters)
 	return args.Int(0), args.Error(1)
 }
+
+func (m *StudentRepository) ExistingDocumentIDs(ctx context.Context, documentIDs []string) (map[string]bool, error) {
+	args := m.Called(ctx, documentIDs)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(map[string]bool), args.Error(1)
+}
+
+func (m *StudentRepository) ExistingEmails(ctx context.Context, emails []string) (map[string]bool, error) {
+	args := m.Called(ctx, emails)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(map[string]bool), args.Error(1)
+}
+
+func (m *StudentRepository) ListUniversities(ctx context.Context, studentID uuid.UUID) ([]models.StudentUniversity, error) {
+	args := m.Called(ctx, studentID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.StudentUniversity), args.Error(1)
+}
+
+func (m *StudentRepository) GetCatalogs(ctx context.Context, studentID uuid.UUID) (*models.StudentCatalogs, error) {
+	args := m.Called(ctx, studentID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.StudentCatalogs), args.Error(1)
+}
+
+func (m *StudentRepository) GetEnrollmentSummary(ctx context.Context, studentID uuid.UUID) (*models.EnrollmentSummary, error) {
+	args := m.Called(ctx, studentID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.EnrollmentSummary), args.Error(1)
+}
+
+func (m *StudentRepository) MaxUniversities(ctx context.Context, filters repositories.StudentFilters) (int, error) {
+	args := m.Called(ctx, filters)
+	return args.Int(0), args.Error(1)
+}
+
+// Export calls fn with the rows given to Return, then returns its error.
+func (m *StudentRepository) Export(ctx context.Context, filters repositories.StudentFilters, fn func(*models.StudentExportRow) error) error {
+	args := m.Called(ctx, filters, fn)
+	if rows, ok := args.Get(0).([]*models.StudentExportRow); ok {
+		for _, row := range rows {
+			if err := fn(row); err != nil {
+				return err
+			}
+		}
+	}
+	return args.Error(1)
+}
+
+func (m *StudentRepository) ChangeStatus(ctx context.Context, change *models.StudentStatusChange, graduationDate *time.Time) error {
+	args := m.Called(ctx, change, graduationDate)
+	return args.Error(0)
+}
+
+func (m *StudentRepository) ListStatusHistory(ctx context.Context, studentID uuid.UUID) ([]models.StudentStatusChange, error) {
+	args := m.Called(ctx, studentID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.StudentStatusChange), args.Error(1)
+}
@@ -0,0 +1,105 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/dcorreal/coordinador/internal/models"
+)
+
+// MaxUniversities returns the largest number of universities of a student
+// matching the filters, so exports know how many university columns to write.
+func (r *studentRepository) MaxUniversities(ctx context.Context, filters StudentFilters) (int, error) {
+	where, args := studentWhere(filters)
+	query := `
+		SELECT COALESCE(MAX(n), 0) FROM (
+			SELECT (SELECT COUNT(*) FROM student_universities su WHERE su.student_id = students.id) AS n
+			FROM students` + where + `
+		) counts`
+
+	var most int
+	if err := r.db.QueryRow(ctx, query, args...).Scan(&most); err != nil {
+		return 0, fmt.Errorf("failed to count student universities: %w", err)
+	}
+	return most, nil
+}
+
+// Export calls fn for every student matching the filters, in filters.Sort
+// order, as rows are read. Catalogs are resolved to their names; Limit,
+// Offset and After are ignored.
+func (r *studentRepository) Export(ctx context.Context, filters StudentFilters, fn func(*models.StudentExportRow) error) error {
+	where, args := studentWhere(filters)
+
+	order := filters.Sort.orDefault()
+	sortKey := order.Column
+	if sortKey == StudentSortRelevance && filters.Search != nil {
+		sortKey = studentRelevanceAt(len(args) + 1)
+		args = append(args, *filters.Search)
+	}
+
+	// Scalar subqueries keep the outer query on students alone, so the
+	// filter and sort columns stay unambiguous
+	query := `
+		SELECT
+			first_names, last_names, document_id, birth_date, gender, emails, phones,
+			(SELECT name FROM countries WHERE id = students.nationality_country_id),
+			(SELECT name FROM countries WHERE id = students.residence_country_id),
+			(SELECT name FROM cities WHERE id = students.residence_city_id),
+			(SELECT name FROM companies WHERE id = students.company_id),
+			(SELECT name FROM job_title_categories WHERE id = students.job_title_category_id),
+			(SELECT name FROM professions WHERE id = students.profession_id),
+			student_code, status, cohort, enrollment_date, graduation_date,
+			(SELECT json_agg(json_build_object(
+					'name', u.name, 'city', ci.name, 'country', co.name,
+					'degree', su.degree_obtained, 'graduation_year', su.graduation_year
+				) ORDER BY su.graduation_year NULLS LAST, u.name)
+			 FROM student_universities su
+			 JOIN universities u ON u.id = su.university_id
+			 JOIN countries co ON co.id = u.country_id
+			 LEFT JOIN cities ci ON ci.id = u.city_id
+			 WHERE su.student_id = students.id)
+		FROM students` + where + `
+		ORDER BY ` + order.orderBy(sortKey)
+
+	rows, err := r.db.Query(ctx, query, args...)
+	if err != nil {
+		return fmt.Errorf("failed to export students: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		row := &models.StudentExportRow{}
+		err := rows.Scan(
+			&row.FirstNames,
+			&row.LastNames,
+			&row.DocumentID,
+			&row.BirthDate,
+			&row.Gender,
+			&row.Emails,
+			&row.Phones,
+			&row.Nationality,
+			&row.ResidenceCountry,
+			&row.ResidenceCity,
+			&row.Company,
+			&row.JobTitleCategory,
+			&row.Profession,
+			&row.StudentCode,
+			&row.Status,
+			&row.Cohort,
+			&row.EnrollmentDate,
+			&row.GraduationDate,
+			&row.Universities,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to scan exported student: %w", err)
+		}
+		if err := fn(row); err != nil {
+			return err
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("error iterating exported students: %w", err)
+	}
+	return nil
+}
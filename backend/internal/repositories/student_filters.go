@@ -0,0 +1,281 @@
+package repositories
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/dcorreal/coordinador/internal/models"
+)
+
+// StudentFilters holds the query filters for listing students.
+type StudentFilters struct {
+	Statuses             []string
+	Cohort               *string
+	NationalityCountryID *uuid.UUID
+	ResidenceCountryID   *uuid.UUID
+	ResidenceCityID      *uuid.UUID
+	CompanyID            *uuid.UUID
+	ProfessionID         *uuid.UUID
+	JobTitleCategoryID   *uuid.UUID
+	UniversityID         *uuid.UUID // any of the student's universities
+	Gender               *string
+	EnrollmentFrom       *time.Time // inclusive
+	EnrollmentTo         *time.Time // inclusive
+	GraduationFrom       *time.Time // inclusive
+	GraduationTo         *time.Time // inclusive
+	MinAge               *int       // students without birth date are excluded
+	MaxAge               *int
+	Search               *string // words in the full name, document ID, student code or emails
+
+	Sort StudentSort
+	// After lists the students that follow a cursor, in place of Offset
+	After  *StudentCursor
+	Limit  int
+	Offset int
+}
+
+// Validate checks the filter values that the database would otherwise reject
+// or silently ignore.
+func (f StudentFilters) Validate() error {
+	for _, status := range f.Statuses {
+		switch models.StudentStatus(status) {
+		case models.StudentStatusActive, models.StudentStatusGraduated, models.StudentStatusWithdrawn, models.StudentStatusSuspended:
+		default:
+			return fmt.Errorf("invalid status %q", status)
+		}
+	}
+	if f.Gender != nil && *f.Gender != "M" && *f.Gender != "F" {
+		return fmt.Errorf("invalid gender %q, must be M or F", *f.Gender)
+	}
+	if f.EnrollmentFrom != nil && f.EnrollmentTo != nil && f.EnrollmentFrom.After(*f.EnrollmentTo) {
+		return fmt.Errorf("enrollment_from must not be after enrollment_to")
+	}
+	if f.GraduationFrom != nil && f.GraduationTo != nil && f.GraduationFrom.After(*f.GraduationTo) {
+		return fmt.Errorf("graduation_from must not be after graduation_to")
+	}
+	if (f.MinAge != nil && *f.MinAge < 0) || (f.MaxAge != nil && *f.MaxAge < 0) {
+		return fmt.Errorf("age must not be negative")
+	}
+	if f.MinAge != nil && f.MaxAge != nil && *f.MinAge > *f.MaxAge {
+		return fmt.Errorf("min_age must not be greater than max_age")
+	}
+	if f.Sort.Column == StudentSortRelevance && f.Search == nil {
+		return fmt.Errorf("sort by relevance requires search")
+	}
+	if f.After != nil && f.After.Sort != f.Sort.orDefault().String() {
+		return fmt.Errorf("cursor belongs to another sort order")
+	}
+	return nil
+}
+
+// studentSearchDocument is the normalized text that Search matches, indexed by
+// migration 019.
+const studentSearchDocument = "student_search_document(first_names, last_names, document_id, student_code, emails)"
+
+// studentRelevance ranks a student for a search ($? is the search): an exact
+// document ID or student code first, then by how closely the words match.
+const studentRelevance = "((CASE WHEN TRIM($?) IN (document_id, student_code) THEN 1 ELSE 0 END) + word_similarity(catalog_name_key($?), " + studentSearchDocument + "))::float8"
+
+func studentRelevanceAt(arg int) string {
+	return strings.ReplaceAll(studentRelevance, "$?", fmt.Sprintf("$%d", arg))
+}
+
+var likeEscaper = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+
+// studentWhere builds the WHERE clause and arguments shared by List and Count.
+func studentWhere(filters StudentFilters) (string, []interface{}) {
+	where := " WHERE deleted_at IS NULL"
+	args := []interface{}{}
+	argCount := 1
+
+	add := func(condition string, arg interface{}) {
+		where += " AND " + strings.ReplaceAll(condition, "$?", fmt.Sprintf("$%d", argCount))
+		args = append(args, arg)
+		argCount++
+	}
+
+	if len(filters.Statuses) > 0 {
+		add("status = ANY($?)", filters.Statuses)
+	}
+	if filters.Cohort != nil {
+		add("cohort = $?", *filters.Cohort)
+	}
+	if filters.NationalityCountryID != nil {
+		add("nationality_country_id = $?", *filters.NationalityCountryID)
+	}
+	if filters.ResidenceCountryID != nil {
+		add("residence_country_id = $?", *filters.ResidenceCountryID)
+	}
+	if filters.ResidenceCityID != nil {
+		add("residence_city_id = $?", *filters.ResidenceCityID)
+	}
+	if filters.CompanyID != nil {
+		add("company_id = $?", *filters.CompanyID)
+	}
+	if filters.ProfessionID != nil {
+		add("profession_id = $?", *filters.ProfessionID)
+	}
+	if filters.JobTitleCategoryID != nil {
+		add("job_title_category_id = $?", *filters.JobTitleCategoryID)
+	}
+	if filters.UniversityID != nil {
+		add("EXISTS (SELECT 1 FROM student_universities su WHERE su.student_id = students.id AND su.university_id = $?)", *filters.UniversityID)
+	}
+	if filters.Gender != nil {
+		add("gender = $?", *filters.Gender)
+	}
+	if filters.EnrollmentFrom != nil {
+		add("enrollment_date >= $?", *filters.EnrollmentFrom)
+	}
+	if filters.EnrollmentTo != nil {
+		add("enrollment_date <= $?", *filters.EnrollmentTo)
+	}
+	if filters.GraduationFrom != nil {
+		add("graduation_date >= $?", *filters.GraduationFrom)
+	}
+	if filters.GraduationTo != nil {
+		add("graduation_date <= $?", *filters.GraduationTo)
+	}
+	// A student is N years old from their Nth birthday until the day before their (N+1)th
+	if filters.MinAge != nil {
+		add("birth_date <= CURRENT_DATE - make_interval(years => $?)", *filters.MinAge)
+	}
+	if filters.MaxAge != nil {
+		add("birth_date > CURRENT_DATE - make_interval(years => $? + 1)", *filters.MaxAge)
+	}
+	// Every word must appear in the search document, so "jose perez" finds "José Pérez"
+	if filters.Search != nil {
+		for _, word := range strings.Fields(*filters.Search) {
+			add(studentSearchDocument+" LIKE '%' || catalog_name_key($?) || '%'", likeEscaper.Replace(word))
+		}
+	}
+
+	return where, args
+}
+
+// studentSortColumns lists the columns the student listing can be sorted by,
+// with the SQL type of their cursor values. Only NOT NULL columns are allowed
+// so cursors can compare them directly. relevance ranks search results and is
+// always descending.
+var studentSortColumns = map[string]string{
+	"enrollment_date": "date",
+	"first_names":     "text",
+	"last_names":      "text",
+	"cohort":          "text",
+	"status":          "text",
+	"relevance":       "float8",
+}
+
+// StudentSortRelevance orders search results by relevance.
+const StudentSortRelevance = "relevance"
+
+// StudentSort is the order of the student listing; id in the same direction
+// breaks ties. The zero value is enrollment_date descending.
+type StudentSort struct {
+	Column string
+	Desc   bool
+}
+
+// ParseStudentSort parses a sort parameter such as "last_names" or
+// "-enrollment_date" (descending). An empty string gives the default order.
+func ParseStudentSort(s string) (StudentSort, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return StudentSort{}.orDefault(), nil
+	}
+	order := StudentSort{Column: strings.TrimPrefix(s, "-"), Desc: strings.HasPrefix(s, "-")}
+	if _, ok := studentSortColumns[order.Column]; !ok {
+		columns := make([]string, 0, len(studentSortColumns))
+		for c := range studentSortColumns {
+			columns = append(columns, c)
+		}
+		sort.Strings(columns)
+		return StudentSort{}, fmt.Errorf("invalid sort %q, must be one of %s (prefixed with - for descending)", s, strings.Join(columns, ", "))
+	}
+	if order.Column == StudentSortRelevance {
+		order.Desc = true
+	}
+	return order, nil
+}
+
+func (s StudentSort) orDefault() StudentSort {
+	if s.Column == "" {
+		return StudentSort{Column: "enrollment_date", Desc: true}
+	}
+	return s
+}
+
+// String returns the sort in the format accepted by ParseStudentSort.
+func (s StudentSort) String() string {
+	if s.Desc {
+		return "-" + s.Column
+	}
+	return s.Column
+}
+
+// orderBy returns the ORDER BY list for the sort key, the column or the
+// relevance expression.
+func (s StudentSort) orderBy(key string) string {
+	dir := "ASC"
+	if s.Desc {
+		dir = "DESC"
+	}
+	return fmt.Sprintf("%s %s, id %s", key, dir, dir)
+}
+
+// StudentCursor is the position of a student in a listing order. It is sent to
+// clients as an opaque string.
+type StudentCursor struct {
+	Sort  string    `json:"s"`
+	Value string    `json:"v"`
+	ID    uuid.UUID `json:"id"`
+}
+
+// NewStudentCursor returns the cursor positioned at a student in the given order.
+func NewStudentCursor(student *models.Student, order StudentSort) StudentCursor {
+	order = order.orDefault()
+	var value string
+	switch order.Column {
+	case "enrollment_date":
+		value = student.EnrollmentDate.Format(time.DateOnly)
+	case "first_names":
+		value = student.FirstNames
+	case "last_names":
+		value = student.LastNames
+	case "cohort":
+		value = student.Cohort
+	case "status":
+		value = string(student.Status)
+	case StudentSortRelevance:
+		if student.Relevance != nil {
+			value = strconv.FormatFloat(*student.Relevance, 'g', -1, 64)
+		}
+	}
+	return StudentCursor{Sort: order.String(), Value: value, ID: student.ID}
+}
+
+// Encode returns the opaque form of the cursor.
+func (c StudentCursor) Encode() string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// DecodeStudentCursor parses a cursor returned by Encode.
+func DecodeStudentCursor(s string) (*StudentCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, fmt.Errorf("invalid cursor")
+	}
+	var c StudentCursor
+	if err := json.Unmarshal(data, &c); err != nil || c.ID == uuid.Nil || c.Sort == "" {
+		return nil, fmt.Errorf("invalid cursor")
+	}
+	return &c, nil
+}
@@ -2,35 +2,38 @@ package repositories
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"strings"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgxpool"
 
 	"github.com/dcorreal/coordinador/internal/models"
 )
 
-// StudentFilters holds the query filters for listing students.
-type StudentFilters struct {
-	Status             *string
-	Cohort             *string
-	ResidenceCountryID *uuid.UUID
-	Search             *string // ILIKE search on first_names || last_names
-	Limit              int
-	Offset             int
-}
-
 // StudentRepository defines the data access interface for students.
 type StudentRepository interface {
 	Create(ctx context.Context, student *models.Student) error
+	CreateBatch(ctx context.Context, students []*models.Student) ([]error, error)
 	GetByID(ctx context.Context, id uuid.UUID) (*models.Student, error)
+	GetByStudentCode(ctx context.Context, code string) (*models.Student, error)
 	List(ctx context.Context, filters StudentFilters) ([]*models.Student, error)
 	Update(ctx context.Context, student *models.Student) error
 	Delete(ctx context.Context, id uuid.UUID, deletedBy *uuid.UUID) error
 	Count(ctx context.Context, filters StudentFilters) (int, error)
 	ExistingDocumentIDs(ctx context.Context, documentIDs []string) (map[string]bool, error)
 	ExistingEmails(ctx context.Context, emails []string) (map[string]bool, error)
+	ListUniversities(ctx context.Context, studentID uuid.UUID) ([]models.StudentUniversity, error)
+	GetCatalogs(ctx context.Context, studentID uuid.UUID) (*models.StudentCatalogs, error)
+	GetEnrollmentSummary(ctx context.Context, studentID uuid.UUID) (*models.EnrollmentSummary, error)
+	MaxUniversities(ctx context.Context, filters StudentFilters) (int, error)
+	Export(ctx context.Context, filters StudentFilters, fn func(*models.StudentExportRow) error) error
+	ChangeStatus(ctx context.Context, change *models.StudentStatusChange, graduationDate *time.Time) error
+	ListStatusHistory(ctx context.Context, studentID uuid.UUID) ([]models.StudentStatusChange, error)
 }
 
 type studentRepository struct {
@@ -42,20 +45,27 @@ func NewStudentRepository(db *pgxpool.Pool) StudentRepository {
 	return &studentRepository{db: db}
 }
 
+// Create inserts the student and its universities in a single transaction.
 func (r *studentRepository) Create(ctx context.Context, student *models.Student) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
 	query := `
 		INSERT INTO students (
 			id, first_names, last_names, document_id, birth_date, profile_photo_url,
 			gender, nationality_country_id, residence_country_id, residence_city_id,
 			emails, phones, company_id, job_title_category_id, profession_id,
-			student_code, status, cohort, enrollment_date
+			student_code, status, cohort, enrollment_date, graduation_date, created_by
 		) VALUES (
-			$1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19
+			$1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20, $21
 		)
 		RETURNING created_at, updated_at
 	`
 
-	err := r.db.QueryRow(ctx, query,
+	err = tx.QueryRow(ctx, query,
 		student.ID,
 		student.FirstNames,
 		student.LastNames,
@@ -75,29 +85,163 @@ func (r *studentRepository) Create(ctx context.Context, student *models.Student)
 		student.Status,
 		student.Cohort,
 		student.EnrollmentDate,
+		student.GraduationDate,
+		student.CreatedBy,
 	).Scan(&student.CreatedAt, &student.UpdatedAt)
 
 	if err != nil {
 		return fmt.Errorf("failed to create student: %w", err)
 	}
 
+	if err := insertStudentUniversities(ctx, tx, student.ID, student.Universities); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to create student: %w", err)
+	}
 	return nil
 }
 
-func (r *studentRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.Student, error) {
+// insertStudentUniversities links a student to its universities inside tx.
+func insertStudentUniversities(ctx context.Context, tx pgx.Tx, studentID uuid.UUID, universities []models.StudentUniversity) error {
 	query := `
-		SELECT
+		INSERT INTO student_universities (student_id, university_id, degree_obtained, graduation_year)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id, created_at
+	`
+	for i := range universities {
+		u := &universities[i]
+		u.StudentID = studentID
+		err := tx.QueryRow(ctx, query, studentID, u.UniversityID, u.DegreeObtained, u.GraduationYear).Scan(&u.ID, &u.CreatedAt)
+		if err != nil {
+			return fmt.Errorf("failed to create student_university: %w", err)
+		}
+	}
+	return nil
+}
+
+// studentCopyColumns are the columns written by CreateBatch, in row order.
+var studentCopyColumns = []string{
+	"id", "first_names", "last_names", "document_id", "birth_date", "profile_photo_url",
+	"gender", "nationality_country_id", "residence_country_id", "residence_city_id",
+	"emails", "phones", "company_id", "job_title_category_id", "profession_id",
+	"student_code", "status", "cohort", "enrollment_date", "graduation_date",
+	"created_at", "created_by", "updated_at",
+}
+
+// CreateBatch inserts the students, and their universities, with COPY inside a transaction.
+// If the COPY fails on a row's data (a constraint violation on any row aborts it),
+// the batch is inserted row by row instead so each failure is attributed to its
+// student. The returned slice has one entry per student, nil for the ones created.
+// Other failures, such as a cancelled context or a lost connection, are returned
+// as the error and no student is created.
+func (r *studentRepository) CreateBatch(ctx context.Context, students []*models.Student) ([]error, error) {
+	errs := make([]error, len(students))
+	if len(students) == 0 {
+		return errs, nil
+	}
+
+	now := time.Now()
+	rows := make([][]interface{}, len(students))
+	for i, student := range students {
+		rows[i] = []interface{}{
+			student.ID,
+			student.FirstNames,
+			student.LastNames,
+			student.DocumentID,
+			student.BirthDate,
+			student.ProfilePhotoURL,
+			student.Gender,
+			student.NationalityCountryID,
+			student.ResidenceCountryID,
+			student.ResidenceCityID,
+			student.Emails,
+			student.Phones,
+			student.CompanyID,
+			student.JobTitleCategoryID,
+			student.ProfessionID,
+			student.StudentCode,
+			student.Status,
+			student.Cohort,
+			student.EnrollmentDate,
+			student.GraduationDate,
+			now,
+			student.CreatedBy,
+			now,
+		}
+	}
+
+	var links [][]interface{}
+	for _, student := range students {
+		for i := range student.Universities {
+			u := &student.Universities[i]
+			u.ID = uuid.New()
+			u.StudentID = student.ID
+			u.CreatedAt = now
+			links = append(links, []interface{}{u.ID, u.StudentID, u.UniversityID, u.DegreeObtained, u.GraduationYear, now})
+		}
+	}
+
+	err := r.copyStudents(ctx, rows, links)
+	if err == nil {
+		for _, student := range students {
+			student.CreatedAt = now
+			student.UpdatedAt = now
+		}
+		return errs, nil
+	}
+	if !isDataError(err) {
+		return nil, err
+	}
+
+	for i, student := range students {
+		errs[i] = r.Create(ctx, student)
+	}
+	return errs, nil
+}
+
+// isDataError reports whether err is a PostgreSQL integrity constraint violation
+// (class 23) or data exception (class 22), caused by the values of a row.
+func isDataError(err error) bool {
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) {
+		return false
+	}
+	class := pgErr.Code[:2]
+	return class == "23" || class == "22"
+}
+
+func (r *studentRepository) copyStudents(ctx context.Context, rows, links [][]interface{}) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.CopyFrom(ctx, pgx.Identifier{"students"}, studentCopyColumns, pgx.CopyFromRows(rows)); err != nil {
+		return fmt.Errorf("failed to copy students: %w", err)
+	}
+	if len(links) > 0 {
+		columns := []string{"id", "student_id", "university_id", "degree_obtained", "graduation_year", "created_at"}
+		if _, err := tx.CopyFrom(ctx, pgx.Identifier{"student_universities"}, columns, pgx.CopyFromRows(links)); err != nil {
+			return fmt.Errorf("failed to copy student universities: %w", err)
+		}
+	}
+	return tx.Commit(ctx)
+}
+
+// studentDetailColumns is the column list scanned by scanStudentDetail.
+const studentDetailColumns = `
 			id, first_names, last_names, document_id, birth_date, profile_photo_url,
 			gender, nationality_country_id, residence_country_id, residence_city_id,
 			emails, phones, company_id, job_title_category_id, profession_id,
 			student_code, status, cohort, enrollment_date, graduation_date,
-			created_at, created_by, updated_at, updated_by, deleted_at, deleted_by
-		FROM students
-		WHERE id = $1 AND deleted_at IS NULL
-	`
+			created_at, created_by, updated_at, updated_by, deleted_at, deleted_by`
 
+func scanStudentDetail(row pgx.Row) (*models.Student, error) {
 	student := &models.Student{}
-	err := r.db.QueryRow(ctx, query, id).Scan(
+	err := row.Scan(
 		&student.ID,
 		&student.FirstNames,
 		&student.LastNames,
@@ -125,57 +269,72 @@ func (r *studentRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.
 		&student.DeletedAt,
 		&student.DeletedBy,
 	)
-
 	if err != nil {
 		if err == pgx.ErrNoRows {
 			return nil, fmt.Errorf("student not found")
 		}
 		return nil, fmt.Errorf("failed to get student: %w", err)
 	}
-
 	return student, nil
 }
 
-func (r *studentRepository) List(ctx context.Context, filters StudentFilters) ([]*models.Student, error) {
-	query := `
-		SELECT
-			id, first_names, last_names, document_id, birth_date, profile_photo_url,
-			gender, nationality_country_id, residence_country_id, residence_city_id,
-			emails, phones, company_id, job_title_category_id, profession_id,
-			student_code, status, cohort, enrollment_date, graduation_date,
-			created_at, created_by, updated_at, updated_by
+func (r *studentRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.Student, error) {
+	query := "SELECT" + studentDetailColumns + `
 		FROM students
-		WHERE deleted_at IS NULL
+		WHERE id = $1 AND deleted_at IS NULL
 	`
+	return scanStudentDetail(r.db.QueryRow(ctx, query, id))
+}
 
-	args := []interface{}{}
-	argCount := 1
+func (r *studentRepository) GetByStudentCode(ctx context.Context, code string) (*models.Student, error) {
+	query := "SELECT" + studentDetailColumns + `
+		FROM students
+		WHERE student_code = $1 AND deleted_at IS NULL
+	`
+	return scanStudentDetail(r.db.QueryRow(ctx, query, code))
+}
 
-	if filters.Status != nil {
-		query += fmt.Sprintf(" AND status = $%d", argCount)
-		args = append(args, *filters.Status)
-		argCount++
-	}
+// List returns students in filters.Sort order with id as tiebreaker, so pages
+// are stable. With filters.After, it continues from that cursor instead of
+// skipping rows.
+func (r *studentRepository) List(ctx context.Context, filters StudentFilters) ([]*models.Student, error) {
+	where, args := studentWhere(filters)
+	argCount := len(args) + 1
 
-	if filters.Cohort != nil {
-		query += fmt.Sprintf(" AND cohort = $%d", argCount)
-		args = append(args, *filters.Cohort)
+	// Searches are ranked by relevance, selected to build cursors and to sort by
+	relevance := "NULL::float8"
+	if filters.Search != nil {
+		relevance = studentRelevanceAt(argCount)
+		args = append(args, *filters.Search)
 		argCount++
 	}
 
-	if filters.ResidenceCountryID != nil {
-		query += fmt.Sprintf(" AND residence_country_id = $%d", argCount)
-		args = append(args, *filters.ResidenceCountryID)
-		argCount++
-	}
+	query := `
+		SELECT
+			id, first_names, last_names, document_id, birth_date, profile_photo_url,
+			gender, nationality_country_id, residence_country_id, residence_city_id,
+			emails, phones, company_id, job_title_category_id, profession_id,
+			student_code, status, cohort, enrollment_date, graduation_date,
+			created_at, created_by, updated_at, updated_by, ` + relevance + `
+		FROM students` + where
 
-	if filters.Search != nil {
-		query += fmt.Sprintf(" AND (first_names ILIKE $%d OR last_names ILIKE $%d)", argCount, argCount)
-		args = append(args, "%"+*filters.Search+"%")
-		argCount++
+	order := filters.Sort.orDefault()
+	sortKey := order.Column
+	if sortKey == StudentSortRelevance {
+		sortKey = relevance
+	}
+	if filters.After != nil {
+		op := ">"
+		if order.Desc {
+			op = "<"
+		}
+		query += fmt.Sprintf(" AND (%s, id) %s ($%d::text::%s, $%d)",
+			sortKey, op, argCount, studentSortColumns[order.Column], argCount+1)
+		args = append(args, filters.After.Value, filters.After.ID)
+		argCount += 2
 	}
 
-	query += " ORDER BY enrollment_date DESC"
+	query += " ORDER BY " + order.orderBy(sortKey)
 
 	if filters.Limit > 0 {
 		query += fmt.Sprintf(" LIMIT $%d", argCount)
@@ -183,10 +342,9 @@ func (r *studentRepository) List(ctx context.Context, filters StudentFilters) ([
 		argCount++
 	}
 
-	if filters.Offset > 0 {
+	if filters.Offset > 0 && filters.After == nil {
 		query += fmt.Sprintf(" OFFSET $%d", argCount)
 		args = append(args, filters.Offset)
-		argCount++
 	}
 
 	rows, err := r.db.Query(ctx, query, args...)
@@ -223,6 +381,7 @@ func (r *studentRepository) List(ctx context.Context, filters StudentFilters) ([
 			&student.CreatedBy,
 			&student.UpdatedAt,
 			&student.UpdatedBy,
+			&student.Relevance,
 		)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan student row: %w", err)
@@ -237,7 +396,15 @@ func (r *studentRepository) List(ctx context.Context, filters StudentFilters) ([
 	return students, nil
 }
 
+// Update saves the student and, when student.Universities is not nil, replaces
+// its universities, in a single transaction.
 func (r *studentRepository) Update(ctx context.Context, student *models.Student) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
 	query := `
 		UPDATE students
 		SET
@@ -261,7 +428,7 @@ func (r *studentRepository) Update(ctx context.Context, student *models.Student)
 		RETURNING updated_at
 	`
 
-	err := r.db.QueryRow(ctx, query,
+	err = tx.QueryRow(ctx, query,
 		student.ID,
 		student.FirstNames,
 		student.LastNames,
@@ -288,6 +455,18 @@ func (r *studentRepository) Update(ctx context.Context, student *models.Student)
 		return fmt.Errorf("failed to update student: %w", err)
 	}
 
+	if student.Universities != nil {
+		if _, err := tx.Exec(ctx, "DELETE FROM student_universities WHERE student_id = $1", student.ID); err != nil {
+			return fmt.Errorf("failed to delete student universities: %w", err)
+		}
+		if err := insertStudentUniversities(ctx, tx, student.ID, student.Universities); err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
 	return nil
 }
 
@@ -311,34 +490,8 @@ func (r *studentRepository) Delete(ctx context.Context, id uuid.UUID, deletedBy
 }
 
 func (r *studentRepository) Count(ctx context.Context, filters StudentFilters) (int, error) {
-	query := "SELECT COUNT(*) FROM students WHERE deleted_at IS NULL"
-
-	args := []interface{}{}
-	argCount := 1
-
-	if filters.Status != nil {
-		query += fmt.Sprintf(" AND status = $%d", argCount)
-		args = append(args, *filters.Status)
-		argCount++
-	}
-
-	if filters.Cohort != nil {
-		query += fmt.Sprintf(" AND cohort = $%d", argCount)
-		args = append(args, *filters.Cohort)
-		argCount++
-	}
-
-	if filters.ResidenceCountryID != nil {
-		query += fmt.Sprintf(" AND residence_country_id = $%d", argCount)
-		args = append(args, *filters.ResidenceCountryID)
-		argCount++
-	}
-
-	if filters.Search != nil {
-		query += fmt.Sprintf(" AND (first_names ILIKE $%d OR last_names ILIKE $%d)", argCount, argCount)
-		args = append(args, "%"+*filters.Search+"%")
-		argCount++
-	}
+	where, args := studentWhere(filters)
+	query := "SELECT COUNT(*) FROM students" + where
 
 	var count int
 	err := r.db.QueryRow(ctx, query, args...).Scan(&count)
@@ -372,13 +525,24 @@ func (r *studentRepository) ExistingDocumentIDs(ctx context.Context, documentIDs
 	return result, rows.Err()
 }
 
+// ExistingEmails returns which of the emails belong to a student, ignoring case.
+// The keys are lowercase.
 func (r *studentRepository) ExistingEmails(ctx context.Context, emails []string) (map[string]bool, error) {
 	if len(emails) == 0 {
 		return map[string]bool{}, nil
 	}
 
-	query := "SELECT UNNEST(emails) AS email FROM students WHERE deleted_at IS NULL AND emails && $1"
-	rows, err := r.db.Query(ctx, query, emails)
+	lowered := make([]string, len(emails))
+	for i, email := range emails {
+		lowered[i] = strings.ToLower(email)
+	}
+
+	query := `
+		SELECT DISTINCT LOWER(e)
+		FROM students, UNNEST(emails) AS e
+		WHERE deleted_at IS NULL AND LOWER(e) = ANY($1)
+	`
+	rows, err := r.db.Query(ctx, query, lowered)
 	if err != nil {
 		return nil, fmt.Errorf("failed to query existing emails: %w", err)
 	}
@@ -394,3 +558,124 @@ func (r *studentRepository) ExistingEmails(ctx context.Context, emails []string)
 	}
 	return result, rows.Err()
 }
+
+func (r *studentRepository) ListUniversities(ctx context.Context, studentID uuid.UUID) ([]models.StudentUniversity, error) {
+	query := `
+		SELECT su.id, su.student_id, su.university_id, u.name, su.degree_obtained, su.graduation_year, su.created_at
+		FROM student_universities su
+		JOIN universities u ON u.id = su.university_id
+		WHERE su.student_id = $1
+		ORDER BY su.graduation_year NULLS LAST, u.name
+	`
+	rows, err := r.db.Query(ctx, query, studentID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list student universities: %w", err)
+	}
+	defer rows.Close()
+
+	universities := []models.StudentUniversity{}
+	for rows.Next() {
+		var u models.StudentUniversity
+		if err := rows.Scan(&u.ID, &u.StudentID, &u.UniversityID, &u.UniversityName, &u.DegreeObtained, &u.GraduationYear, &u.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan student university: %w", err)
+		}
+		universities = append(universities, u)
+	}
+	return universities, rows.Err()
+}
+
+// GetCatalogs returns the catalog entries the student references.
+func (r *studentRepository) GetCatalogs(ctx context.Context, studentID uuid.UUID) (*models.StudentCatalogs, error) {
+	query := `
+		SELECT
+			n.id, n.name, n.code,
+			rc.id, rc.name, rc.code,
+			ci.id, ci.name, ci.country_id,
+			co.id, co.name,
+			p.id, p.name,
+			j.id, j.name
+		FROM students s
+		LEFT JOIN countries n ON n.id = s.nationality_country_id
+		LEFT JOIN countries rc ON rc.id = s.residence_country_id
+		LEFT JOIN cities ci ON ci.id = s.residence_city_id
+		LEFT JOIN companies co ON co.id = s.company_id
+		LEFT JOIN professions p ON p.id = s.profession_id
+		LEFT JOIN job_title_categories j ON j.id = s.job_title_category_id
+		WHERE s.id = $1 AND s.deleted_at IS NULL
+	`
+
+	var nationality, residenceCountry, residenceCity, company, profession, jobTitle catalogRef
+	var cityCountryID *uuid.UUID
+	err := r.db.QueryRow(ctx, query, studentID).Scan(
+		&nationality.id, &nationality.name, &nationality.code,
+		&residenceCountry.id, &residenceCountry.name, &residenceCountry.code,
+		&residenceCity.id, &residenceCity.name, &cityCountryID,
+		&company.id, &company.name,
+		&profession.id, &profession.name,
+		&jobTitle.id, &jobTitle.name,
+	)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, fmt.Errorf("student not found")
+		}
+		return nil, fmt.Errorf("failed to get student catalogs: %w", err)
+	}
+
+	catalogs := &models.StudentCatalogs{
+		Nationality:      nationality.entry(),
+		ResidenceCountry: residenceCountry.entry(),
+		ResidenceCity:    residenceCity.entry(),
+		Company:          company.entry(),
+		Profession:       profession.entry(),
+		JobTitleCategory: jobTitle.entry(),
+	}
+	if catalogs.ResidenceCity != nil {
+		catalogs.ResidenceCity.CountryID = cityCountryID
+	}
+	return catalogs, nil
+}
+
+// catalogRef scans an optional catalog entry from a LEFT JOIN.
+type catalogRef struct {
+	id   *uuid.UUID
+	name *string
+	code *string
+}
+
+func (c catalogRef) entry() *models.CatalogEntry {
+	if c.id == nil || c.name == nil {
+		return nil
+	}
+	return &models.CatalogEntry{ID: *c.id, Name: *c.name, Code: c.code}
+}
+
+// GetEnrollmentSummary counts the student's enrollments by status.
+func (r *studentRepository) GetEnrollmentSummary(ctx context.Context, studentID uuid.UUID) (*models.EnrollmentSummary, error) {
+	query := `
+		SELECT
+			COUNT(*),
+			COUNT(*) FILTER (WHERE status = 'enrolled'),
+			COUNT(*) FILTER (WHERE status = 'completed'),
+			COUNT(*) FILTER (WHERE status = 'withdrawn'),
+			COUNT(*) FILTER (WHERE status = 'failed'),
+			COALESCE(SUM(credits_earned), 0),
+			AVG(final_grade)::float8
+		FROM enrollments
+		WHERE student_id = $1 AND deleted_at IS NULL
+	`
+
+	summary := &models.EnrollmentSummary{}
+	err := r.db.QueryRow(ctx, query, studentID).Scan(
+		&summary.Total,
+		&summary.Enrolled,
+		&summary.Completed,
+		&summary.Withdrawn,
+		&summary.Failed,
+		&summary.CreditsEarned,
+		&summary.AverageGrade,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to summarize student enrollments: %w", err)
+	}
+	return summary, nil
+}
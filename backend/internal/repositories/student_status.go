@@ -0,0 +1,124 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+
+	"github.com/dcorreal/coordinador/internal/models"
+)
+
+// ChangeStatus moves the student from change.FromStatus to change.ToStatus,
+// sets its graduation date and records the change in the status history, in a
+// single transaction. It fails if the status is no longer change.FromStatus or
+// if change.EffectiveDate is before the last change, which for a graduated
+// student without history is its graduation date.
+func (r *studentRepository) ChangeStatus(ctx context.Context, change *models.StudentStatusChange, graduationDate *time.Time) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	// Locking the row keeps a concurrent change from slipping in between
+	var current models.StudentStatus
+	var graduatedOn, lastChange *time.Time
+	err = tx.QueryRow(ctx, `
+		SELECT status, graduation_date,
+			(SELECT MAX(effective_date) FROM student_status_history WHERE student_id = students.id)
+		FROM students
+		WHERE id = $1 AND deleted_at IS NULL
+		FOR UPDATE
+	`, change.StudentID).Scan(&current, &graduatedOn, &lastChange)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return fmt.Errorf("student not found")
+		}
+		return fmt.Errorf("failed to lock student: %w", err)
+	}
+	if current != change.FromStatus {
+		return fmt.Errorf("student status changed to %s meanwhile, expected %s", current, change.FromStatus)
+	}
+	// Changes are recorded in order, so one can't take effect before the previous one
+	if current == models.StudentStatusGraduated && graduatedOn != nil && (lastChange == nil || graduatedOn.After(*lastChange)) {
+		lastChange = graduatedOn
+	}
+	if lastChange != nil && change.EffectiveDate.Before(*lastChange) {
+		return fmt.Errorf("effective_date can't be before the last status change on %s", lastChange.Format("2006-01-02"))
+	}
+
+	_, err = tx.Exec(ctx, `
+		UPDATE students
+		SET status = $2, graduation_date = $3, updated_by = $4
+		WHERE id = $1
+	`, change.StudentID, change.ToStatus, graduationDate, change.CreatedBy)
+	if err != nil {
+		return fmt.Errorf("failed to update student status: %w", err)
+	}
+
+	err = tx.QueryRow(ctx, `
+		INSERT INTO student_status_history (id, student_id, from_status, to_status, reason, effective_date, created_by)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING created_at
+	`,
+		change.ID,
+		change.StudentID,
+		change.FromStatus,
+		change.ToStatus,
+		change.Reason,
+		change.EffectiveDate,
+		change.CreatedBy,
+	).Scan(&change.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to record status change: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit status change: %w", err)
+	}
+	return nil
+}
+
+// ListStatusHistory returns the student's status changes, latest first.
+func (r *studentRepository) ListStatusHistory(ctx context.Context, studentID uuid.UUID) ([]models.StudentStatusChange, error) {
+	query := `
+		SELECT id, student_id, from_status, to_status, reason, effective_date, created_at, created_by
+		FROM student_status_history
+		WHERE student_id = $1
+		ORDER BY effective_date DESC, created_at DESC
+	`
+
+	rows, err := r.db.Query(ctx, query, studentID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list status history: %w", err)
+	}
+	defer rows.Close()
+
+	history := []models.StudentStatusChange{}
+	for rows.Next() {
+		var change models.StudentStatusChange
+		err := rows.Scan(
+			&change.ID,
+			&change.StudentID,
+			&change.FromStatus,
+			&change.ToStatus,
+			&change.Reason,
+			&change.EffectiveDate,
+			&change.CreatedAt,
+			&change.CreatedBy,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan status change: %w", err)
+		}
+		history = append(history, change)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating status history: %w", err)
+	}
+
+	return history, nil
+}
@@ -0,0 +1,147 @@
+package services
+
+import (
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/dcorreal/coordinador/internal/models"
+)
+
+// CatalogCache maps normalized names to catalog entry IDs. It is safe for
+// concurrent use, so one cache can be shared by every import and request that
+// resolves catalog names.
+type CatalogCache struct {
+	ttl time.Duration
+	now func() time.Time
+
+	mu      sync.RWMutex
+	entries map[models.Catalog]map[string]catalogCacheEntry
+	// generation counts the invalidations of each catalog. A lookup that started
+	// before an invalidation must not store what it found.
+	generation map[models.Catalog]uint64
+}
+
+// catalogCacheMaxEntries bounds the names cached per catalog, so imports full
+// of distinct names (e.g. typos) don't grow the cache without limit.
+const catalogCacheMaxEntries = 10000
+
+type catalogCacheEntry struct {
+	id      uuid.UUID
+	expires time.Time
+}
+
+// NewCatalogCache creates an empty cache whose entries expire after ttl.
+// A ttl of zero keeps entries until they are invalidated.
+func NewCatalogCache(ttl time.Duration) *CatalogCache {
+	return &CatalogCache{
+		ttl:        ttl,
+		now:        time.Now,
+		entries:    make(map[models.Catalog]map[string]catalogCacheEntry),
+		generation: make(map[models.Catalog]uint64),
+	}
+}
+
+// Get returns the cached ID of a name, if present and not expired. Expired
+// entries are removed.
+func (c *CatalogCache) Get(catalog models.Catalog, key string) (uuid.UUID, bool) {
+	c.mu.RLock()
+	entry, ok := c.entries[catalog][key]
+	c.mu.RUnlock()
+	if !ok {
+		return uuid.Nil, false
+	}
+	if c.expired(entry) {
+		c.mu.Lock()
+		// Another lookup may have stored a fresh entry meanwhile
+		if entry, ok := c.entries[catalog][key]; ok && c.expired(entry) {
+			delete(c.entries[catalog], key)
+		}
+		c.mu.Unlock()
+		return uuid.Nil, false
+	}
+	return entry.id, true
+}
+
+// Len returns the number of cached names, including expired ones not yet removed.
+func (c *CatalogCache) Len() int {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	n := 0
+	for _, entries := range c.entries {
+		n += len(entries)
+	}
+	return n
+}
+
+func (c *CatalogCache) expired(entry catalogCacheEntry) bool {
+	return !entry.expires.IsZero() && c.now().After(entry.expires)
+}
+
+// Generation returns a token to pass to Set once a lookup completes.
+func (c *CatalogCache) Generation(catalog models.Catalog) uint64 {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.generation[catalog]
+}
+
+// Set caches the ID of a name, unless the catalog was invalidated since
+// generation was taken.
+func (c *CatalogCache) Set(catalog models.Catalog, key string, id uuid.UUID, generation uint64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.generation[catalog] != generation {
+		return
+	}
+	entry := catalogCacheEntry{id: id}
+	if c.ttl > 0 {
+		entry.expires = c.now().Add(c.ttl)
+	}
+	entries := c.entries[catalog]
+	if entries == nil {
+		entries = make(map[string]catalogCacheEntry)
+		c.entries[catalog] = entries
+	}
+	if _, ok := entries[key]; !ok && len(entries) >= catalogCacheMaxEntries {
+		c.prune(entries)
+	}
+	entries[key] = entry
+}
+
+// prune makes room in a full catalog: it removes the expired entries and, if
+// that isn't enough, a tenth of the rest, so pruning doesn't run on every Set.
+func (c *CatalogCache) prune(entries map[string]catalogCacheEntry) {
+	for key, entry := range entries {
+		if c.expired(entry) {
+			delete(entries, key)
+		}
+	}
+	evict := len(entries) - catalogCacheMaxEntries*9/10
+	for key := range entries {
+		if evict <= 0 {
+			break
+		}
+		delete(entries, key)
+		evict--
+	}
+}
+
+// Invalidate forgets the cached entries of a catalog, e.g. after entries were
+// renamed or merged. Cities and universities are cached per country, so
+// invalidating countries forgets them too.
+func (c *CatalogCache) Invalidate(catalog models.Catalog) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	catalogs := []models.Catalog{catalog}
+	if catalog == models.CatalogCountries {
+		catalogs = append(catalogs, models.CatalogCities, models.CatalogUniversities)
+	}
+	for _, cat := range catalogs {
+		delete(c.entries, cat)
+		c.generation[cat]++
+	}
+}
@@ -0,0 +1,98 @@
+package services_test
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/dcorreal/coordinador/internal/models"
+	"github.com/dcorreal/coordinador/internal/services"
+)
+
+func TestCatalogCache_Expires(t *testing.T) {
+	cache := services.NewCatalogCache(time.Millisecond)
+	id := uuid.New()
+
+	cache.Set(models.CatalogCompanies, "ecopetrol", id, cache.Generation(models.CatalogCompanies))
+	got, ok := cache.Get(models.CatalogCompanies, "ecopetrol")
+	assert.True(t, ok)
+	assert.Equal(t, id, got)
+
+	time.Sleep(5 * time.Millisecond)
+	_, ok = cache.Get(models.CatalogCompanies, "ecopetrol")
+	assert.False(t, ok)
+}
+
+func TestCatalogCache_RemovesExpiredEntries(t *testing.T) {
+	cache := services.NewCatalogCache(time.Millisecond)
+	cache.Set(models.CatalogCompanies, "ecopetrol", uuid.New(), cache.Generation(models.CatalogCompanies))
+
+	time.Sleep(5 * time.Millisecond)
+	_, ok := cache.Get(models.CatalogCompanies, "ecopetrol")
+	assert.False(t, ok)
+	assert.Equal(t, 0, cache.Len())
+}
+
+func TestCatalogCache_BoundedSize(t *testing.T) {
+	cache := services.NewCatalogCache(0)
+	generation := cache.Generation(models.CatalogCompanies)
+
+	for i := 0; i < 25000; i++ {
+		cache.Set(models.CatalogCompanies, fmt.Sprintf("empresa %d", i), uuid.New(), generation)
+	}
+
+	assert.LessOrEqual(t, cache.Len(), 10000)
+	_, ok := cache.Get(models.CatalogCompanies, "empresa 24999")
+	assert.True(t, ok, "the latest name is kept")
+}
+
+func TestCatalogCache_InvalidateCountriesForgetsCities(t *testing.T) {
+	cache := services.NewCatalogCache(0)
+	for _, catalog := range []models.Catalog{models.CatalogCountries, models.CatalogCities, models.CatalogUniversities, models.CatalogCompanies} {
+		cache.Set(catalog, "x", uuid.New(), cache.Generation(catalog))
+	}
+
+	cache.Invalidate(models.CatalogCountries)
+
+	for _, catalog := range []models.Catalog{models.CatalogCountries, models.CatalogCities, models.CatalogUniversities} {
+		_, ok := cache.Get(catalog, "x")
+		assert.False(t, ok, catalog)
+	}
+	_, ok := cache.Get(models.CatalogCompanies, "x")
+	assert.True(t, ok)
+}
+
+func TestCatalogCache_IgnoresLookupsStartedBeforeInvalidation(t *testing.T) {
+	cache := services.NewCatalogCache(0)
+
+	generation := cache.Generation(models.CatalogCompanies)
+	cache.Invalidate(models.CatalogCompanies)
+	cache.Set(models.CatalogCompanies, "ecopetrol", uuid.New(), generation)
+
+	_, ok := cache.Get(models.CatalogCompanies, "ecopetrol")
+	assert.False(t, ok)
+}
+
+func TestCatalogCache_ConcurrentAccess(t *testing.T) {
+	cache := services.NewCatalogCache(time.Minute)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				cache.Set(models.CatalogCities, "bogota", uuid.New(), cache.Generation(models.CatalogCities))
+				cache.Get(models.CatalogCities, "bogota")
+				if j%10 == i%10 {
+					cache.Invalidate(models.CatalogCountries)
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+}
@@ -2,36 +2,122 @@ package services
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"strings"
+	"time"
 
 	"github.com/google/uuid"
 
+	"github.com/dcorreal/coordinador/internal/models"
 	"github.com/dcorreal/coordinador/internal/repositories"
+	"github.com/dcorreal/coordinador/internal/shared"
 )
 
+// CatalogResolverOptions controls how names without an exact match are resolved.
+type CatalogResolverOptions struct {
+	// MatchThreshold is the similarity (0 to 1) from which a name resolves to the
+	// closest existing entry, e.g. "Ecopetrol S.A" to "Ecopetrol S.A.".
+	MatchThreshold float64
+	// SuggestThreshold is the similarity from which entries are offered as
+	// suggestions when a name is reported.
+	SuggestThreshold float64
+	// TypoThreshold is the similarity from which a name is taken for a likely
+	// typo of an existing entry, e.g. "Bogta" for "Bogotá", and reported instead
+	// of auto-created. Names that are merely alike, such as two universities
+	// starting with "Universidad", are below it and still created.
+	TypoThreshold float64
+	// MaxSuggestions limits the suggestions returned for a name.
+	MaxSuggestions int
+	// NoAutoCreate lists the catalogs whose unknown names are reported instead of created.
+	NoAutoCreate []models.Catalog
+	// CacheTTL is how long a resolved name is remembered. Zero keeps names until
+	// their catalog is invalidated.
+	CacheTTL time.Duration
+}
+
+// DefaultCatalogResolverOptions returns the options used when none are configured.
+func DefaultCatalogResolverOptions() CatalogResolverOptions {
+	return CatalogResolverOptions{
+		MatchThreshold:   0.9,
+		SuggestThreshold: 0.4,
+		TypoThreshold:    0.7,
+		MaxSuggestions:   3,
+		CacheTTL:         10 * time.Minute,
+	}
+}
+
+// CatalogNotFoundError reports a name that matches no catalog entry and was not
+// auto-created, with the most similar entries as suggestions.
+type CatalogNotFoundError struct {
+	Catalog     models.Catalog
+	Name        string
+	Suggestions []string
+	// Reason optionally explains why the name could not be created
+	Reason string
+}
+
+func (e *CatalogNotFoundError) Error() string {
+	msg := fmt.Sprintf("unknown %s %q", e.Catalog.Singular(), e.Name)
+	if len(e.Suggestions) > 0 {
+		msg += fmt.Sprintf(", did you mean %s?", strings.Join(e.Suggestions, " or "))
+	} else if e.Reason != "" {
+		msg += ": " + e.Reason
+	}
+	return msg
+}
+
+// catalogSuggestions returns the suggestions carried by a CatalogNotFoundError.
+func catalogSuggestions(err error) []string {
+	var notFound *CatalogNotFoundError
+	if errors.As(err, &notFound) {
+		return notFound.Suggestions
+	}
+	return nil
+}
+
 // CatalogResolver resolves human-readable names to UUIDs, auto-creating missing entries.
+// It is safe for concurrent use; one resolver is shared by all imports and by the
+// catalog service, which invalidates it when entries change.
 type CatalogResolver struct {
-	repo repositories.CatalogRepository
+	repo  repositories.CatalogRepository
+	opts  CatalogResolverOptions
+	cache *CatalogCache
 
-	countryCache  map[string]uuid.UUID
-	cityCache     map[string]uuid.UUID
-	profCache     map[string]uuid.UUID
-	jobCache      map[string]uuid.UUID
-	uniCache      map[string]uuid.UUID
+	noAutoCreate map[models.Catalog]bool
+	// createSimilar creates names even when they look like a typo of an entry
+	createSimilar bool
 }
 
-// NewCatalogResolver creates a new CatalogResolver with empty caches.
-func NewCatalogResolver(repo repositories.CatalogRepository) *CatalogResolver {
+// NewCatalogResolver creates a new CatalogResolver with an empty cache.
+func NewCatalogResolver(repo repositories.CatalogRepository, opts CatalogResolverOptions) *CatalogResolver {
+	noAutoCreate := make(map[models.Catalog]bool, len(opts.NoAutoCreate))
+	for _, c := range opts.NoAutoCreate {
+		noAutoCreate[c] = true
+	}
 	return &CatalogResolver{
 		repo:         repo,
-		countryCache: make(map[string]uuid.UUID),
-		cityCache:    make(map[string]uuid.UUID),
-		profCache:    make(map[string]uuid.UUID),
-		jobCache:     make(map[string]uuid.UUID),
-		uniCache:     make(map[string]uuid.UUID),
+		opts:         opts,
+		cache:        NewCatalogCache(opts.CacheTTL),
+		noAutoCreate: noAutoCreate,
 	}
 }
 
+// CreatingSimilar returns a resolver sharing r's cache that creates names even
+// when they look like a typo of an existing entry. Imports use it when the user
+// confirmed that the reported names are new.
+func (r *CatalogResolver) CreatingSimilar() *CatalogResolver {
+	similar := *r
+	similar.createSimilar = true
+	return &similar
+}
+
+// Invalidate forgets the cached entries of a catalog, e.g. after entries were
+// renamed or merged.
+func (r *CatalogResolver) Invalidate(catalog models.Catalog) {
+	r.cache.Invalidate(catalog)
+}
+
 func cacheKey(parts ...string) string {
 	normalized := make([]string, len(parts))
 	for i, p := range parts {
@@ -40,122 +126,186 @@ func cacheKey(parts ...string) string {
 	return strings.Join(normalized, "|")
 }
 
-func (r *CatalogResolver) ResolveCountry(ctx context.Context, name string) (uuid.UUID, error) {
-	name = strings.TrimSpace(name)
-	if name == "" {
-		return uuid.Nil, nil
-	}
-	key := cacheKey(name)
-	if id, ok := r.countryCache[key]; ok {
+// catalogLookup describes how to find and create an entry of one catalog.
+type catalogLookup struct {
+	catalog   models.Catalog
+	countryID *uuid.UUID
+	key       string
+	find      func() (uuid.UUID, error)
+	// known optionally resolves names that identify an entry without being its
+	// stored name, such as ISO country names and codes
+	known func() (uuid.UUID, error)
+	// create is nil for catalogs whose entries can't be created from a name,
+	// with notCreatable explaining why
+	create       func() (uuid.UUID, error)
+	notCreatable string
+}
+
+// resolve looks a name up by exact (unaccented, case-insensitive) match, then by
+// alias, then by similarity, and creates it unless it looks like a typo of an
+// existing entry or the catalog doesn't allow it.
+func (r *CatalogResolver) resolve(ctx context.Context, name string, l catalogLookup) (uuid.UUID, error) {
+	if id, ok := r.cache.Get(l.catalog, l.key); ok {
 		return id, nil
 	}
+	generation := r.cache.Generation(l.catalog)
 
-	id, err := r.repo.FindCountryByName(ctx, name)
+	id, err := l.find()
 	if err != nil {
 		return uuid.Nil, err
 	}
 	if id == uuid.Nil {
-		id, err = r.repo.CreateCountry(ctx, name)
+		id, err = r.repo.FindByAlias(ctx, l.catalog, name, l.countryID)
 		if err != nil {
 			return uuid.Nil, err
 		}
 	}
-	r.countryCache[key] = id
-	return id, nil
-}
-
-func (r *CatalogResolver) ResolveCity(ctx context.Context, name string, countryID uuid.UUID) (uuid.UUID, error) {
-	name = strings.TrimSpace(name)
-	if name == "" {
-		return uuid.Nil, nil
+	if id == uuid.Nil && l.known != nil {
+		id, err = l.known()
+		if err != nil {
+			return uuid.Nil, err
+		}
 	}
-	key := cacheKey(name, countryID.String())
-	if id, ok := r.cityCache[key]; ok {
+	if id != uuid.Nil {
+		r.cache.Set(l.catalog, l.key, id, generation)
 		return id, nil
 	}
 
-	id, err := r.repo.FindCityByName(ctx, name, countryID)
+	matches, err := r.repo.FindSimilar(ctx, l.catalog, name, l.countryID, r.opts.SuggestThreshold, r.opts.MaxSuggestions)
 	if err != nil {
 		return uuid.Nil, err
 	}
-	if id == uuid.Nil {
-		id, err = r.repo.CreateCity(ctx, name, countryID)
-		if err != nil {
-			return uuid.Nil, err
+	if len(matches) > 0 && matches[0].Similarity >= r.opts.MatchThreshold {
+		r.cache.Set(l.catalog, l.key, matches[0].ID, generation)
+		return matches[0].ID, nil
+	}
+	likelyTypo := len(matches) > 0 && matches[0].Similarity >= r.opts.TypoThreshold && !r.createSimilar
+	if likelyTypo || r.noAutoCreate[l.catalog] || l.create == nil {
+		notFound := &CatalogNotFoundError{Catalog: l.catalog, Name: name}
+		if !r.noAutoCreate[l.catalog] {
+			notFound.Reason = l.notCreatable
+		}
+		for _, m := range matches {
+			notFound.Suggestions = append(notFound.Suggestions, m.Name)
 		}
+		return uuid.Nil, notFound
+	}
+
+	id, err = l.create()
+	if err != nil {
+		return uuid.Nil, err
 	}
-	r.cityCache[key] = id
+	r.cache.Set(l.catalog, l.key, id, generation)
 	return id, nil
 }
 
-func (r *CatalogResolver) ResolveProfession(ctx context.Context, name string) (uuid.UUID, error) {
+func (r *CatalogResolver) ResolveCountry(ctx context.Context, name string) (uuid.UUID, error) {
 	name = strings.TrimSpace(name)
 	if name == "" {
 		return uuid.Nil, nil
 	}
-	key := cacheKey(name)
-	if id, ok := r.profCache[key]; ok {
-		return id, nil
+	return r.resolve(ctx, name, catalogLookup{
+		catalog:      models.CatalogCountries,
+		key:          cacheKey(name),
+		find:         func() (uuid.UUID, error) { return r.repo.FindCountryByName(ctx, name) },
+		known:        func() (uuid.UUID, error) { return r.resolveISOCountry(ctx, name) },
+		notCreatable: "not an ISO 3166 country name or code",
+	})
+}
+
+// resolveISOCountry resolves an ISO 3166 country name or code to the country with
+// that code, creating it with its ISO code and Spanish name if needed. It returns
+// uuid.Nil for names that aren't ISO countries.
+func (r *CatalogResolver) resolveISOCountry(ctx context.Context, name string) (uuid.UUID, error) {
+	iso, ok := shared.LookupCountry(name)
+	if !ok {
+		return uuid.Nil, nil
 	}
 
-	id, err := r.repo.FindProfessionByName(ctx, name)
-	if err != nil {
-		return uuid.Nil, err
+	id, err := r.repo.FindCountryByCode(ctx, iso.Alpha3)
+	if err != nil || id != uuid.Nil {
+		return id, err
 	}
-	if id == uuid.Nil {
-		id, err = r.repo.CreateProfession(ctx, name)
-		if err != nil {
-			return uuid.Nil, err
+	// Countries created before codes were checked may have the name but another code
+	for _, isoName := range []string{iso.NameES, iso.NameEN} {
+		if strings.EqualFold(isoName, name) {
+			continue
+		}
+		id, err := r.repo.FindCountryByName(ctx, isoName)
+		if err != nil || id != uuid.Nil {
+			return id, err
 		}
 	}
-	r.profCache[key] = id
-	return id, nil
+
+	if r.noAutoCreate[models.CatalogCountries] {
+		return uuid.Nil, nil
+	}
+	return r.repo.FindOrCreateCountry(ctx, iso.Alpha3, iso.NameES)
 }
 
-func (r *CatalogResolver) ResolveJobTitleCategory(ctx context.Context, name string) (uuid.UUID, error) {
+func (r *CatalogResolver) ResolveCity(ctx context.Context, name string, countryID uuid.UUID) (uuid.UUID, error) {
 	name = strings.TrimSpace(name)
 	if name == "" {
 		return uuid.Nil, nil
 	}
-	key := cacheKey(name)
-	if id, ok := r.jobCache[key]; ok {
-		return id, nil
-	}
+	return r.resolve(ctx, name, catalogLookup{
+		catalog:   models.CatalogCities,
+		countryID: &countryID,
+		key:       cacheKey(name, countryID.String()),
+		find:      func() (uuid.UUID, error) { return r.repo.FindCityByName(ctx, name, countryID) },
+		create:    func() (uuid.UUID, error) { return r.repo.FindOrCreateCity(ctx, name, countryID) },
+	})
+}
 
-	id, err := r.repo.FindJobTitleCategoryByName(ctx, name)
-	if err != nil {
-		return uuid.Nil, err
-	}
-	if id == uuid.Nil {
-		id, err = r.repo.CreateJobTitleCategory(ctx, name)
-		if err != nil {
-			return uuid.Nil, err
-		}
+func (r *CatalogResolver) ResolveProfession(ctx context.Context, name string) (uuid.UUID, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return uuid.Nil, nil
 	}
-	r.jobCache[key] = id
-	return id, nil
+	return r.resolve(ctx, name, catalogLookup{
+		catalog: models.CatalogProfessions,
+		key:     cacheKey(name),
+		find:    func() (uuid.UUID, error) { return r.repo.FindProfessionByName(ctx, name) },
+		create:  func() (uuid.UUID, error) { return r.repo.FindOrCreateProfession(ctx, name) },
+	})
 }
 
-func (r *CatalogResolver) ResolveUniversity(ctx context.Context, name string, cityID *uuid.UUID, countryID uuid.UUID) (uuid.UUID, error) {
+func (r *CatalogResolver) ResolveJobTitleCategory(ctx context.Context, name string) (uuid.UUID, error) {
 	name = strings.TrimSpace(name)
 	if name == "" {
 		return uuid.Nil, nil
 	}
-	key := cacheKey(name, countryID.String())
-	if id, ok := r.uniCache[key]; ok {
-		return id, nil
-	}
+	return r.resolve(ctx, name, catalogLookup{
+		catalog: models.CatalogJobTitleCategories,
+		key:     cacheKey(name),
+		find:    func() (uuid.UUID, error) { return r.repo.FindJobTitleCategoryByName(ctx, name) },
+		create:  func() (uuid.UUID, error) { return r.repo.FindOrCreateJobTitleCategory(ctx, name) },
+	})
+}
 
-	id, err := r.repo.FindUniversityByName(ctx, name, countryID)
-	if err != nil {
-		return uuid.Nil, err
+func (r *CatalogResolver) ResolveCompany(ctx context.Context, name string) (uuid.UUID, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return uuid.Nil, nil
 	}
-	if id == uuid.Nil {
-		id, err = r.repo.CreateUniversity(ctx, name, cityID, countryID)
-		if err != nil {
-			return uuid.Nil, err
-		}
+	return r.resolve(ctx, name, catalogLookup{
+		catalog: models.CatalogCompanies,
+		key:     cacheKey(name),
+		find:    func() (uuid.UUID, error) { return r.repo.FindCompanyByName(ctx, name) },
+		create:  func() (uuid.UUID, error) { return r.repo.FindOrCreateCompany(ctx, name) },
+	})
+}
+
+func (r *CatalogResolver) ResolveUniversity(ctx context.Context, name string, cityID *uuid.UUID, countryID uuid.UUID) (uuid.UUID, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return uuid.Nil, nil
 	}
-	r.uniCache[key] = id
-	return id, nil
+	return r.resolve(ctx, name, catalogLookup{
+		catalog:   models.CatalogUniversities,
+		countryID: &countryID,
+		key:       cacheKey(name, countryID.String()),
+		find:      func() (uuid.UUID, error) { return r.repo.FindUniversityByName(ctx, name, countryID) },
+		create:    func() (uuid.UUID, error) { return r.repo.FindOrCreateUniversity(ctx, name, cityID, countryID) },
+	})
 }
@@ -0,0 +1,213 @@
+package services_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dcorreal/coordinador/internal/models"
+	"github.com/dcorreal/coordinador/internal/repositories/mocks"
+	"github.com/dcorreal/coordinador/internal/services"
+)
+
+func TestResolveCountry_ExactMatch(t *testing.T) {
+	catalogRepo := new(mocks.CatalogRepository)
+	resolver := services.NewCatalogResolver(catalogRepo, services.DefaultCatalogResolverOptions())
+
+	countryID := uuid.New()
+	catalogRepo.On("FindCountryByName", mock.Anything, "Colombia").Return(countryID, nil).Once()
+
+	id, err := resolver.ResolveCountry(context.Background(), " Colombia ")
+	require.NoError(t, err)
+	assert.Equal(t, countryID, id)
+
+	// Cached: the repository is not queried again
+	id, err = resolver.ResolveCountry(context.Background(), "colombia")
+	require.NoError(t, err)
+	assert.Equal(t, countryID, id)
+	catalogRepo.AssertExpectations(t)
+}
+
+func TestResolveCountry_Alias(t *testing.T) {
+	catalogRepo := new(mocks.CatalogRepository)
+	resolver := services.NewCatalogResolver(catalogRepo, services.DefaultCatalogResolverOptions())
+
+	usaID := uuid.New()
+	catalogRepo.On("FindCountryByName", mock.Anything, "EE.UU.").Return(uuid.Nil, nil)
+	catalogRepo.On("FindByAlias", mock.Anything, models.CatalogCountries, "EE.UU.", (*uuid.UUID)(nil)).Return(usaID, nil).Once()
+
+	id, err := resolver.ResolveCountry(context.Background(), "EE.UU.")
+
+	require.NoError(t, err)
+	assert.Equal(t, usaID, id)
+	catalogRepo.AssertNotCalled(t, "FindSimilar", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	catalogRepo.AssertNotCalled(t, "FindOrCreateCountry", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestResolveCompany_CloseMatch(t *testing.T) {
+	catalogRepo := new(mocks.CatalogRepository)
+	resolver := services.NewCatalogResolver(catalogRepo, services.DefaultCatalogResolverOptions())
+
+	companyID := uuid.New()
+	catalogRepo.On("FindCompanyByName", mock.Anything, "Ecopetrol S.A").Return(uuid.Nil, nil)
+	catalogRepo.On("FindByAlias", mock.Anything, models.CatalogCompanies, "Ecopetrol S.A", (*uuid.UUID)(nil)).Return(uuid.Nil, nil)
+	catalogRepo.On("FindSimilar", mock.Anything, models.CatalogCompanies, "Ecopetrol S.A", (*uuid.UUID)(nil), 0.4, 3).
+		Return([]models.CatalogMatch{{ID: companyID, Name: "Ecopetrol S.A.", Similarity: 1}}, nil)
+
+	id, err := resolver.ResolveCompany(context.Background(), "Ecopetrol S.A")
+
+	require.NoError(t, err)
+	assert.Equal(t, companyID, id)
+	catalogRepo.AssertNotCalled(t, "FindOrCreateCompany", mock.Anything, mock.Anything)
+}
+
+func TestResolveCountry_ISOCodeOfExistingCountry(t *testing.T) {
+	catalogRepo := new(mocks.CatalogRepository)
+	resolver := services.NewCatalogResolver(catalogRepo, services.DefaultCatalogResolverOptions())
+
+	usaID := uuid.New()
+	catalogRepo.On("FindCountryByName", mock.Anything, "United States of America").Return(uuid.Nil, nil)
+	catalogRepo.On("FindByAlias", mock.Anything, models.CatalogCountries, "United States of America", (*uuid.UUID)(nil)).Return(uuid.Nil, nil)
+	catalogRepo.On("FindCountryByCode", mock.Anything, "USA").Return(usaID, nil).Once()
+
+	id, err := resolver.ResolveCountry(context.Background(), "United States of America")
+
+	require.NoError(t, err)
+	assert.Equal(t, usaID, id)
+	catalogRepo.AssertNotCalled(t, "FindOrCreateCountry", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestResolveCountry_CreatesWithISOCode(t *testing.T) {
+	catalogRepo := new(mocks.CatalogRepository)
+	resolver := services.NewCatalogResolver(catalogRepo, services.DefaultCatalogResolverOptions())
+
+	germanyID := uuid.New()
+	catalogRepo.On("FindCountryByName", mock.Anything, "Germany").Return(uuid.Nil, nil)
+	catalogRepo.On("FindByAlias", mock.Anything, models.CatalogCountries, "Germany", (*uuid.UUID)(nil)).Return(uuid.Nil, nil)
+	catalogRepo.On("FindCountryByCode", mock.Anything, "DEU").Return(uuid.Nil, nil)
+	catalogRepo.On("FindCountryByName", mock.Anything, "Alemania").Return(uuid.Nil, nil)
+	catalogRepo.On("FindOrCreateCountry", mock.Anything, "DEU", "Alemania").Return(germanyID, nil).Once()
+
+	id, err := resolver.ResolveCountry(context.Background(), "Germany")
+
+	require.NoError(t, err)
+	assert.Equal(t, germanyID, id)
+	catalogRepo.AssertExpectations(t)
+}
+
+func TestResolveCountry_NotISO(t *testing.T) {
+	catalogRepo := new(mocks.CatalogRepository)
+	resolver := services.NewCatalogResolver(catalogRepo, services.DefaultCatalogResolverOptions())
+
+	catalogRepo.On("FindCountryByName", mock.Anything, "Wakanda").Return(uuid.Nil, nil)
+	catalogRepo.On("FindByAlias", mock.Anything, models.CatalogCountries, "Wakanda", (*uuid.UUID)(nil)).Return(uuid.Nil, nil)
+	catalogRepo.On("FindSimilar", mock.Anything, models.CatalogCountries, "Wakanda", (*uuid.UUID)(nil), 0.4, 3).
+		Return([]models.CatalogMatch{}, nil)
+
+	_, err := resolver.ResolveCountry(context.Background(), "Wakanda")
+
+	require.Error(t, err)
+	assert.Equal(t, `unknown country "Wakanda": not an ISO 3166 country name or code`, err.Error())
+	catalogRepo.AssertNotCalled(t, "FindOrCreateCountry", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestResolveCity_SuggestsInsteadOfCreating(t *testing.T) {
+	catalogRepo := new(mocks.CatalogRepository)
+	resolver := services.NewCatalogResolver(catalogRepo, services.DefaultCatalogResolverOptions())
+
+	countryID := uuid.New()
+	catalogRepo.On("FindCityByName", mock.Anything, "Bogta", countryID).Return(uuid.Nil, nil)
+	catalogRepo.On("FindByAlias", mock.Anything, models.CatalogCities, "Bogta", &countryID).Return(uuid.Nil, nil)
+	catalogRepo.On("FindSimilar", mock.Anything, models.CatalogCities, "Bogta", &countryID, 0.4, 3).
+		Return([]models.CatalogMatch{
+			{ID: uuid.New(), Name: "Bogotá", Similarity: 0.75},
+			{ID: uuid.New(), Name: "Bogotá D.C.", Similarity: 0.45},
+		}, nil)
+
+	_, err := resolver.ResolveCity(context.Background(), "Bogta", countryID)
+
+	var notFound *services.CatalogNotFoundError
+	require.ErrorAs(t, err, &notFound)
+	assert.Equal(t, []string{"Bogotá", "Bogotá D.C."}, notFound.Suggestions)
+	assert.Equal(t, `unknown city "Bogta", did you mean Bogotá or Bogotá D.C.?`, err.Error())
+	catalogRepo.AssertNotCalled(t, "FindOrCreateCity", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestResolveUniversity_CreatesAlikeName(t *testing.T) {
+	catalogRepo := new(mocks.CatalogRepository)
+	resolver := services.NewCatalogResolver(catalogRepo, services.DefaultCatalogResolverOptions())
+
+	countryID := uuid.New()
+	universityID := uuid.New()
+	name := "Universidad del Rosario"
+	catalogRepo.On("FindUniversityByName", mock.Anything, name, countryID).Return(uuid.Nil, nil)
+	catalogRepo.On("FindByAlias", mock.Anything, models.CatalogUniversities, name, &countryID).Return(uuid.Nil, nil)
+	catalogRepo.On("FindSimilar", mock.Anything, models.CatalogUniversities, name, &countryID, 0.4, 3).
+		Return([]models.CatalogMatch{{ID: uuid.New(), Name: "Universidad del Valle", Similarity: 0.5}}, nil)
+	catalogRepo.On("FindOrCreateUniversity", mock.Anything, name, (*uuid.UUID)(nil), countryID).Return(universityID, nil).Once()
+
+	id, err := resolver.ResolveUniversity(context.Background(), name, nil, countryID)
+
+	require.NoError(t, err)
+	assert.Equal(t, universityID, id)
+	catalogRepo.AssertExpectations(t)
+}
+
+func TestResolveCity_CreatingSimilar(t *testing.T) {
+	catalogRepo := new(mocks.CatalogRepository)
+	resolver := services.NewCatalogResolver(catalogRepo, services.DefaultCatalogResolverOptions()).CreatingSimilar()
+
+	countryID := uuid.New()
+	cityID := uuid.New()
+	catalogRepo.On("FindCityByName", mock.Anything, "Bogta", countryID).Return(uuid.Nil, nil)
+	catalogRepo.On("FindByAlias", mock.Anything, models.CatalogCities, "Bogta", &countryID).Return(uuid.Nil, nil)
+	catalogRepo.On("FindSimilar", mock.Anything, models.CatalogCities, "Bogta", &countryID, 0.4, 3).
+		Return([]models.CatalogMatch{{ID: uuid.New(), Name: "Bogotá", Similarity: 0.75}}, nil)
+	catalogRepo.On("FindOrCreateCity", mock.Anything, "Bogta", countryID).Return(cityID, nil).Once()
+
+	id, err := resolver.ResolveCity(context.Background(), "Bogta", countryID)
+
+	require.NoError(t, err)
+	assert.Equal(t, cityID, id)
+	catalogRepo.AssertExpectations(t)
+}
+
+func TestResolveProfession_CreatesWhenNothingSimilar(t *testing.T) {
+	catalogRepo := new(mocks.CatalogRepository)
+	resolver := services.NewCatalogResolver(catalogRepo, services.DefaultCatalogResolverOptions())
+
+	professionID := uuid.New()
+	catalogRepo.On("FindProfessionByName", mock.Anything, "Economista").Return(uuid.Nil, nil)
+	catalogRepo.On("FindByAlias", mock.Anything, models.CatalogProfessions, "Economista", (*uuid.UUID)(nil)).Return(uuid.Nil, nil)
+	catalogRepo.On("FindSimilar", mock.Anything, models.CatalogProfessions, "Economista", (*uuid.UUID)(nil), 0.4, 3).
+		Return([]models.CatalogMatch{}, nil)
+	catalogRepo.On("FindOrCreateProfession", mock.Anything, "Economista").Return(professionID, nil).Once()
+
+	id, err := resolver.ResolveProfession(context.Background(), "Economista")
+
+	require.NoError(t, err)
+	assert.Equal(t, professionID, id)
+	catalogRepo.AssertExpectations(t)
+}
+
+func TestResolveCountry_NoAutoCreate(t *testing.T) {
+	catalogRepo := new(mocks.CatalogRepository)
+	opts := services.DefaultCatalogResolverOptions()
+	opts.NoAutoCreate = []models.Catalog{models.CatalogCountries}
+	resolver := services.NewCatalogResolver(catalogRepo, opts)
+
+	catalogRepo.On("FindCountryByName", mock.Anything, "Atlantis").Return(uuid.Nil, nil)
+	catalogRepo.On("FindByAlias", mock.Anything, models.CatalogCountries, "Atlantis", (*uuid.UUID)(nil)).Return(uuid.Nil, nil)
+	catalogRepo.On("FindSimilar", mock.Anything, models.CatalogCountries, "Atlantis", (*uuid.UUID)(nil), 0.4, 3).
+		Return([]models.CatalogMatch{}, nil)
+
+	_, err := resolver.ResolveCountry(context.Background(), "Atlantis")
+
+	require.Error(t, err)
+	assert.Equal(t, `unknown country "Atlantis"`, err.Error())
+	catalogRepo.AssertNotCalled(t, "FindOrCreateCountry", mock.Anything, mock.Anything, mock.Anything)
+}
@@ -0,0 +1,265 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/google/uuid"
+
+	"github.com/dcorreal/coordinador/internal/models"
+	"github.com/dcorreal/coordinador/internal/repositories"
+)
+
+// CatalogService defines the business logic interface for catalog maintenance.
+type CatalogService interface {
+	ListEntries(ctx context.Context, catalog models.Catalog, filters repositories.CatalogFilters, withUsage bool) ([]models.CatalogEntry, int, error)
+	GetEntry(ctx context.Context, catalog models.Catalog, id uuid.UUID) (*models.CatalogEntry, error)
+	UpdateEntry(ctx context.Context, catalog models.Catalog, id uuid.UUID, req *models.UpdateCatalogEntryRequest) (*models.CatalogEntry, error)
+	DeleteEntry(ctx context.Context, catalog models.Catalog, id uuid.UUID, reassignTo *uuid.UUID) error
+	ListAliases(ctx context.Context, catalog models.Catalog, entryID *uuid.UUID) ([]models.CatalogAlias, error)
+	CreateAlias(ctx context.Context, catalog models.Catalog, req *models.CreateCatalogAliasRequest) (*models.CatalogAlias, error)
+	DeleteAlias(ctx context.Context, catalog models.Catalog, id uuid.UUID) error
+	MergeEntries(ctx context.Context, catalog models.Catalog, req *models.MergeCatalogEntriesRequest) (*models.CatalogMergeResult, error)
+}
+
+// catalogNameMaxLength is the length of the name column of each catalog table.
+var catalogNameMaxLength = map[models.Catalog]int{
+	models.CatalogCountries:          100,
+	models.CatalogCities:             100,
+	models.CatalogProfessions:        150,
+	models.CatalogJobTitleCategories: 100,
+	models.CatalogCompanies:          255,
+	models.CatalogUniversities:       255,
+}
+
+// CatalogEntryInUseError reports an entry that can't be deleted because other
+// rows reference it.
+type CatalogEntryInUseError struct {
+	Catalog models.Catalog
+	Usage   models.CatalogUsage
+}
+
+func (e *CatalogEntryInUseError) Error() string {
+	var refs []string
+	for _, count := range []struct {
+		n                int
+		singular, plural string
+	}{
+		{e.Usage.Students, "student", "students"},
+		{e.Usage.Cities, "city", "cities"},
+		{e.Usage.Universities, "university", "universities"},
+	} {
+		switch {
+		case count.n == 1:
+			refs = append(refs, "1 "+count.singular)
+		case count.n > 1:
+			refs = append(refs, fmt.Sprintf("%d %s", count.n, count.plural))
+		}
+	}
+	return fmt.Sprintf("%s is referenced by %s, reassign them to another entry to delete it",
+		e.Catalog.Singular(), strings.Join(refs, ", "))
+}
+
+type catalogService struct {
+	catalogRepo repositories.CatalogRepository
+	resolver    *CatalogResolver
+}
+
+// NewCatalogService creates a new CatalogService. Changes that can make cached
+// names stale invalidate the resolver used by imports.
+func NewCatalogService(catalogRepo repositories.CatalogRepository, resolver *CatalogResolver) CatalogService {
+	return &catalogService{catalogRepo: catalogRepo, resolver: resolver}
+}
+
+func (s *catalogService) ListEntries(ctx context.Context, catalog models.Catalog, filters repositories.CatalogFilters, withUsage bool) ([]models.CatalogEntry, int, error) {
+	if filters.CountryID != nil && catalog != models.CatalogCities && catalog != models.CatalogUniversities {
+		return nil, 0, fmt.Errorf("country_id filter only applies to cities and universities")
+	}
+	if filters.CityID != nil && catalog != models.CatalogUniversities {
+		return nil, 0, fmt.Errorf("city_id filter only applies to universities")
+	}
+
+	entries, err := s.catalogRepo.ListEntries(ctx, catalog, filters)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	count, err := s.catalogRepo.CountEntries(ctx, catalog, filters)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	if withUsage {
+		ids := make([]uuid.UUID, len(entries))
+		for i, entry := range entries {
+			ids[i] = entry.ID
+		}
+		usages, err := s.catalogRepo.GetUsages(ctx, catalog, ids)
+		if err != nil {
+			return nil, 0, err
+		}
+		for i := range entries {
+			entries[i].Usage = usages[entries[i].ID]
+		}
+	}
+
+	return entries, count, nil
+}
+
+// GetEntry returns an entry with its usage.
+func (s *catalogService) GetEntry(ctx context.Context, catalog models.Catalog, id uuid.UUID) (*models.CatalogEntry, error) {
+	entry, err := s.catalogRepo.GetEntry(ctx, catalog, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if entry.Usage, err = s.catalogRepo.GetUsage(ctx, catalog, id); err != nil {
+		return nil, err
+	}
+	return entry, nil
+}
+
+func (s *catalogService) UpdateEntry(ctx context.Context, catalog models.Catalog, id uuid.UUID, req *models.UpdateCatalogEntryRequest) (*models.CatalogEntry, error) {
+	if req.Name == nil && req.Description == nil {
+		return nil, fmt.Errorf("name or description is required")
+	}
+	if req.Name != nil {
+		name := strings.TrimSpace(*req.Name)
+		if name == "" {
+			return nil, fmt.Errorf("name must not be empty")
+		}
+		if max := catalogNameMaxLength[catalog]; len(name) > max {
+			return nil, fmt.Errorf("name must be at most %d characters", max)
+		}
+		req.Name = &name
+	}
+	if req.Description != nil {
+		if catalog != models.CatalogJobTitleCategories {
+			return nil, fmt.Errorf("description only applies to job title categories")
+		}
+		description := strings.TrimSpace(*req.Description)
+		req.Description = &description
+	}
+
+	if err := s.catalogRepo.UpdateEntry(ctx, catalog, id, req); err != nil {
+		return nil, err
+	}
+	if req.Name != nil {
+		s.resolver.Invalidate(catalog)
+		if catalog == models.CatalogCompanies {
+			if err := s.catalogRepo.RefreshStudentsByCompany(ctx); err != nil {
+				log.Printf("catalog rename: %v", err)
+			}
+		}
+	}
+
+	return s.GetEntry(ctx, catalog, id)
+}
+
+// DeleteEntry deletes an entry. An entry in use is only deleted when its references
+// can be reassigned to another entry.
+func (s *catalogService) DeleteEntry(ctx context.Context, catalog models.Catalog, id uuid.UUID, reassignTo *uuid.UUID) error {
+	if reassignTo != nil && *reassignTo == id {
+		return fmt.Errorf("reassign_to must be another entry")
+	}
+	if reassignTo == nil {
+		usage, err := s.catalogRepo.GetUsage(ctx, catalog, id)
+		if err != nil {
+			return err
+		}
+		if usage.InUse() {
+			return &CatalogEntryInUseError{Catalog: catalog, Usage: *usage}
+		}
+	}
+
+	if err := s.catalogRepo.DeleteEntry(ctx, catalog, id, reassignTo); err != nil {
+		return err
+	}
+	s.resolver.Invalidate(catalog)
+
+	if catalog == models.CatalogCompanies {
+		if err := s.catalogRepo.RefreshStudentsByCompany(ctx); err != nil {
+			log.Printf("catalog delete: %v", err)
+		}
+	}
+	return nil
+}
+
+func (s *catalogService) ListAliases(ctx context.Context, catalog models.Catalog, entryID *uuid.UUID) ([]models.CatalogAlias, error) {
+	return s.catalogRepo.ListAliases(ctx, catalog, entryID)
+}
+
+func (s *catalogService) CreateAlias(ctx context.Context, catalog models.Catalog, req *models.CreateCatalogAliasRequest) (*models.CatalogAlias, error) {
+	entryID, err := uuid.Parse(req.EntryID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid entry_id: %w", err)
+	}
+	name := strings.TrimSpace(req.Alias)
+	if name == "" {
+		return nil, fmt.Errorf("alias is required")
+	}
+	if len(name) > 255 {
+		return nil, fmt.Errorf("alias must be at most 255 characters")
+	}
+
+	alias := &models.CatalogAlias{
+		Catalog: catalog,
+		EntryID: entryID,
+		Alias:   name,
+	}
+	if err := s.catalogRepo.CreateAlias(ctx, alias); err != nil {
+		return nil, err
+	}
+	// Names cached before the alias existed may have resolved elsewhere
+	s.resolver.Invalidate(catalog)
+	return alias, nil
+}
+
+func (s *catalogService) DeleteAlias(ctx context.Context, catalog models.Catalog, id uuid.UUID) error {
+	if err := s.catalogRepo.DeleteAlias(ctx, catalog, id); err != nil {
+		return err
+	}
+	s.resolver.Invalidate(catalog)
+	return nil
+}
+
+func (s *catalogService) MergeEntries(ctx context.Context, catalog models.Catalog, req *models.MergeCatalogEntriesRequest) (*models.CatalogMergeResult, error) {
+	canonicalID, err := uuid.Parse(req.CanonicalID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid canonical_id: %w", err)
+	}
+	if len(req.DuplicateIDs) == 0 {
+		return nil, fmt.Errorf("duplicate_ids is required")
+	}
+
+	seen := map[uuid.UUID]bool{canonicalID: true}
+	duplicateIDs := make([]uuid.UUID, 0, len(req.DuplicateIDs))
+	for _, raw := range req.DuplicateIDs {
+		id, err := uuid.Parse(raw)
+		if err != nil {
+			return nil, fmt.Errorf("invalid duplicate_ids: %w", err)
+		}
+		if id == canonicalID {
+			return nil, fmt.Errorf("duplicate_ids must not include canonical_id")
+		}
+		if !seen[id] {
+			seen[id] = true
+			duplicateIDs = append(duplicateIDs, id)
+		}
+	}
+
+	result, err := s.catalogRepo.MergeEntries(ctx, catalog, canonicalID, duplicateIDs)
+	if err != nil {
+		return nil, err
+	}
+	s.resolver.Invalidate(catalog)
+
+	if catalog == models.CatalogCompanies {
+		if err := s.catalogRepo.RefreshStudentsByCompany(ctx); err != nil {
+			log.Printf("catalog merge: %v", err)
+		}
+	}
+	return result, nil
+}
@@ -0,0 +1,286 @@
+package services_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dcorreal/coordinador/internal/models"
+	"github.com/dcorreal/coordinador/internal/repositories"
+	"github.com/dcorreal/coordinador/internal/repositories/mocks"
+	"github.com/dcorreal/coordinador/internal/services"
+)
+
+func newCatalogService(catalogRepo *mocks.CatalogRepository) services.CatalogService {
+	return services.NewCatalogService(catalogRepo, services.NewCatalogResolver(catalogRepo, services.DefaultCatalogResolverOptions()))
+}
+
+func TestCreateAlias_Success(t *testing.T) {
+	catalogRepo := new(mocks.CatalogRepository)
+	service := newCatalogService(catalogRepo)
+
+	entryID := uuid.New()
+	catalogRepo.On("CreateAlias", mock.Anything, mock.MatchedBy(func(a *models.CatalogAlias) bool {
+		return a.Catalog == models.CatalogCountries && a.EntryID == entryID && a.Alias == "EE.UU."
+	})).Return(nil).Once()
+
+	alias, err := service.CreateAlias(context.Background(), models.CatalogCountries, &models.CreateCatalogAliasRequest{
+		EntryID: entryID.String(),
+		Alias:   "  EE.UU. ",
+	})
+
+	require.NoError(t, err)
+	assert.Equal(t, "EE.UU.", alias.Alias)
+	catalogRepo.AssertExpectations(t)
+}
+
+func TestCreateAlias_InvalidatesResolver(t *testing.T) {
+	catalogRepo := new(mocks.CatalogRepository)
+	resolver := services.NewCatalogResolver(catalogRepo, services.DefaultCatalogResolverOptions())
+	service := services.NewCatalogService(catalogRepo, resolver)
+
+	countryID := uuid.New()
+	catalogRepo.On("FindCountryByName", mock.Anything, "Colombia").Return(countryID, nil).Twice()
+	catalogRepo.On("CreateAlias", mock.Anything, mock.Anything).Return(nil).Once()
+
+	_, err := resolver.ResolveCountry(context.Background(), "Colombia")
+	require.NoError(t, err)
+
+	_, err = service.CreateAlias(context.Background(), models.CatalogCountries, &models.CreateCatalogAliasRequest{
+		EntryID: countryID.String(),
+		Alias:   "Republica de Colombia",
+	})
+	require.NoError(t, err)
+
+	// The cached name is looked up again after the alias is created
+	_, err = resolver.ResolveCountry(context.Background(), "Colombia")
+	require.NoError(t, err)
+	catalogRepo.AssertExpectations(t)
+}
+
+func TestCreateAlias_Validation(t *testing.T) {
+	tests := []struct {
+		name    string
+		req     models.CreateCatalogAliasRequest
+		wantErr string
+	}{
+		{"invalid entry", models.CreateCatalogAliasRequest{EntryID: "usa", Alias: "USA"}, "invalid entry_id"},
+		{"empty alias", models.CreateCatalogAliasRequest{EntryID: uuid.New().String(), Alias: "  "}, "alias is required"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			catalogRepo := new(mocks.CatalogRepository)
+			service := newCatalogService(catalogRepo)
+
+			_, err := service.CreateAlias(context.Background(), models.CatalogCountries, &tt.req)
+
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.wantErr)
+			catalogRepo.AssertNotCalled(t, "CreateAlias", mock.Anything, mock.Anything)
+		})
+	}
+}
+
+func TestMergeEntries_Success(t *testing.T) {
+	catalogRepo := new(mocks.CatalogRepository)
+	resolver := services.NewCatalogResolver(catalogRepo, services.DefaultCatalogResolverOptions())
+	service := services.NewCatalogService(catalogRepo, resolver)
+
+	canonicalID, duplicateID := uuid.New(), uuid.New()
+
+	// The resolver has cached the duplicate
+	catalogRepo.On("FindCompanyByName", mock.Anything, "Ecopetrol SA").Return(duplicateID, nil).Once()
+	_, err := resolver.ResolveCompany(context.Background(), "Ecopetrol SA")
+	require.NoError(t, err)
+
+	catalogRepo.On("MergeEntries", mock.Anything, models.CatalogCompanies, canonicalID, []uuid.UUID{duplicateID}).
+		Return(&models.CatalogMergeResult{CanonicalID: canonicalID, Merged: 1, RowsUpdated: 4, Aliases: []string{"Ecopetrol SA"}}, nil).Once()
+	catalogRepo.On("RefreshStudentsByCompany", mock.Anything).Return(nil).Once()
+
+	result, err := service.MergeEntries(context.Background(), models.CatalogCompanies, &models.MergeCatalogEntriesRequest{
+		CanonicalID:  canonicalID.String(),
+		DuplicateIDs: []string{duplicateID.String(), duplicateID.String()},
+	})
+
+	require.NoError(t, err)
+	assert.Equal(t, 1, result.Merged)
+	assert.Equal(t, []string{"Ecopetrol SA"}, result.Aliases)
+
+	// The merged name now resolves through the database again
+	catalogRepo.On("FindCompanyByName", mock.Anything, "Ecopetrol SA").Return(uuid.Nil, nil).Once()
+	catalogRepo.On("FindByAlias", mock.Anything, models.CatalogCompanies, "Ecopetrol SA", (*uuid.UUID)(nil)).Return(canonicalID, nil).Once()
+	id, err := resolver.ResolveCompany(context.Background(), "Ecopetrol SA")
+	require.NoError(t, err)
+	assert.Equal(t, canonicalID, id)
+	catalogRepo.AssertExpectations(t)
+}
+
+func TestMergeEntries_Validation(t *testing.T) {
+	canonicalID := uuid.New().String()
+	tests := []struct {
+		name    string
+		req     models.MergeCatalogEntriesRequest
+		wantErr string
+	}{
+		{"invalid canonical", models.MergeCatalogEntriesRequest{CanonicalID: "x", DuplicateIDs: []string{uuid.New().String()}}, "invalid canonical_id"},
+		{"no duplicates", models.MergeCatalogEntriesRequest{CanonicalID: canonicalID}, "duplicate_ids is required"},
+		{"invalid duplicate", models.MergeCatalogEntriesRequest{CanonicalID: canonicalID, DuplicateIDs: []string{"x"}}, "invalid duplicate_ids"},
+		{"canonical among duplicates", models.MergeCatalogEntriesRequest{CanonicalID: canonicalID, DuplicateIDs: []string{canonicalID}}, "must not include canonical_id"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			catalogRepo := new(mocks.CatalogRepository)
+			service := newCatalogService(catalogRepo)
+
+			_, err := service.MergeEntries(context.Background(), models.CatalogCountries, &tt.req)
+
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.wantErr)
+			catalogRepo.AssertNotCalled(t, "MergeEntries", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+		})
+	}
+}
+
+func TestListEntries_CitiesByCountry(t *testing.T) {
+	catalogRepo := new(mocks.CatalogRepository)
+	service := newCatalogService(catalogRepo)
+
+	countryID := uuid.New()
+	search := "bogo"
+	filters := repositories.CatalogFilters{Search: &search, CountryID: &countryID, Limit: 20}
+	cities := []models.CatalogEntry{{ID: uuid.New(), Name: "Bogotá", CountryID: &countryID}}
+	catalogRepo.On("ListEntries", mock.Anything, models.CatalogCities, filters).Return(cities, nil).Once()
+	catalogRepo.On("CountEntries", mock.Anything, models.CatalogCities, filters).Return(1, nil).Once()
+
+	entries, total, err := service.ListEntries(context.Background(), models.CatalogCities, filters, false)
+
+	require.NoError(t, err)
+	assert.Equal(t, cities, entries)
+	assert.Equal(t, 1, total)
+	catalogRepo.AssertExpectations(t)
+}
+
+func TestListEntries_UsageInOneQuery(t *testing.T) {
+	catalogRepo := new(mocks.CatalogRepository)
+	service := newCatalogService(catalogRepo)
+
+	filters := repositories.CatalogFilters{Limit: 20}
+	first, second := uuid.New(), uuid.New()
+	companies := []models.CatalogEntry{{ID: first, Name: "Bancolombia"}, {ID: second, Name: "Ecopetrol"}}
+	catalogRepo.On("ListEntries", mock.Anything, models.CatalogCompanies, filters).Return(companies, nil).Once()
+	catalogRepo.On("CountEntries", mock.Anything, models.CatalogCompanies, filters).Return(2, nil).Once()
+	catalogRepo.On("GetUsages", mock.Anything, models.CatalogCompanies, []uuid.UUID{first, second}).
+		Return(map[uuid.UUID]*models.CatalogUsage{first: {Students: 4}, second: {}}, nil).Once()
+
+	entries, _, err := service.ListEntries(context.Background(), models.CatalogCompanies, filters, true)
+
+	require.NoError(t, err)
+	assert.Equal(t, 4, entries[0].Usage.Students)
+	assert.Equal(t, 0, entries[1].Usage.Students)
+	catalogRepo.AssertExpectations(t)
+	catalogRepo.AssertNotCalled(t, "GetUsage", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestListEntries_RejectsInapplicableFilters(t *testing.T) {
+	id := uuid.New()
+	tests := []struct {
+		name    string
+		catalog models.Catalog
+		filters repositories.CatalogFilters
+		wantErr string
+	}{
+		{"country of a profession", models.CatalogProfessions, repositories.CatalogFilters{CountryID: &id}, "country_id filter"},
+		{"city of a city", models.CatalogCities, repositories.CatalogFilters{CityID: &id}, "city_id filter"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			catalogRepo := new(mocks.CatalogRepository)
+			service := newCatalogService(catalogRepo)
+
+			_, _, err := service.ListEntries(context.Background(), tt.catalog, tt.filters, false)
+
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.wantErr)
+		})
+	}
+}
+
+func TestDeleteEntry_InUse(t *testing.T) {
+	catalogRepo := new(mocks.CatalogRepository)
+	service := newCatalogService(catalogRepo)
+
+	id := uuid.New()
+	catalogRepo.On("GetUsage", mock.Anything, models.CatalogCountries, id).
+		Return(&models.CatalogUsage{Students: 3, Cities: 1}, nil).Once()
+
+	err := service.DeleteEntry(context.Background(), models.CatalogCountries, id, nil)
+
+	var inUse *services.CatalogEntryInUseError
+	require.ErrorAs(t, err, &inUse)
+	assert.Equal(t, "country is referenced by 3 students, 1 city, reassign them to another entry to delete it", err.Error())
+	catalogRepo.AssertNotCalled(t, "DeleteEntry", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestDeleteEntry_Reassign(t *testing.T) {
+	catalogRepo := new(mocks.CatalogRepository)
+	service := newCatalogService(catalogRepo)
+
+	id, target := uuid.New(), uuid.New()
+	catalogRepo.On("DeleteEntry", mock.Anything, models.CatalogProfessions, id, &target).Return(nil).Once()
+
+	err := service.DeleteEntry(context.Background(), models.CatalogProfessions, id, &target)
+
+	require.NoError(t, err)
+	catalogRepo.AssertExpectations(t)
+	catalogRepo.AssertNotCalled(t, "GetUsage", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestUpdateEntry_RenamingCompanyRefreshesView(t *testing.T) {
+	catalogRepo := new(mocks.CatalogRepository)
+	service := newCatalogService(catalogRepo)
+
+	id := uuid.New()
+	name := "Bancolombia S.A."
+	catalogRepo.On("UpdateEntry", mock.Anything, models.CatalogCompanies, id, mock.Anything).Return(nil).Once()
+	catalogRepo.On("RefreshStudentsByCompany", mock.Anything).Return(nil).Once()
+	catalogRepo.On("GetEntry", mock.Anything, models.CatalogCompanies, id).Return(&models.CatalogEntry{ID: id, Name: name}, nil).Once()
+	catalogRepo.On("GetUsage", mock.Anything, models.CatalogCompanies, id).Return(&models.CatalogUsage{}, nil).Once()
+
+	entry, err := service.UpdateEntry(context.Background(), models.CatalogCompanies, id, &models.UpdateCatalogEntryRequest{Name: &name})
+
+	require.NoError(t, err)
+	assert.Equal(t, name, entry.Name)
+	catalogRepo.AssertExpectations(t)
+}
+
+func TestUpdateEntry_Validation(t *testing.T) {
+	empty, long, description := "  ", strings.Repeat("a", 101), "Directivos"
+	tests := []struct {
+		name    string
+		catalog models.Catalog
+		req     models.UpdateCatalogEntryRequest
+		wantErr string
+	}{
+		{"nothing to update", models.CatalogCountries, models.UpdateCatalogEntryRequest{}, "name or description is required"},
+		{"empty name", models.CatalogCountries, models.UpdateCatalogEntryRequest{Name: &empty}, "must not be empty"},
+		{"name too long", models.CatalogCities, models.UpdateCatalogEntryRequest{Name: &long}, "at most 100"},
+		{"description of a company", models.CatalogCompanies, models.UpdateCatalogEntryRequest{Description: &description}, "only applies to job title categories"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			catalogRepo := new(mocks.CatalogRepository)
+			service := newCatalogService(catalogRepo)
+
+			_, err := service.UpdateEntry(context.Background(), tt.catalog, uuid.New(), &tt.req)
+
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.wantErr)
+			catalogRepo.AssertNotCalled(t, "UpdateEntry", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+		})
+	}
+}
@@ -0,0 +1,206 @@
+package services
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/xuri/excelize/v2"
+
+	"github.com/dcorreal/coordinador/internal/models"
+	"github.com/dcorreal/coordinador/internal/repositories"
+)
+
+// StudentExportService writes students to files that the importer accepts.
+type StudentExportService interface {
+	Export(ctx context.Context, w io.Writer, format string, filters repositories.StudentFilters) error
+}
+
+type studentExportService struct {
+	studentRepo repositories.StudentRepository
+}
+
+// NewStudentExportService creates a new StudentExportService.
+func NewStudentExportService(studentRepo repositories.StudentRepository) StudentExportService {
+	return &studentExportService{studentRepo: studentRepo}
+}
+
+// universityFields are the columns of one university group, without suffix.
+var universityFields = []string{"universidad", "universidad-ciudad", "universidad-pais", "universidad-titulo", "universidad-anio"}
+
+// exportHeader returns the import columns with one university group per
+// university of the student that has the most. Several emails and phones share
+// one column, so the numbered email and phone columns are left out.
+func exportHeader(universities int) []string {
+	var header []string
+	for _, col := range importColumns {
+		if !strings.HasSuffix(col.Name, "_2") {
+			header = append(header, col.Name)
+		}
+	}
+	for n := 2; n <= universities; n++ {
+		for _, field := range universityFields {
+			header = append(header, fmt.Sprintf("%s_%d", field, n))
+		}
+	}
+	return header
+}
+
+// exportRecord returns the values of a student in header order.
+func exportRecord(header []string, row *models.StudentExportRow) []string {
+	values := map[string]string{
+		"first_names":            row.FirstNames,
+		"last_names":             row.LastNames,
+		"document_id":            deref(row.DocumentID),
+		"gender":                 deref(row.Gender),
+		"email":                  strings.Join(row.Emails, "; "),
+		"phone":                  strings.Join(row.Phones, "; "),
+		"nationality_country_id": deref(row.Nationality),
+		"residence_country_id":   deref(row.ResidenceCountry),
+		"residence_city_id":      deref(row.ResidenceCity),
+		"company_id":             deref(row.Company),
+		"job_title_category_id":  deref(row.JobTitleCategory),
+		"profession_id":          deref(row.Profession),
+		"student_code":           deref(row.StudentCode),
+		"status":                 row.Status,
+		"cohort":                 row.Cohort,
+		"enrollment_date":        row.EnrollmentDate.Format(isoDateLayout),
+	}
+	if row.BirthDate != nil {
+		values["birth_date"] = row.BirthDate.Format(isoDateLayout)
+	}
+	if row.GraduationDate != nil {
+		values["graduation_date"] = row.GraduationDate.Format(isoDateLayout)
+	}
+	for i, u := range row.Universities {
+		suffix := ""
+		if i > 0 {
+			suffix = fmt.Sprintf("_%d", i+1)
+		}
+		values["universidad"+suffix] = u.Name
+		values["universidad-ciudad"+suffix] = deref(u.City)
+		values["universidad-pais"+suffix] = u.Country
+		values["universidad-titulo"+suffix] = deref(u.Degree)
+		if u.GraduationYear != nil {
+			values["universidad-anio"+suffix] = strconv.Itoa(*u.GraduationYear)
+		}
+	}
+
+	record := make([]string, len(header))
+	for i, name := range header {
+		record[i] = values[name]
+	}
+	return record
+}
+
+func deref(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
+
+// Export writes the students matching the filters to w as csv or xlsx, with
+// catalogs by name and the importer's columns, so the file can be edited and
+// imported again. Students are written as they are read.
+func (s *studentExportService) Export(ctx context.Context, w io.Writer, format string, filters repositories.StudentFilters) error {
+	if format != "csv" && format != "xlsx" {
+		return fmt.Errorf("unsupported format: %s, expected csv or xlsx", format)
+	}
+
+	universities, err := s.studentRepo.MaxUniversities(ctx, filters)
+	if err != nil {
+		return err
+	}
+	header := exportHeader(universities)
+
+	if format == "csv" {
+		return s.exportCSV(ctx, w, header, filters)
+	}
+	return s.exportXLSX(ctx, w, header, filters)
+}
+
+func (s *studentExportService) exportCSV(ctx context.Context, w io.Writer, header []string, filters repositories.StudentFilters) error {
+	// The BOM lets spreadsheet programs detect UTF-8; the importer skips it
+	if _, err := w.Write(utf8BOM); err != nil {
+		return fmt.Errorf("failed to write csv: %w", err)
+	}
+	writer := csv.NewWriter(w)
+	if err := writer.Write(header); err != nil {
+		return fmt.Errorf("failed to write csv: %w", err)
+	}
+
+	written := 0
+	err := s.studentRepo.Export(ctx, filters, func(row *models.StudentExportRow) error {
+		if err := writer.Write(exportRecord(header, row)); err != nil {
+			return fmt.Errorf("failed to write csv: %w", err)
+		}
+		written++
+		if written%importBatchSize == 0 {
+			writer.Flush()
+			return writer.Error()
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return fmt.Errorf("failed to write csv: %w", err)
+	}
+	return nil
+}
+
+func (s *studentExportService) exportXLSX(ctx context.Context, w io.Writer, header []string, filters repositories.StudentFilters) error {
+	f := excelize.NewFile()
+	defer f.Close()
+
+	// The sheet is named like the template's so it is recognized on import
+	if err := f.SetSheetName(f.GetSheetName(0), templateDataSheet); err != nil {
+		return err
+	}
+	sw, err := f.NewStreamWriter(templateDataSheet)
+	if err != nil {
+		return fmt.Errorf("failed to write xlsx: %w", err)
+	}
+
+	rowNum := 1
+	writeRow := func(record []string) error {
+		cell, err := excelize.CoordinatesToCellName(1, rowNum)
+		if err != nil {
+			return err
+		}
+		values := make([]interface{}, len(record))
+		for i, v := range record {
+			values[i] = v
+		}
+		if err := sw.SetRow(cell, values); err != nil {
+			return fmt.Errorf("failed to write xlsx row %d: %w", rowNum, err)
+		}
+		rowNum++
+		return nil
+	}
+
+	if err := writeRow(header); err != nil {
+		return err
+	}
+	err = s.studentRepo.Export(ctx, filters, func(row *models.StudentExportRow) error {
+		return writeRow(exportRecord(header, row))
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := sw.Flush(); err != nil {
+		return fmt.Errorf("failed to write xlsx: %w", err)
+	}
+	if err := f.Write(w); err != nil {
+		return fmt.Errorf("failed to write xlsx: %w", err)
+	}
+	return nil
+}
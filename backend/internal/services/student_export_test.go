@@ -0,0 +1,113 @@
+package services_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/xuri/excelize/v2"
+
+	"github.com/dcorreal/coordinador/internal/models"
+	"github.com/dcorreal/coordinador/internal/repositories"
+	"github.com/dcorreal/coordinador/internal/repositories/mocks"
+	"github.com/dcorreal/coordinador/internal/services"
+)
+
+func exportRows() []*models.StudentExportRow {
+	str := func(s string) *string { return &s }
+	year := 2015
+	birth := time.Date(1992, 5, 20, 0, 0, 0, 0, time.UTC)
+	return []*models.StudentExportRow{
+		{
+			FirstNames: "María José", LastNames: "Rodríguez", DocumentID: str("1020304050"), BirthDate: &birth, Gender: str("F"),
+			Emails: []string{"maria@example.com", "mj@empresa.com"}, Nationality: str("Colombia"), ResidenceCountry: str("Colombia"),
+			ResidenceCity: str("Bogotá"), Company: str("Bancolombia"), Status: "active", Cohort: "2026-1",
+			EnrollmentDate: time.Date(2026, 1, 20, 0, 0, 0, 0, time.UTC),
+			Universities: []models.StudentExportUniversity{
+				{Name: "Universidad Nacional de Colombia", City: str("Bogotá"), Country: "Colombia", Degree: str("Ingeniería"), GraduationYear: &year},
+				{Name: "Universidad de los Andes", Country: "Colombia"},
+			},
+		},
+		{
+			FirstNames: "Carlos", LastNames: "Gómez", Nationality: str("México"), Status: "graduated", Cohort: "2025-2",
+			EnrollmentDate: time.Date(2025, 7, 15, 0, 0, 0, 0, time.UTC),
+		},
+	}
+}
+
+func TestExport_CSVUsesImportLayout(t *testing.T) {
+	studentRepo := new(mocks.StudentRepository)
+	service := services.NewStudentExportService(studentRepo)
+
+	status := "active"
+	filters := repositories.StudentFilters{Statuses: []string{status}}
+	studentRepo.On("MaxUniversities", mock.Anything, filters).Return(2, nil)
+	studentRepo.On("Export", mock.Anything, filters, mock.Anything).Return(exportRows(), nil)
+
+	var buf bytes.Buffer
+	err := service.Export(context.Background(), &buf, "csv", filters)
+
+	require.NoError(t, err)
+	assert.True(t, bytes.HasPrefix(buf.Bytes(), []byte("\xEF\xBB\xBF")))
+	records, err := csv.NewReader(bytes.NewReader(buf.Bytes()[3:])).ReadAll()
+	require.NoError(t, err)
+	require.Len(t, records, 3)
+
+	header := records[0]
+	assert.NotContains(t, header, "email_2")
+	assert.Equal(t, []string{"universidad_2", "universidad-ciudad_2", "universidad-pais_2", "universidad-titulo_2", "universidad-anio_2"}, header[len(header)-5:])
+
+	row := map[string]string{}
+	for i, name := range header {
+		row[name] = records[1][i]
+	}
+	assert.Equal(t, "maria@example.com; mj@empresa.com", row["email"])
+	assert.Equal(t, "1992-05-20", row["birth_date"])
+	assert.Equal(t, "Colombia", row["nationality_country_id"])
+	assert.Equal(t, "Bogotá", row["residence_city_id"])
+	assert.Equal(t, "2015", row["universidad-anio"])
+	assert.Equal(t, "Universidad de los Andes", row["universidad_2"])
+
+	// The importer recognizes the file as a students sheet
+	sheets, err := newImportService().ListSheets(bytes.NewReader(buf.Bytes()), "csv", services.CSVOptions{})
+	require.NoError(t, err)
+	require.Len(t, sheets, 1)
+	assert.Equal(t, models.ImportSheetStudents, sheets[0].Kind)
+	assert.Equal(t, 2, sheets[0].DataRows)
+}
+
+func TestExport_XLSX(t *testing.T) {
+	studentRepo := new(mocks.StudentRepository)
+	service := services.NewStudentExportService(studentRepo)
+
+	filters := repositories.StudentFilters{}
+	studentRepo.On("MaxUniversities", mock.Anything, filters).Return(0, nil)
+	studentRepo.On("Export", mock.Anything, filters, mock.Anything).Return(exportRows(), nil)
+
+	var buf bytes.Buffer
+	require.NoError(t, service.Export(context.Background(), &buf, "xlsx", filters))
+
+	f, err := excelize.OpenReader(&buf)
+	require.NoError(t, err)
+	defer f.Close()
+	rows, err := f.GetRows("Estudiantes")
+	require.NoError(t, err)
+	require.Len(t, rows, 3)
+	assert.Equal(t, "first_names", rows[0][0])
+	assert.Equal(t, "Carlos", rows[2][0])
+}
+
+func TestExport_UnsupportedFormat(t *testing.T) {
+	studentRepo := new(mocks.StudentRepository)
+	service := services.NewStudentExportService(studentRepo)
+
+	err := service.Export(context.Background(), &bytes.Buffer{}, "pdf", repositories.StudentFilters{})
+
+	assert.ErrorContains(t, err, "unsupported format")
+	studentRepo.AssertNotCalled(t, "Export", mock.Anything, mock.Anything, mock.Anything)
+}
@@ -0,0 +1,202 @@
+package services
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/encoding/unicode"
+	"golang.org/x/text/transform"
+)
+
+// CSVOptions overrides the detected dialect of a CSV upload. Empty fields are detected.
+type CSVOptions struct {
+	// Encoding is utf-8, windows-1252 (latin-1 is accepted as an alias) or utf-16
+	Encoding string
+	// Delimiter is one of , ; | or "tab"
+	Delimiter string
+}
+
+// csvDialect is the encoding and delimiter of a CSV file.
+type csvDialect struct {
+	encoding  string
+	delimiter rune
+}
+
+// csvSniffSize is how much of the file is inspected to detect its dialect.
+const csvSniffSize = 64 * 1024
+
+var (
+	utf8BOM    = []byte{0xEF, 0xBB, 0xBF}
+	utf16LEBOM = []byte{0xFF, 0xFE}
+	utf16BEBOM = []byte{0xFE, 0xFF}
+)
+
+// csvDelimiters are the delimiters recognized by detection, in order of preference on ties.
+var csvDelimiters = []rune{',', ';', '\t', '|'}
+
+func csvEncoding(name string) (encoding.Encoding, error) {
+	switch name {
+	case "utf-8":
+		return nil, nil
+	case "windows-1252":
+		return charmap.Windows1252, nil
+	case "utf-16":
+		return unicode.UTF16(unicode.LittleEndian, unicode.UseBOM), nil
+	default:
+		return nil, fmt.Errorf("unsupported encoding %q, expected utf-8, windows-1252 or utf-16", name)
+	}
+}
+
+func normalizeEncodingName(name string) string {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "":
+		return ""
+	case "utf-8", "utf8":
+		return "utf-8"
+	case "windows-1252", "cp1252", "latin-1", "latin1", "iso-8859-1":
+		return "windows-1252"
+	case "utf-16", "utf16", "utf-16le":
+		return "utf-16"
+	default:
+		return name
+	}
+}
+
+func parseDelimiter(value string) (rune, error) {
+	switch value {
+	case ",", ";", "|":
+		return rune(value[0]), nil
+	case "tab", "\t", `\t`:
+		return '\t', nil
+	default:
+		return 0, fmt.Errorf("unsupported delimiter %q, expected , ; | or tab", value)
+	}
+}
+
+// decodeCSV returns a UTF-8 reader over the CSV upload and its dialect. The
+// encoding comes from the byte order mark if present, otherwise from whether the
+// start of the file is valid UTF-8 (falling back to Windows-1252, what Excel in
+// Spanish locales writes). The delimiter is the most frequent candidate in the header.
+func decodeCSV(r io.Reader, opts CSVOptions) (io.Reader, csvDialect, error) {
+	var dialect csvDialect
+
+	raw := bufio.NewReaderSize(r, csvSniffSize)
+	head, err := raw.Peek(csvSniffSize)
+	if err != nil && err != io.EOF && err != bufio.ErrBufferFull {
+		return nil, dialect, err
+	}
+
+	dialect.encoding = normalizeEncodingName(opts.Encoding)
+	if dialect.encoding == "" {
+		dialect.encoding = sniffEncoding(head)
+	}
+	enc, err := csvEncoding(dialect.encoding)
+	if err != nil {
+		return nil, dialect, err
+	}
+
+	var text io.Reader = raw
+	if enc != nil {
+		text = enc.NewDecoder().Reader(raw)
+	} else if bytes.HasPrefix(head, utf8BOM) {
+		raw.Discard(len(utf8BOM))
+	}
+
+	decoded := bufio.NewReaderSize(text, csvSniffSize)
+	if opts.Delimiter != "" {
+		dialect.delimiter, err = parseDelimiter(opts.Delimiter)
+		if err != nil {
+			return nil, dialect, err
+		}
+	} else {
+		head, err := decoded.Peek(csvSniffSize)
+		if err != nil && err != io.EOF && err != bufio.ErrBufferFull {
+			return nil, dialect, err
+		}
+		dialect.delimiter = sniffDelimiter(head)
+	}
+	return decoded, dialect, nil
+}
+
+func sniffEncoding(head []byte) string {
+	switch {
+	case bytes.HasPrefix(head, utf8BOM):
+		return "utf-8"
+	case bytes.HasPrefix(head, utf16LEBOM), bytes.HasPrefix(head, utf16BEBOM):
+		return "utf-16"
+	}
+
+	// Ignore a rune cut in half at the end of the sniffed block
+	if len(head) == csvSniffSize {
+		if i := bytes.LastIndexByte(head, '\n'); i >= 0 {
+			head = head[:i]
+		}
+	}
+	if utf8.Valid(head) {
+		return "utf-8"
+	}
+	return "windows-1252"
+}
+
+// sniffDelimiter counts the candidate delimiters outside quotes on the header line.
+func sniffDelimiter(head []byte) rune {
+	counts := make(map[rune]int, len(csvDelimiters))
+	inQuotes := false
+	for _, c := range string(head) {
+		if c == '"' {
+			inQuotes = !inQuotes
+			continue
+		}
+		if inQuotes {
+			continue
+		}
+		if c == '\n' || c == '\r' {
+			break
+		}
+		counts[c]++
+	}
+
+	best := csvDelimiters[0]
+	for _, d := range csvDelimiters[1:] {
+		if counts[d] > counts[best] {
+			best = d
+		}
+	}
+	return best
+}
+
+// writeCSVDialect writes rows with the given dialect, so files such as error reports
+// open the same way as the upload they come from.
+func writeCSVDialect(rows [][]string, dialect csvDialect) ([]byte, error) {
+	var buf bytes.Buffer
+	var out io.Writer = &buf
+	var encoder io.WriteCloser
+
+	enc, err := csvEncoding(dialect.encoding)
+	if err != nil {
+		return nil, err
+	}
+	if enc != nil {
+		encoder = transform.NewWriter(&buf, encoding.ReplaceUnsupported(enc.NewEncoder()))
+		out = encoder
+	}
+
+	writer := csv.NewWriter(out)
+	writer.Comma = dialect.delimiter
+	if err := writer.WriteAll(rows); err != nil {
+		return nil, fmt.Errorf("failed to write csv: %w", err)
+	}
+	if encoder != nil {
+		if err := encoder.Close(); err != nil {
+			return nil, fmt.Errorf("failed to write csv: %w", err)
+		}
+	}
+	return buf.Bytes(), nil
+}
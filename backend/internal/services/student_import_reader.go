@@ -0,0 +1,298 @@
+package services
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// importFile gives streaming access to the sheets of an uploaded file, so large
+// files are processed row by row instead of being loaded whole into memory.
+type importFile interface {
+	// Sheets returns the sheets in file order. CSV files have a single unnamed sheet.
+	Sheets() []importSheet
+	// Rows iterates over the data rows of a sheet, after its header.
+	Rows(sheet string) (rowIterator, error)
+	Close() error
+}
+
+// rowIterator yields the data rows of a sheet one at a time, skipping blank rows.
+type rowIterator interface {
+	// Next returns the 1-based row number and values of the next row, or io.EOF.
+	Next() (int, []string, error)
+	Close() error
+}
+
+// openImportFile opens an upload for reading. csvOpts only applies to CSV files.
+func openImportFile(r io.Reader, format string, csvOpts CSVOptions) (importFile, error) {
+	var file importFile
+	var err error
+
+	switch format {
+	case "csv":
+		file, err = openCSVFile(r, csvOpts)
+	case "xlsx":
+		file, err = openXLSXFile(r)
+	default:
+		return nil, fmt.Errorf("unsupported format: %s, expected csv or xlsx", format)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse file: %w", err)
+	}
+
+	// Selected sheets are checked on import; here only a file without any header fails
+	if len(firstSheet(file.Sheets()).Header) == 0 {
+		file.Close()
+		return nil, fmt.Errorf("file must have a header row")
+	}
+	return file, nil
+}
+
+func isBlankRow(row []string) bool {
+	for _, v := range row {
+		if strings.TrimSpace(v) != "" {
+			return false
+		}
+	}
+	return true
+}
+
+// --- CSV ---
+
+// csvFile reads a CSV upload in a single pass; its rows can only be iterated once.
+type csvFile struct {
+	reader  *csv.Reader
+	dialect csvDialect
+	sheet   importSheet
+	// First data row, read ahead to know whether the file has any data
+	pending    []string
+	pendingNum int
+	consumed   bool
+}
+
+func openCSVFile(r io.Reader, opts CSVOptions) (*csvFile, error) {
+	text, dialect, err := decodeCSV(r, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	reader := csv.NewReader(text)
+	reader.Comma = dialect.delimiter
+	reader.TrimLeadingSpace = true
+	reader.LazyQuotes = true
+
+	f := &csvFile{reader: reader, dialect: dialect}
+	header, err := reader.Read()
+	if err == io.EOF {
+		return f, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	f.sheet.Header = header
+
+	it := &csvRows{file: f, rowNum: 1}
+	f.pendingNum, f.pending, err = it.Next()
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+	f.sheet.HasData = err == nil
+	return f, nil
+}
+
+func (f *csvFile) Sheets() []importSheet {
+	return []importSheet{f.sheet}
+}
+
+func (f *csvFile) Rows(sheet string) (rowIterator, error) {
+	if sheet != "" {
+		return nil, fmt.Errorf("sheet %q not found", sheet)
+	}
+	if f.consumed {
+		return nil, fmt.Errorf("csv rows can only be read once")
+	}
+	f.consumed = true
+	return &csvRows{file: f, rowNum: f.pendingNum}, nil
+}
+
+func (f *csvFile) Close() error {
+	return nil
+}
+
+type csvRows struct {
+	file   *csvFile
+	rowNum int
+}
+
+func (it *csvRows) Next() (int, []string, error) {
+	if it.file.pending != nil {
+		row := it.file.pending
+		it.file.pending = nil
+		return it.rowNum, row, nil
+	}
+	for {
+		row, err := it.file.reader.Read()
+		if err != nil {
+			return 0, nil, err
+		}
+		it.rowNum++
+		if !isBlankRow(row) {
+			return it.rowNum, row, nil
+		}
+	}
+}
+
+func (it *csvRows) Close() error {
+	return nil
+}
+
+// --- XLSX ---
+
+// MaxXLSXImportSize is the largest xlsx upload accepted. Unlike CSV, excelize
+// reads the whole (compressed) workbook into memory before streaming its rows.
+const MaxXLSXImportSize = 20 << 20
+
+const (
+	// xlsxUnzipSizeLimit bounds the uncompressed size of a workbook, so a small
+	// upload can't expand into gigabytes of XML.
+	xlsxUnzipSizeLimit = 500 << 20
+	// xlsxUnzipXMLSizeLimit is the worksheet size above which excelize unzips
+	// the sheet to a temporary file instead of memory.
+	xlsxUnzipXMLSizeLimit = 4 << 20
+)
+
+// xlsxFile reads worksheets through excelize's row iterator, which streams the
+// sheet XML (spilling large sheets to temporary files) instead of building every row.
+type xlsxFile struct {
+	f      *excelize.File
+	sheets []importSheet
+}
+
+func openXLSXFile(r io.Reader) (*xlsxFile, error) {
+	f, err := excelize.OpenReader(&sizeLimitReader{r: r, remaining: MaxXLSXImportSize}, excelize.Options{
+		UnzipSizeLimit:    xlsxUnzipSizeLimit,
+		UnzipXMLSizeLimit: xlsxUnzipXMLSizeLimit,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	names := f.GetSheetList()
+	if len(names) == 0 {
+		f.Close()
+		return nil, fmt.Errorf("no sheets found in xlsx file")
+	}
+
+	file := &xlsxFile{f: f}
+	for _, name := range names {
+		sheet, err := file.peekSheet(name)
+		if err != nil {
+			f.Close()
+			return nil, fmt.Errorf("failed to read sheet %q: %w", name, err)
+		}
+		file.sheets = append(file.sheets, sheet)
+	}
+	return file, nil
+}
+
+// peekSheet reads the header and checks whether the sheet has at least one data row.
+func (x *xlsxFile) peekSheet(name string) (importSheet, error) {
+	sheet := importSheet{Name: name}
+
+	rows, err := x.f.Rows(name)
+	if err != nil {
+		return sheet, err
+	}
+	defer rows.Close()
+
+	it := &xlsxRows{rows: rows}
+	_, header, err := it.next(false)
+	if err == io.EOF {
+		return sheet, nil
+	}
+	if err != nil {
+		return sheet, err
+	}
+	sheet.Header = header
+
+	_, _, err = it.Next()
+	if err != nil && err != io.EOF {
+		return sheet, err
+	}
+	sheet.HasData = err == nil
+	return sheet, nil
+}
+
+func (x *xlsxFile) Sheets() []importSheet {
+	return x.sheets
+}
+
+func (x *xlsxFile) Rows(sheet string) (rowIterator, error) {
+	rows, err := x.f.Rows(sheet)
+	if err != nil {
+		return nil, fmt.Errorf("sheet %q not found", sheet)
+	}
+	it := &xlsxRows{rows: rows}
+	// Skip the header row
+	if _, _, err := it.next(false); err != nil && err != io.EOF {
+		rows.Close()
+		return nil, err
+	}
+	return it, nil
+}
+
+func (x *xlsxFile) Close() error {
+	return x.f.Close()
+}
+
+type xlsxRows struct {
+	rows   *excelize.Rows
+	rowNum int
+}
+
+func (it *xlsxRows) Next() (int, []string, error) {
+	return it.next(true)
+}
+
+func (it *xlsxRows) next(skipBlank bool) (int, []string, error) {
+	for it.rows.Next() {
+		it.rowNum++
+		// Raw values keep date cells as Excel serial numbers instead of
+		// text in the workbook's display format
+		row, err := it.rows.Columns(excelize.Options{RawCellValue: true})
+		if err != nil {
+			return 0, nil, err
+		}
+		if skipBlank && isBlankRow(row) {
+			continue
+		}
+		return it.rowNum, row, nil
+	}
+	if err := it.rows.Error(); err != nil {
+		return 0, nil, err
+	}
+	return 0, nil, io.EOF
+}
+
+func (it *xlsxRows) Close() error {
+	return it.rows.Close()
+}
+
+// sizeLimitReader fails once more than remaining bytes have been read, so an
+// upload that is too large errors out instead of being truncated.
+type sizeLimitReader struct {
+	r         io.Reader
+	remaining int64
+}
+
+func (l *sizeLimitReader) Read(p []byte) (int, error) {
+	n, err := l.r.Read(p)
+	l.remaining -= int64(n)
+	if l.remaining < 0 {
+		return n, fmt.Errorf("xlsx file exceeds the %d MB limit", MaxXLSXImportSize>>20)
+	}
+	return n, err
+}
@@ -5,7 +5,11 @@ import (
 	"context"
 	"encoding/csv"
 	"fmt"
+	"io"
+	"log"
 	"net/mail"
+	"sort"
+	"strconv"
 	"strings"
 
 	"github.com/google/uuid"
@@ -13,18 +17,36 @@ import (
 
 	"github.com/dcorreal/coordinador/internal/models"
 	"github.com/dcorreal/coordinador/internal/repositories"
+	"github.com/dcorreal/coordinador/internal/shared"
 )
 
 // StudentImportService handles bulk student imports from files.
 type StudentImportService interface {
-	ImportFromFile(ctx context.Context, fileData []byte, format string, createdBy *uuid.UUID) (*models.ImportResult, error)
+	ImportFromFile(ctx context.Context, file io.Reader, format string, opts ImportOptions, createdBy *uuid.UUID) (*models.ImportResult, error)
+	ListSheets(file io.Reader, format string, csvOpts CSVOptions) ([]models.ImportSheet, error)
+	BuildErrorReport(file io.Reader, format string, csvOpts CSVOptions, rowErrors []models.ImportRowError) ([]byte, error)
+	BuildTemplate(format string) ([]byte, error)
+}
+
+// ImportOptions holds caller choices for a single import.
+type ImportOptions struct {
+	// Sheets restricts an xlsx import to the named sheets. When empty, every
+	// sheet recognized as students, universities or enrollments is imported.
+	Sheets []string
+	// CSV overrides the detected encoding and delimiter of CSV files
+	CSV CSVOptions
+	// CreateSimilar creates catalog names that look like a typo of an existing
+	// entry instead of reporting them, once the user confirmed they are new
+	CreateSimilar bool
 }
 
 type studentImportService struct {
 	studentService  StudentService
 	studentRepo     repositories.StudentRepository
 	catalogRepo     repositories.CatalogRepository
+	enrollmentRepo  repositories.EnrollmentRepository
 	catalogResolver *CatalogResolver
+	dates           *shared.DateParser
 }
 
 // NewStudentImportService creates a new StudentImportService.
@@ -32,15 +54,52 @@ func NewStudentImportService(
 	studentService StudentService,
 	studentRepo repositories.StudentRepository,
 	catalogRepo repositories.CatalogRepository,
+	enrollmentRepo repositories.EnrollmentRepository,
+	catalogResolver *CatalogResolver,
+	dates *shared.DateParser,
 ) StudentImportService {
 	return &studentImportService{
 		studentService:  studentService,
 		studentRepo:     studentRepo,
 		catalogRepo:     catalogRepo,
-		catalogResolver: NewCatalogResolver(catalogRepo),
+		enrollmentRepo:  enrollmentRepo,
+		catalogResolver: catalogResolver,
+		dates:           dates,
 	}
 }
 
+// importRun holds the state shared by every row of a single import.
+type importRun struct {
+	createdBy      *uuid.UUID
+	resolver       *CatalogResolver
+	existingDocs   map[string]bool
+	existingEmails map[string]bool
+
+	// Track duplicates within the file itself
+	seenDocs   map[string]int
+	seenEmails map[string]int
+
+	// Students by student_code, used to link rows on universities and enrollments sheets.
+	// Only filled when the import has linked sheets.
+	trackStudents bool
+	students      map[string]studentRef
+
+	// Whether any created student references a company
+	companiesLinked bool
+}
+
+// studentRef is the part of a student that linked sheets need.
+type studentRef struct {
+	ID                   uuid.UUID
+	NationalityCountryID uuid.UUID
+}
+
+const isoDateLayout = "2006-01-02"
+
+// importBatchSize is the number of student rows processed together.
+// Duplicate checks against the database are done once per batch.
+const importBatchSize = 500
+
 // statusMap translates Spanish status values to English.
 var statusMap = map[string]string{
 	"activo":     "active",
@@ -63,86 +122,260 @@ func isUUID(s string) bool {
 	return err == nil
 }
 
-func (s *studentImportService) ImportFromFile(ctx context.Context, fileData []byte, format string, createdBy *uuid.UUID) (*models.ImportResult, error) {
-	var rows [][]string
-	var err error
-
-	switch format {
-	case "csv":
-		rows, err = parseCSV(fileData)
-	case "xlsx":
-		rows, err = parseXLSX(fileData)
-	default:
-		return nil, fmt.Errorf("unsupported format: %s, expected csv or xlsx", format)
+func (s *studentImportService) ImportFromFile(ctx context.Context, file io.Reader, format string, opts ImportOptions, createdBy *uuid.UUID) (*models.ImportResult, error) {
+	f, err := openImportFile(file, format, opts.CSV)
+	if err != nil {
+		return nil, err
 	}
+	defer f.Close()
+
+	selected, err := selectSheets(f.Sheets(), opts.Sheets)
 	if err != nil {
-		return nil, fmt.Errorf("failed to parse file: %w", err)
+		return nil, err
+	}
+	for _, sheet := range selected {
+		if len(sheet.Header) == 0 {
+			return nil, fmt.Errorf("%sfile must have a header row", sheet.label())
+		}
 	}
 
-	if len(rows) < 2 {
-		return nil, fmt.Errorf("file must have a header row and at least one data row")
+	// Students go first so linked sheets can reference students created in this import
+	var studentSheets, universitySheets, enrollmentSheets []importSheet
+	for _, sheet := range selected {
+		switch sheet.kind() {
+		case models.ImportSheetUniversities:
+			universitySheets = append(universitySheets, sheet)
+		case models.ImportSheetEnrollments:
+			enrollmentSheets = append(enrollmentSheets, sheet)
+		default:
+			studentSheets = append(studentSheets, sheet)
+		}
 	}
 
-	headerMap, err := mapHeaders(rows[0])
-	if err != nil {
-		return nil, err
+	// Validate every student sheet before importing anything
+	headerMaps := make([]map[string]int, len(studentSheets))
+	for i, sheet := range studentSheets {
+		if !sheet.HasData {
+			return nil, fmt.Errorf("%sfile must have a header row and at least one data row", sheet.label())
+		}
+		headerMap, err := mapHeaders(sheet.Header)
+		if err != nil {
+			return nil, fmt.Errorf("%s%w", sheet.label(), err)
+		}
+		headerMaps[i] = headerMap
 	}
 
-	dataRows := rows[1:]
 	result := &models.ImportResult{
-		TotalRows: len(dataRows),
-		Errors:    []models.ImportRowError{},
+		Errors:   []models.ImportRowError{},
+		Warnings: []models.ImportRowError{},
+	}
+	run := &importRun{
+		createdBy:     createdBy,
+		resolver:      s.catalogResolver,
+		seenDocs:      make(map[string]int),
+		seenEmails:    make(map[string]int),
+		trackStudents: len(universitySheets)+len(enrollmentSheets) > 0,
+		students:      make(map[string]studentRef),
+	}
+	if opts.CreateSimilar {
+		run.resolver = s.catalogResolver.CreatingSimilar()
+	}
+
+	// Earlier batches are already saved when a sheet fails, so the result goes
+	// back with the error
+	for i, sheet := range studentSheets {
+		if err := s.importStudentSheet(ctx, f, sheet, headerMaps[i], run, result); err != nil {
+			return abortImport(result, sheet, err)
+		}
+	}
+	for _, sheet := range universitySheets {
+		if err := s.importUniversitySheet(ctx, f, sheet, run, result); err != nil {
+			return abortImport(result, sheet, err)
+		}
+	}
+	for _, sheet := range enrollmentSheets {
+		if err := s.importEnrollmentSheet(ctx, f, sheet, run, result); err != nil {
+			return abortImport(result, sheet, err)
+		}
 	}
 
-	// Collect all document_ids and emails for batch duplicate check
-	var allDocIDs []string
-	var allEmails []string
-	for _, row := range dataRows {
-		if docID := getField(row, headerMap, "document_id"); docID != "" {
-			allDocIDs = append(allDocIDs, docID)
+	// Keep the company report in sync; the import itself already succeeded
+	if run.companiesLinked {
+		if err := s.catalogRepo.RefreshStudentsByCompany(ctx); err != nil {
+			log.Printf("student import: %v", err)
 		}
-		if email := getField(row, headerMap, "email"); email != "" {
-			allEmails = append(allEmails, email)
+	}
+
+	return result, nil
+}
+
+// abortImport records why the import stopped, unless the failed batch already did.
+func abortImport(result *models.ImportResult, sheet importSheet, err error) (*models.ImportResult, error) {
+	if result.Fatal == nil {
+		result.Fatal = &models.ImportRowError{Sheet: sheet.Name, Field: "_import", Message: err.Error()}
+	}
+	return result, err
+}
+
+// importRow is a data row with its 1-based row number in the sheet.
+type importRow struct {
+	num    int
+	values []string
+}
+
+// importStudentSheet streams the rows of a student sheet in batches of importBatchSize.
+func (s *studentImportService) importStudentSheet(ctx context.Context, f importFile, sheet importSheet, headerMap map[string]int, run *importRun, result *models.ImportResult) error {
+	rows, err := f.Rows(sheet.Name)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	batch := make([]importRow, 0, importBatchSize)
+	for {
+		rowNum, values, err := rows.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("%sfailed to read row: %w", sheet.label(), err)
+		}
+		result.TotalRows++
+		batch = append(batch, importRow{num: rowNum, values: values})
+		if len(batch) == importBatchSize {
+			if err := s.importStudentBatch(ctx, sheet, batch, headerMap, run, result); err != nil {
+				return err
+			}
+			batch = batch[:0]
 		}
 	}
+	return s.importStudentBatch(ctx, sheet, batch, headerMap, run, result)
+}
 
-	existingDocs, err := s.studentRepo.ExistingDocumentIDs(ctx, allDocIDs)
+func (s *studentImportService) importStudentBatch(ctx context.Context, sheet importSheet, batch []importRow, headerMap map[string]int, run *importRun, result *models.ImportResult) error {
+	if len(batch) == 0 {
+		return nil
+	}
+
+	// Collect the batch's document_ids and emails for a single duplicate check
+	var docIDs []string
+	var emails []string
+	for _, row := range batch {
+		if docID := getField(row.values, headerMap, "document_id"); docID != "" {
+			docIDs = append(docIDs, docID)
+		}
+		emails = append(emails, multiValueField(row.values, headerMap, "email", emailSeparators)...)
+	}
+
+	var err error
+	run.existingDocs, err = s.studentRepo.ExistingDocumentIDs(ctx, docIDs)
 	if err != nil {
-		return nil, fmt.Errorf("failed to check existing documents: %w", err)
+		return fmt.Errorf("failed to check existing documents: %w", err)
 	}
-	existingEmails, err := s.studentRepo.ExistingEmails(ctx, allEmails)
+	run.existingEmails, err = s.studentRepo.ExistingEmails(ctx, emails)
 	if err != nil {
-		return nil, fmt.Errorf("failed to check existing emails: %w", err)
+		return fmt.Errorf("failed to check existing emails: %w", err)
 	}
 
-	// Track duplicates within the file itself
-	seenDocs := make(map[string]int)
-	seenEmails := make(map[string]int)
-
-	for i, row := range dataRows {
-		rowNum := i + 2 // 1-based, skip header
+	firstError := len(result.Errors)
 
-		rowErrors := s.validateAndImportRow(ctx, row, headerMap, rowNum, createdBy, existingDocs, existingEmails, seenDocs, seenEmails)
+	prepared := make([]*preparedRow, 0, len(batch))
+	for _, row := range batch {
+		p, rowErrors := s.prepareRow(ctx, row.values, headerMap, row.num, run)
 		if len(rowErrors) > 0 {
-			result.Errors = append(result.Errors, rowErrors...)
-		} else {
-			result.Created++
+			result.Errors = append(result.Errors, sheet.tag(rowErrors)...)
+			continue
 		}
+		prepared = append(prepared, p)
+	}
+	if len(prepared) == 0 {
+		return nil
 	}
 
-	return result, nil
+	students := make([]*models.Student, len(prepared))
+	for i, p := range prepared {
+		students[i] = p.student
+	}
+	insertErrors, err := s.studentRepo.CreateBatch(ctx, students)
+	if err != nil {
+		// Nothing of the batch was saved; its rows are reported so the caller
+		// knows where to resume
+		err = fmt.Errorf("failed to create students: %w", err)
+		result.Fatal = &models.ImportRowError{Sheet: sheet.Name, Row: prepared[0].num, Field: "_import", Message: err.Error()}
+		for _, p := range prepared {
+			run.release(p)
+			result.Errors = append(result.Errors, sheet.tag([]models.ImportRowError{
+				{Row: p.num, Field: "_row", Message: "not imported, the import stopped at this batch"},
+			})...)
+		}
+		sortRowErrors(result.Errors[firstError:])
+		return err
+	}
+
+	for i, p := range prepared {
+		if err := insertErrors[i]; err != nil {
+			run.release(p)
+			result.Errors = append(result.Errors, sheet.tag([]models.ImportRowError{
+				{Row: p.num, Field: "_row", Message: err.Error()},
+			})...)
+			continue
+		}
+		result.Created++
+		s.linkRowUniversities(ctx, sheet, p, run, result)
+		run.track(p)
+	}
+
+	// Insert errors were appended after the batch's validation errors
+	sortRowErrors(result.Errors[firstError:])
+	return nil
+}
+
+func sortRowErrors(rowErrors []models.ImportRowError) {
+	sort.SliceStable(rowErrors, func(i, j int) bool { return rowErrors[i].Row < rowErrors[j].Row })
+}
+
+// preparedRow is a validated student row ready to be inserted.
+type preparedRow struct {
+	num     int
+	student *models.Student
+
+	// Optional university columns, linked once the student exists
+	universities []rowUniversity
+}
+
+// release forgets the document and emails of a row whose insert failed, so later
+// rows are not reported as duplicates of a student that was never created.
+func (run *importRun) release(p *preparedRow) {
+	if doc := p.student.DocumentID; doc != nil && run.seenDocs[*doc] == p.num {
+		delete(run.seenDocs, *doc)
+	}
+	for _, email := range p.student.Emails {
+		key := strings.ToLower(email)
+		if run.seenEmails[key] == p.num {
+			delete(run.seenEmails, key)
+		}
+	}
+}
+
+// track records a created student for linked sheets and the company report refresh.
+func (run *importRun) track(p *preparedRow) {
+	if code := p.student.StudentCode; code != nil && run.trackStudents {
+		run.students[*code] = studentRef{ID: p.student.ID, NationalityCountryID: p.student.NationalityCountryID}
+	}
+	if p.student.CompanyID != nil {
+		run.companiesLinked = true
+	}
 }
 
-func (s *studentImportService) validateAndImportRow(
+// prepareRow validates a student row and resolves its catalogs, returning the
+// student to insert. The row is counted as seen for intra-file duplicate checks.
+func (s *studentImportService) prepareRow(
 	ctx context.Context,
 	row []string,
 	headerMap map[string]int,
 	rowNum int,
-	createdBy *uuid.UUID,
-	existingDocs, existingEmails map[string]bool,
-	seenDocs map[string]int,
-	seenEmails map[string]int,
-) []models.ImportRowError {
+	run *importRun,
+) (*preparedRow, []models.ImportRowError) {
 	var errors []models.ImportRowError
 
 	addError := func(field, value, message string) {
@@ -153,6 +386,15 @@ func (s *studentImportService) validateAndImportRow(
 			Message: message,
 		})
 	}
+	addCatalogError := func(field, value string, err error) {
+		errors = append(errors, models.ImportRowError{
+			Row:         rowNum,
+			Field:       field,
+			Value:       value,
+			Message:     err.Error(),
+			Suggestions: catalogSuggestions(err),
+		})
+	}
 
 	// Extract fields
 	firstNames := strings.TrimSpace(getField(row, headerMap, "first_names"))
@@ -160,8 +402,8 @@ func (s *studentImportService) validateAndImportRow(
 	documentID := strings.TrimSpace(getField(row, headerMap, "document_id"))
 	birthDate := strings.TrimSpace(getField(row, headerMap, "birth_date"))
 	gender := strings.TrimSpace(getField(row, headerMap, "gender"))
-	email := strings.TrimSpace(getField(row, headerMap, "email"))
-	phone := strings.TrimSpace(getField(row, headerMap, "phone"))
+	emails := uniqueValues(multiValueField(row, headerMap, "email", emailSeparators))
+	phones := uniqueValues(multiValueField(row, headerMap, "phone", phoneSeparators))
 	nationalityRaw := strings.TrimSpace(getField(row, headerMap, "nationality_country_id"))
 	residenceRaw := strings.TrimSpace(getField(row, headerMap, "residence_country_id"))
 	residenceCityRaw := strings.TrimSpace(getField(row, headerMap, "residence_city_id"))
@@ -172,11 +414,15 @@ func (s *studentImportService) validateAndImportRow(
 	status := normalizeStatus(getField(row, headerMap, "status"))
 	cohort := strings.TrimSpace(getField(row, headerMap, "cohort"))
 	enrollmentDate := strings.TrimSpace(getField(row, headerMap, "enrollment_date"))
+	graduationDate := strings.TrimSpace(getField(row, headerMap, "graduation_date"))
 
-	// University columns (optional)
-	universityName := strings.TrimSpace(getField(row, headerMap, "universidad"))
-	universityCityName := strings.TrimSpace(getField(row, headerMap, "universidad-ciudad"))
-	universityCountryName := strings.TrimSpace(getField(row, headerMap, "universidad-pais"))
+	// University columns (optional, one group per university)
+	var universities []rowUniversity
+	for _, suffix := range universitySuffixes(headerMap) {
+		if u, ok := readRowUniversity(row, headerMap, suffix, addError); ok {
+			universities = append(universities, u)
+		}
+	}
 
 	// Validate required fields
 	if firstNames == "" {
@@ -185,11 +431,16 @@ func (s *studentImportService) validateAndImportRow(
 	if lastNames == "" {
 		addError("last_names", "", "required field is empty")
 	}
-	if email != "" {
+	for _, email := range emails {
 		if _, err := mail.ParseAddress(email); err != nil {
 			addError("email", email, "invalid email format")
 		}
 	}
+	for _, phone := range phones {
+		if len(phone) > 50 {
+			addError("phone", phone, "must be at most 50 characters")
+		}
+	}
 	if nationalityRaw == "" {
 		addError("nationality_country_id", "", "required field is empty")
 	}
@@ -202,6 +453,33 @@ func (s *studentImportService) validateAndImportRow(
 	if enrollmentDate == "" {
 		addError("enrollment_date", "", "required field is empty")
 	}
+	if status == string(models.StudentStatusGraduated) && graduationDate == "" {
+		addError("graduation_date", "", "required for graduated students")
+	}
+
+	// Dates may be text in any accepted format or Excel serial numbers; they are
+	// passed on to the student service in ISO format
+	if birthDate != "" {
+		if parsed, err := s.dates.ParseCell(birthDate); err != nil {
+			addError("birth_date", birthDate, err.Error())
+		} else {
+			birthDate = parsed.Format(isoDateLayout)
+		}
+	}
+	if enrollmentDate != "" {
+		if parsed, err := s.dates.ParseCell(enrollmentDate); err != nil {
+			addError("enrollment_date", enrollmentDate, err.Error())
+		} else {
+			enrollmentDate = parsed.Format(isoDateLayout)
+		}
+	}
+	if graduationDate != "" {
+		if parsed, err := s.dates.ParseCell(graduationDate); err != nil {
+			addError("graduation_date", graduationDate, err.Error())
+		} else {
+			graduationDate = parsed.Format(isoDateLayout)
+		}
+	}
 
 	// Validate gender if provided
 	if gender != "" {
@@ -215,23 +493,23 @@ func (s *studentImportService) validateAndImportRow(
 
 	// Check duplicates against DB
 	if documentID != "" {
-		if existingDocs[documentID] {
+		if run.existingDocs[documentID] {
 			addError("document_id", documentID, "duplicate: student with this document already exists")
-		} else if prevRow, ok := seenDocs[documentID]; ok {
+		} else if prevRow, ok := run.seenDocs[documentID]; ok {
 			addError("document_id", documentID, fmt.Sprintf("duplicate: same document_id as row %d in this file", prevRow))
 		}
 	}
-	if email != "" {
-		if existingEmails[email] {
+	for _, email := range emails {
+		if run.existingEmails[strings.ToLower(email)] {
 			addError("email", email, "duplicate: student with this email already exists")
-		} else if prevRow, ok := seenEmails[email]; ok {
+		} else if prevRow, ok := run.seenEmails[strings.ToLower(email)]; ok {
 			addError("email", email, fmt.Sprintf("duplicate: same email as row %d in this file", prevRow))
 		}
 	}
 
 	// If there are validation errors, don't attempt to resolve catalogs or insert
 	if len(errors) > 0 {
-		return errors
+		return nil, errors
 	}
 
 	// --- Resolve names to UUIDs via CatalogResolver ---
@@ -241,10 +519,10 @@ func (s *studentImportService) validateAndImportRow(
 	if isUUID(nationalityRaw) {
 		nationalityCountryUUID = nationalityRaw
 	} else {
-		id, err := s.catalogResolver.ResolveCountry(ctx, nationalityRaw)
+		id, err := run.resolver.ResolveCountry(ctx, nationalityRaw)
 		if err != nil {
-			addError("nationality_country_id", nationalityRaw, err.Error())
-			return errors
+			addCatalogError("nationality_country_id", nationalityRaw, err)
+			return nil, errors
 		}
 		nationalityCountryUUID = id.String()
 	}
@@ -256,10 +534,10 @@ func (s *studentImportService) validateAndImportRow(
 	} else if isUUID(residenceRaw) {
 		residenceCountryUUID = residenceRaw
 	} else {
-		id, err := s.catalogResolver.ResolveCountry(ctx, residenceRaw)
+		id, err := run.resolver.ResolveCountry(ctx, residenceRaw)
 		if err != nil {
-			addError("residence_country_id", residenceRaw, err.Error())
-			return errors
+			addCatalogError("residence_country_id", residenceRaw, err)
+			return nil, errors
 		}
 		residenceCountryUUID = id.String()
 	}
@@ -271,10 +549,10 @@ func (s *studentImportService) validateAndImportRow(
 			residenceCityUUID = &residenceCityRaw
 		} else {
 			resCountryID, _ := uuid.Parse(residenceCountryUUID)
-			id, err := s.catalogResolver.ResolveCity(ctx, residenceCityRaw, resCountryID)
+			id, err := run.resolver.ResolveCity(ctx, residenceCityRaw, resCountryID)
 			if err != nil {
-				addError("residence_city_id", residenceCityRaw, err.Error())
-				return errors
+				addCatalogError("residence_city_id", residenceCityRaw, err)
+				return nil, errors
 			}
 			if id != uuid.Nil {
 				s := id.String()
@@ -289,10 +567,10 @@ func (s *studentImportService) validateAndImportRow(
 		if isUUID(professionRaw) {
 			professionUUID = &professionRaw
 		} else {
-			id, err := s.catalogResolver.ResolveProfession(ctx, professionRaw)
+			id, err := run.resolver.ResolveProfession(ctx, professionRaw)
 			if err != nil {
-				addError("profession_id", professionRaw, err.Error())
-				return errors
+				addCatalogError("profession_id", professionRaw, err)
+				return nil, errors
 			}
 			if id != uuid.Nil {
 				s := id.String()
@@ -301,16 +579,34 @@ func (s *studentImportService) validateAndImportRow(
 		}
 	}
 
+	// Company (optional)
+	var companyUUID *string
+	if companyRaw != "" {
+		if isUUID(companyRaw) {
+			companyUUID = &companyRaw
+		} else {
+			id, err := run.resolver.ResolveCompany(ctx, companyRaw)
+			if err != nil {
+				addCatalogError("company_id", companyRaw, err)
+				return nil, errors
+			}
+			if id != uuid.Nil {
+				s := id.String()
+				companyUUID = &s
+			}
+		}
+	}
+
 	// Job title category (optional)
 	var jobTitleUUID *string
 	if jobTitleRaw != "" {
 		if isUUID(jobTitleRaw) {
 			jobTitleUUID = &jobTitleRaw
 		} else {
-			id, err := s.catalogResolver.ResolveJobTitleCategory(ctx, jobTitleRaw)
+			id, err := run.resolver.ResolveJobTitleCategory(ctx, jobTitleRaw)
 			if err != nil {
-				addError("job_title_category_id", jobTitleRaw, err.Error())
-				return errors
+				addCatalogError("job_title_category_id", jobTitleRaw, err)
+				return nil, errors
 			}
 			if id != uuid.Nil {
 				s := id.String()
@@ -330,6 +626,7 @@ func (s *studentImportService) validateAndImportRow(
 		Status:               status,
 		Cohort:               cohort,
 		EnrollmentDate:       enrollmentDate,
+		GraduationDate:       graduationDate,
 	}
 
 	if documentID != "" {
@@ -338,14 +635,14 @@ func (s *studentImportService) validateAndImportRow(
 	if gender != "" {
 		req.Gender = &gender
 	}
-	if email != "" {
-		req.Emails = []string{email}
+	if len(emails) > 0 {
+		req.Emails = emails
 	}
-	if phone != "" {
-		req.Phones = []string{phone}
+	if len(phones) > 0 {
+		req.Phones = phones
 	}
-	if companyRaw != "" && isUUID(companyRaw) {
-		req.CompanyID = &companyRaw
+	if companyUUID != nil {
+		req.CompanyID = companyUUID
 	}
 	if jobTitleUUID != nil {
 		req.JobTitleCategoryID = jobTitleUUID
@@ -357,83 +654,290 @@ func (s *studentImportService) validateAndImportRow(
 		req.StudentCode = &studentCode
 	}
 
-	// Create the student
-	student, err := s.studentService.CreateStudent(ctx, req, createdBy)
+	student, err := s.studentService.NewStudent(req, run.createdBy)
 	if err != nil {
 		addError("_row", "", err.Error())
-		return errors
+		return nil, errors
 	}
 
-	// --- Create student_university relationship ---
-	if universityName != "" {
-		// Resolve university country (default to nationality if not provided)
-		uniCountryID, _ := uuid.Parse(nationalityCountryUUID)
-		if universityCountryName != "" {
-			resolved, err := s.catalogResolver.ResolveCountry(ctx, universityCountryName)
-			if err == nil && resolved != uuid.Nil {
-				uniCountryID = resolved
-			}
+	// Track as seen for intra-file duplicate detection
+	if documentID != "" {
+		run.seenDocs[documentID] = rowNum
+	}
+	for _, email := range emails {
+		run.seenEmails[strings.ToLower(email)] = rowNum
+	}
+
+	return &preparedRow{
+		num:          rowNum,
+		student:      student,
+		universities: universities,
+	}, nil
+}
+
+// linkRowUniversities links a created student to the universities given on its row.
+// The student is already saved, so failures are reported as warnings.
+func (s *studentImportService) linkRowUniversities(ctx context.Context, sheet importSheet, p *preparedRow, run *importRun, result *models.ImportResult) {
+	student := studentRef{ID: p.student.ID, NationalityCountryID: p.student.NationalityCountryID}
+	for _, u := range p.universities {
+		field, value, err := s.linkUniversity(ctx, run.resolver, student, u)
+		if err != nil {
+			result.Warnings = append(result.Warnings, models.ImportRowError{
+				Sheet:       sheet.Name,
+				Row:         p.num,
+				Field:       field,
+				Value:       value,
+				Message:     "student created without this university: " + err.Error(),
+				Suggestions: catalogSuggestions(err),
+			})
+			continue
+		}
+		result.UniversitiesLinked++
+	}
+}
+
+// BuildErrorReport returns the original file restricted to the rows that failed,
+// with an extra "errors" column, in the same format as the upload. CSV reports
+// keep the upload's encoding and delimiter.
+// For xlsx files every sheet with errors is kept under its original name.
+func (s *studentImportService) BuildErrorReport(file io.Reader, format string, csvOpts CSVOptions, rowErrors []models.ImportRowError) ([]byte, error) {
+	f, err := openImportFile(file, format, csvOpts)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	sheets := f.Sheets()
+	first := firstSheet(sheets)
+
+	// Group messages by sheet and row number (1-based, header is row 1).
+	// Errors without a sheet refer to the first sheet with a header.
+	type rowKey struct {
+		sheet string
+		row   int
+	}
+	messages := make(map[rowKey][]string)
+	for _, e := range rowErrors {
+		msg := e.Message
+		if e.Field != "" && e.Field != "_row" {
+			msg = e.Field + ": " + msg
+		}
+		sheet := e.Sheet
+		if sheet == "" {
+			sheet = first.Name
+		}
+		key := rowKey{sheet: sheet, row: e.Row}
+		messages[key] = append(messages[key], msg)
+	}
+
+	var reports []sheetRows
+	for _, sheet := range sheets {
+		if len(sheet.Header) == 0 {
+			continue
+		}
+		report := [][]string{append(append([]string{}, sheet.Header...), "errors")}
+		// XLSX rows are read with raw values, so date cells come as Excel serial
+		// numbers; the report shows them as ISO dates, which the import accepts
+		var dateCols []int
+		if format == "xlsx" {
+			dateCols = dateColumns(sheet.Header)
 		}
 
-		// Resolve university city (optional)
-		var uniCityID *uuid.UUID
-		if universityCityName != "" {
-			resolved, err := s.catalogResolver.ResolveCity(ctx, universityCityName, uniCountryID)
-			if err == nil && resolved != uuid.Nil {
-				uniCityID = &resolved
+		rows, err := f.Rows(sheet.Name)
+		if err != nil {
+			return nil, err
+		}
+		for {
+			rowNum, row, err := rows.Next()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				rows.Close()
+				return nil, fmt.Errorf("%sfailed to read row: %w", sheet.label(), err)
+			}
+			msgs, ok := messages[rowKey{sheet: sheet.Name, row: rowNum}]
+			if !ok {
+				continue
 			}
+			// Pad short rows so the errors column always lines up with the header
+			padded := make([]string, len(sheet.Header))
+			copy(padded, row)
+			for _, i := range dateCols {
+				padded[i] = s.isoDateCell(padded[i])
+			}
+			report = append(report, append(padded, strings.Join(msgs, "; ")))
 		}
+		rows.Close()
 
-		// Resolve university
-		uniID, err := s.catalogResolver.ResolveUniversity(ctx, universityName, uniCityID, uniCountryID)
-		if err == nil && uniID != uuid.Nil {
-			_ = s.catalogRepo.CreateStudentUniversity(ctx, student.ID, uniID)
+		if len(report) > 1 {
+			reports = append(reports, sheetRows{Name: sheet.Name, Rows: report})
 		}
 	}
+	if len(reports) == 0 {
+		// Nothing matched: still return the header so the file shape is preserved
+		header := append(append([]string{}, first.Header...), "errors")
+		reports = append(reports, sheetRows{Name: first.Name, Rows: [][]string{header}})
+	}
 
-	// Track as seen for intra-file duplicate detection
-	if documentID != "" {
-		seenDocs[documentID] = rowNum
+	switch format {
+	case "csv":
+		return writeCSVDialect(reports[0].Rows, f.(*csvFile).dialect)
+	case "xlsx":
+		return writeXLSXSheets(reports)
+	default:
+		return nil, fmt.Errorf("unsupported format: %s, expected csv or xlsx", format)
 	}
-	seenEmails[email] = rowNum
+}
 
-	return nil
+// importDateColumns are the student columns holding dates.
+var importDateColumns = []string{"birth_date", "enrollment_date", "graduation_date"}
+
+// dateColumns returns the positions of the date columns in a header.
+func dateColumns(header []string) []int {
+	headerMap := headerIndex(header)
+	var cols []int
+	for _, name := range importDateColumns {
+		if i, ok := headerMap[name]; ok {
+			cols = append(cols, i)
+		}
+	}
+	return cols
 }
 
-func parseCSV(data []byte) ([][]string, error) {
-	reader := csv.NewReader(bytes.NewReader(data))
-	reader.TrimLeadingSpace = true
-	reader.LazyQuotes = true
-	return reader.ReadAll()
+// isoDateCell converts an Excel serial date to YYYY-MM-DD. Other values,
+// including dates typed as text, are returned unchanged.
+func (s *studentImportService) isoDateCell(value string) string {
+	if _, err := strconv.ParseFloat(strings.TrimSpace(value), 64); err != nil {
+		return value
+	}
+	parsed, err := s.dates.ParseCell(value)
+	if err != nil {
+		return value
+	}
+	return parsed.Format(isoDateLayout)
 }
 
-func parseXLSX(data []byte) ([][]string, error) {
-	f, err := excelize.OpenReader(bytes.NewReader(data))
+// ListSheets describes the sheets of an import file. CSV files have a single unnamed sheet.
+func (s *studentImportService) ListSheets(file io.Reader, format string, csvOpts CSVOptions) ([]models.ImportSheet, error) {
+	f, err := openImportFile(file, format, csvOpts)
 	if err != nil {
 		return nil, err
 	}
 	defer f.Close()
 
-	sheetName := f.GetSheetName(0)
-	if sheetName == "" {
-		return nil, fmt.Errorf("no sheets found in xlsx file")
+	result := make([]models.ImportSheet, 0, len(f.Sheets()))
+	for _, sheet := range f.Sheets() {
+		info := models.ImportSheet{
+			Name:    sheet.Name,
+			Kind:    sheet.kind(),
+			Headers: []string{},
+		}
+		if len(sheet.Header) > 0 {
+			info.Headers = sheet.Header
+			count, err := countRows(f, sheet)
+			if err != nil {
+				return nil, err
+			}
+			info.DataRows = count
+		}
+		result = append(result, info)
+	}
+	return result, nil
+}
+
+// countRows counts the non-blank data rows of a sheet.
+func countRows(f importFile, sheet importSheet) (int, error) {
+	rows, err := f.Rows(sheet.Name)
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+
+	count := 0
+	for {
+		_, _, err := rows.Next()
+		if err == io.EOF {
+			return count, nil
+		}
+		if err != nil {
+			return 0, fmt.Errorf("%sfailed to read row: %w", sheet.label(), err)
+		}
+		count++
+	}
+}
+
+// sheetRows is an in-memory sheet written to an output file.
+type sheetRows struct {
+	Name string
+	Rows [][]string
+}
+
+func writeCSV(rows [][]string) ([]byte, error) {
+	var buf bytes.Buffer
+	writer := csv.NewWriter(&buf)
+	if err := writer.WriteAll(rows); err != nil {
+		return nil, fmt.Errorf("failed to write csv: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+func writeXLSXSheets(sheets []sheetRows) ([]byte, error) {
+	f := excelize.NewFile()
+	defer f.Close()
+
+	for i, sheet := range sheets {
+		if i == 0 {
+			if err := f.SetSheetName(f.GetSheetName(0), sheet.Name); err != nil {
+				return nil, err
+			}
+		} else if _, err := f.NewSheet(sheet.Name); err != nil {
+			return nil, err
+		}
+		if err := setSheetRows(f, sheet.Name, sheet.Rows); err != nil {
+			return nil, err
+		}
 	}
 
-	return f.GetRows(sheetName)
+	buf, err := f.WriteToBuffer()
+	if err != nil {
+		return nil, fmt.Errorf("failed to write xlsx: %w", err)
+	}
+	return buf.Bytes(), nil
 }
 
-func mapHeaders(headerRow []string) (map[string]int, error) {
+func setSheetRows(f *excelize.File, sheetName string, rows [][]string) error {
+	for i, row := range rows {
+		cell, err := excelize.CoordinatesToCellName(1, i+1)
+		if err != nil {
+			return err
+		}
+		values := make([]interface{}, len(row))
+		for j, v := range row {
+			values[j] = v
+		}
+		if err := f.SetSheetRow(sheetName, cell, &values); err != nil {
+			return fmt.Errorf("failed to write xlsx row %d: %w", i+1, err)
+		}
+	}
+	return nil
+}
+
+// headerIndex maps normalized header names to their column index.
+func headerIndex(headerRow []string) map[string]int {
 	m := make(map[string]int)
 	for i, h := range headerRow {
 		normalized := strings.ToLower(strings.TrimSpace(h))
 		m[normalized] = i
 	}
+	return m
+}
+
+func mapHeaders(headerRow []string) (map[string]int, error) {
+	m := headerIndex(headerRow)
 
 	// Required headers — residence_country_id is no longer required since we fall back to nationality
-	required := []string{"first_names", "last_names",
-		"nationality_country_id", "status", "cohort", "enrollment_date"}
 	var missing []string
-	for _, r := range required {
+	for _, r := range requiredImportColumns() {
 		if _, ok := m[r]; !ok {
 			missing = append(missing, r)
 		}
@@ -445,6 +949,61 @@ func mapHeaders(headerRow []string) (map[string]int, error) {
 	return m, nil
 }
 
+// Separators accepted inside a single email or phone cell.
+const (
+	emailSeparators = ";,|\n "
+	phoneSeparators = ";|\n"
+)
+
+// multiValueField collects the values of a multi-valued column: the base column
+// plus its numbered variants (email, email_2, email_3, ...), each of which may
+// hold several values separated by any of the given separators.
+func multiValueField(row []string, headerMap map[string]int, base, separators string) []string {
+	type numbered struct {
+		n     int
+		field string
+	}
+	columns := []numbered{{n: 1, field: base}}
+	for header := range headerMap {
+		suffix, ok := strings.CutPrefix(header, base+"_")
+		if !ok {
+			continue
+		}
+		if n, err := strconv.Atoi(suffix); err == nil && n > 1 {
+			columns = append(columns, numbered{n: n, field: header})
+		}
+	}
+	sort.Slice(columns, func(i, j int) bool { return columns[i].n < columns[j].n })
+
+	var values []string
+	for _, col := range columns {
+		parts := strings.FieldsFunc(getField(row, headerMap, col.field), func(r rune) bool {
+			return strings.ContainsRune(separators, r)
+		})
+		for _, p := range parts {
+			if p = strings.TrimSpace(p); p != "" {
+				values = append(values, p)
+			}
+		}
+	}
+	return values
+}
+
+// uniqueValues removes case-insensitive duplicates, keeping the first occurrence.
+func uniqueValues(values []string) []string {
+	seen := make(map[string]bool, len(values))
+	result := make([]string, 0, len(values))
+	for _, v := range values {
+		key := strings.ToLower(v)
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		result = append(result, v)
+	}
+	return result
+}
+
 func getField(row []string, headerMap map[string]int, field string) string {
 	idx, ok := headerMap[field]
 	if !ok || idx >= len(row) {
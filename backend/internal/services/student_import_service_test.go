@@ -0,0 +1,885 @@
+package services_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/xuri/excelize/v2"
+	"golang.org/x/text/encoding/charmap"
+
+	"github.com/dcorreal/coordinador/internal/models"
+	"github.com/dcorreal/coordinador/internal/repositories"
+	"github.com/dcorreal/coordinador/internal/repositories/mocks"
+	"github.com/dcorreal/coordinador/internal/services"
+	"github.com/dcorreal/coordinador/internal/shared"
+)
+
+const importCSV = `first_names,last_names,nationality_country_id,status,cohort,enrollment_date
+Ana,Gomez,Colombia,activo,2024-1,2024-01-15
+,Perez,Colombia,activo,2024-1,2024-01-15
+Luis,Diaz,Colombia,desconocido,2024-1,
+`
+
+func xlsxWorkbook(t *testing.T, sheets map[string][][]interface{}, order ...string) []byte {
+	t.Helper()
+	f := excelize.NewFile()
+	defer f.Close()
+
+	for i, name := range order {
+		if i == 0 {
+			require.NoError(t, f.SetSheetName(f.GetSheetName(0), name))
+		} else {
+			_, err := f.NewSheet(name)
+			require.NoError(t, err)
+		}
+		for r, row := range sheets[name] {
+			cell, err := excelize.CoordinatesToCellName(1, r+1)
+			require.NoError(t, err)
+			require.NoError(t, f.SetSheetRow(name, cell, &row))
+		}
+	}
+
+	buf, err := f.WriteToBuffer()
+	require.NoError(t, err)
+	return buf.Bytes()
+}
+
+func newImportService() services.StudentImportService {
+	return newImportServiceWith(new(mocks.StudentRepository), nil, nil)
+}
+
+func newImportServiceWith(studentRepo *mocks.StudentRepository, catalogRepo *mocks.CatalogRepository, enrollmentRepo *mocks.EnrollmentRepository) services.StudentImportService {
+	dates := shared.DefaultDateParser()
+	var catalog repositories.CatalogRepository
+	if catalogRepo != nil {
+		catalog = catalogRepo
+	}
+	var enrollments repositories.EnrollmentRepository
+	if enrollmentRepo != nil {
+		enrollments = enrollmentRepo
+	}
+	resolver := services.NewCatalogResolver(catalog, services.DefaultCatalogResolverOptions())
+	return services.NewStudentImportService(services.NewStudentService(studentRepo, dates), studentRepo, catalog, enrollments, resolver, dates)
+}
+
+// =============================================================================
+// BuildErrorReport
+// =============================================================================
+
+func TestBuildErrorReport_CSVOnlyFailedRows(t *testing.T) {
+	service := newImportService()
+
+	rowErrors := []models.ImportRowError{
+		{Row: 3, Field: "first_names", Message: "required field is empty"},
+		{Row: 4, Field: "_row", Message: "invalid status"},
+		{Row: 4, Field: "enrollment_date", Message: "required field is empty"},
+	}
+
+	report, err := service.BuildErrorReport(strings.NewReader(importCSV), "csv", services.CSVOptions{}, rowErrors)
+	require.NoError(t, err)
+
+	rows, err := csv.NewReader(bytes.NewReader(report)).ReadAll()
+	require.NoError(t, err)
+	require.Len(t, rows, 3)
+
+	assert.Equal(t, "errors", rows[0][len(rows[0])-1])
+	assert.Equal(t, "Perez", rows[1][1])
+	assert.Equal(t, "first_names: required field is empty", rows[1][6])
+	assert.Equal(t, "Luis", rows[2][0])
+	assert.Equal(t, "", rows[2][5])
+	assert.Equal(t, "invalid status; enrollment_date: required field is empty", rows[2][6])
+}
+
+func TestBuildErrorReport_XLSX(t *testing.T) {
+	service := newImportService()
+
+	src := excelize.NewFile()
+	sheet := src.GetSheetName(0)
+	require.NoError(t, src.SetSheetRow(sheet, "A1", &[]interface{}{"first_names", "last_names"}))
+	require.NoError(t, src.SetSheetRow(sheet, "A2", &[]interface{}{"Ana", "Gomez"}))
+	require.NoError(t, src.SetSheetRow(sheet, "A3", &[]interface{}{"Luis"}))
+	buf, err := src.WriteToBuffer()
+	require.NoError(t, err)
+
+	rowErrors := []models.ImportRowError{{Row: 3, Field: "last_names", Message: "required field is empty"}}
+
+	report, err := service.BuildErrorReport(bytes.NewReader(buf.Bytes()), "xlsx", services.CSVOptions{}, rowErrors)
+	require.NoError(t, err)
+
+	f, err := excelize.OpenReader(bytes.NewReader(report))
+	require.NoError(t, err)
+	defer f.Close()
+
+	rows, err := f.GetRows(f.GetSheetName(0))
+	require.NoError(t, err)
+	require.Len(t, rows, 2)
+	assert.Equal(t, []string{"first_names", "last_names", "errors"}, rows[0])
+	assert.Equal(t, "Luis", rows[1][0])
+	assert.Equal(t, "", rows[1][1], "short rows are padded to the header width")
+	assert.Equal(t, "last_names: required field is empty", rows[1][2])
+}
+
+func TestBuildErrorReport_XLSXDatesAsISO(t *testing.T) {
+	service := newImportService()
+
+	data := xlsxWorkbook(t, map[string][][]interface{}{
+		"Estudiantes": {
+			{"first_names", "student_code", "enrollment_date", "birth_date"},
+			{"Ana", 202610001, 45311, "20/05/1992"},
+		},
+	}, "Estudiantes")
+
+	rowErrors := []models.ImportRowError{{Row: 2, Field: "_row", Message: "invalid status"}}
+
+	report, err := service.BuildErrorReport(bytes.NewReader(data), "xlsx", services.CSVOptions{}, rowErrors)
+	require.NoError(t, err)
+
+	f, err := excelize.OpenReader(bytes.NewReader(report))
+	require.NoError(t, err)
+	defer f.Close()
+
+	rows, err := f.GetRows("Estudiantes")
+	require.NoError(t, err)
+	require.Len(t, rows, 2)
+	assert.Equal(t, []string{"Ana", "202610001", "2024-01-20", "20/05/1992", "invalid status"}, rows[1])
+}
+
+func TestBuildErrorReport_UnsupportedFormat(t *testing.T) {
+	service := newImportService()
+
+	_, err := service.BuildErrorReport(strings.NewReader(importCSV), "ods", services.CSVOptions{}, nil)
+
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "unsupported format")
+}
+
+// =============================================================================
+// BuildTemplate
+// =============================================================================
+
+func TestBuildTemplate_CSVHasRequiredHeaders(t *testing.T) {
+	service := newImportService()
+
+	template, err := service.BuildTemplate("csv")
+	require.NoError(t, err)
+
+	rows, err := csv.NewReader(bytes.NewReader(template)).ReadAll()
+	require.NoError(t, err)
+	require.Greater(t, len(rows), 1, "template includes example rows")
+
+	for _, col := range []string{"first_names", "last_names", "nationality_country_id", "status", "cohort", "enrollment_date"} {
+		assert.Contains(t, rows[0], col)
+	}
+}
+
+func TestBuildTemplate_XLSXHasInstructionsAndDropdowns(t *testing.T) {
+	service := newImportService()
+
+	template, err := service.BuildTemplate("xlsx")
+	require.NoError(t, err)
+
+	f, err := excelize.OpenReader(bytes.NewReader(template))
+	require.NoError(t, err)
+	defer f.Close()
+
+	assert.Equal(t, []string{"Estudiantes", "Instrucciones"}, f.GetSheetList())
+
+	validations, err := f.GetDataValidations("Estudiantes")
+	require.NoError(t, err)
+	require.Len(t, validations, 2)
+
+	var lists []string
+	for _, dv := range validations {
+		lists = append(lists, dv.Formula1)
+	}
+	assert.Contains(t, strings.Join(lists, " "), "activo")
+	assert.Contains(t, strings.Join(lists, " "), "graduated")
+	assert.Contains(t, strings.Join(lists, " "), `"M,F"`)
+}
+
+func TestBuildTemplate_UnsupportedFormat(t *testing.T) {
+	service := newImportService()
+
+	_, err := service.BuildTemplate("json")
+
+	assert.Error(t, err)
+}
+
+// =============================================================================
+// Multi-sheet import
+// =============================================================================
+
+func linkedWorkbook(t *testing.T, countryID uuid.UUID) []byte {
+	return xlsxWorkbook(t, map[string][][]interface{}{
+		"Estudiantes": {
+			{"first_names", "last_names", "nationality_country_id", "status", "cohort", "enrollment_date", "student_code"},
+			{"Ana", "Gomez", countryID.String(), "activo", "2026-1", "2026-01-20", "202610001"},
+		},
+		"Universidades": {
+			{"student_code", "universidad", "universidad-titulo", "universidad-anio"},
+			{"202610001", "Universidad de los Andes", "Economía", "2019"},
+		},
+		"Inscripciones": {
+			{"student_code", "course_code", "period", "status"},
+			{"202610001", "MATE-101", "2026-1", "inscrito"},
+			{"202610001", "DATA-201", "2026-1", ""},
+		},
+		"Notas": {
+			{"observaciones"},
+			{"cualquier cosa"},
+		},
+	}, "Estudiantes", "Universidades", "Inscripciones", "Notas")
+}
+
+func TestListSheets_DetectsKinds(t *testing.T) {
+	service := newImportService()
+
+	sheets, err := service.ListSheets(bytes.NewReader(linkedWorkbook(t, uuid.New())), "xlsx", services.CSVOptions{})
+
+	require.NoError(t, err)
+	require.Len(t, sheets, 4)
+	assert.Equal(t, models.ImportSheetStudents, sheets[0].Kind)
+	assert.Equal(t, 1, sheets[0].DataRows)
+	assert.Equal(t, models.ImportSheetUniversities, sheets[1].Kind)
+	assert.Equal(t, models.ImportSheetEnrollments, sheets[2].Kind)
+	assert.Equal(t, models.ImportSheetUnknown, sheets[3].Kind)
+}
+
+func TestImportFromFile_LinkedSheets(t *testing.T) {
+	studentRepo := new(mocks.StudentRepository)
+	catalogRepo := new(mocks.CatalogRepository)
+	enrollmentRepo := new(mocks.EnrollmentRepository)
+	service := newImportServiceWith(studentRepo, catalogRepo, enrollmentRepo)
+
+	countryID := uuid.New()
+	uniID := uuid.New()
+	scheduledID := uuid.New()
+
+	studentRepo.On("ExistingDocumentIDs", mock.Anything, mock.Anything).Return(map[string]bool{}, nil)
+	studentRepo.On("ExistingEmails", mock.Anything, mock.Anything).Return(map[string]bool{}, nil)
+	studentRepo.On("CreateBatch", mock.Anything, mock.Anything).Return(nil, nil)
+	catalogRepo.On("FindUniversityByName", mock.Anything, "Universidad de los Andes", countryID).Return(uniID, nil)
+	catalogRepo.On("CreateStudentUniversity", mock.Anything, mock.MatchedBy(func(link *models.StudentUniversity) bool {
+		return link.UniversityID == uniID && *link.DegreeObtained == "Economía" && *link.GraduationYear == 2019
+	})).Return(nil)
+	enrollmentRepo.On("FindScheduledCourse", mock.Anything, "MATE-101", "2026-1").Return(scheduledID, nil)
+	enrollmentRepo.On("FindScheduledCourse", mock.Anything, "DATA-201", "2026-1").Return(uuid.Nil, nil)
+	enrollmentRepo.On("Create", mock.Anything, mock.MatchedBy(func(e *models.Enrollment) bool {
+		return e.ScheduledCourseID == scheduledID && e.Status == models.EnrollmentStatusEnrolled
+	})).Return(true, nil)
+
+	result, err := service.ImportFromFile(context.Background(), bytes.NewReader(linkedWorkbook(t, countryID)), "xlsx", services.ImportOptions{}, nil)
+
+	require.NoError(t, err)
+	assert.Equal(t, 1, result.Created)
+	assert.Equal(t, 1, result.UniversitiesLinked)
+	assert.Equal(t, 1, result.EnrollmentsCreated)
+	assert.Equal(t, 4, result.TotalRows)
+	require.Len(t, result.Errors, 1)
+	assert.Equal(t, "Inscripciones", result.Errors[0].Sheet)
+	assert.Equal(t, 3, result.Errors[0].Row)
+	assert.Contains(t, result.Errors[0].Message, "not scheduled")
+	catalogRepo.AssertExpectations(t)
+	enrollmentRepo.AssertExpectations(t)
+}
+
+func TestImportFromFile_UnknownSheetSelection(t *testing.T) {
+	service := newImportService()
+
+	_, err := service.ImportFromFile(context.Background(), bytes.NewReader(linkedWorkbook(t, uuid.New())), "xlsx",
+		services.ImportOptions{Sheets: []string{"Hoja1"}}, nil)
+
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "Hoja1")
+}
+
+func TestImportFromFile_XLSXTooLarge(t *testing.T) {
+	service := newImportService()
+	upload := io.MultiReader(bytes.NewReader(linkedWorkbook(t, uuid.New())), io.LimitReader(zeroReader{}, services.MaxXLSXImportSize))
+
+	_, err := service.ImportFromFile(context.Background(), upload, "xlsx", services.ImportOptions{}, nil)
+
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "exceeds")
+}
+
+type zeroReader struct{}
+
+func (zeroReader) Read(p []byte) (int, error) {
+	clear(p)
+	return len(p), nil
+}
+
+func TestImportFromFile_SelectedSheetMissingColumns(t *testing.T) {
+	service := newImportService()
+
+	_, err := service.ImportFromFile(context.Background(), bytes.NewReader(linkedWorkbook(t, uuid.New())), "xlsx",
+		services.ImportOptions{Sheets: []string{"Notas"}}, nil)
+
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "missing required columns")
+}
+
+func TestImportFromFile_EmptyCoverSheet(t *testing.T) {
+	studentRepo := new(mocks.StudentRepository)
+	service := newImportServiceWith(studentRepo, nil, nil)
+
+	studentRepo.On("ExistingDocumentIDs", mock.Anything, mock.Anything).Return(map[string]bool{}, nil)
+	studentRepo.On("ExistingEmails", mock.Anything, mock.Anything).Return(map[string]bool{}, nil)
+	studentRepo.On("CreateBatch", mock.Anything, mock.Anything).Return(nil, nil)
+
+	workbook := xlsxWorkbook(t, map[string][][]interface{}{
+		"Portada": {},
+		"Datos": {
+			{"first_names", "last_names", "nationality_country_id", "status", "cohort", "enrollment_date"},
+			{"Ana", "Gomez", uuid.New().String(), "activo", "2026-1", "2026-01-20"},
+		},
+	}, "Portada", "Datos")
+
+	result, err := service.ImportFromFile(context.Background(), bytes.NewReader(workbook), "xlsx",
+		services.ImportOptions{Sheets: []string{"Datos"}}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, 1, result.Created)
+
+	_, err = service.ImportFromFile(context.Background(), bytes.NewReader(workbook), "xlsx",
+		services.ImportOptions{Sheets: []string{"Portada"}}, nil)
+	assert.ErrorContains(t, err, "must have a header row")
+}
+
+// =============================================================================
+// Multi-valued emails and phones
+// =============================================================================
+
+func TestImportFromFile_MultipleEmailsAndPhones(t *testing.T) {
+	studentRepo := new(mocks.StudentRepository)
+	service := newImportServiceWith(studentRepo, nil, nil)
+
+	countryID := uuid.New().String()
+	data := "first_names,last_names,nationality_country_id,status,cohort,enrollment_date,email,email_2,phone,phone_2\n" +
+		"Ana,Gomez," + countryID + ",activo,2026-1,2026-01-20,ana@uni.edu; ana@mail.com,ANA@uni.edu,+57 300 1;+57 300 2,+57 300 3\n" +
+		"Luis,Diaz," + countryID + ",activo,2026-1,2026-01-20,luis@uni.edu,ana@mail.com,,\n" +
+		"Eva,Ruiz," + countryID + ",activo,2026-1,2026-01-20,eva@uni.edu|no-es-correo,,,\n" +
+		"Sol,Paz," + countryID + ",activo,2026-1,2026-01-20,sol@uni.edu,viejo@uni.edu,,\n"
+
+	studentRepo.On("ExistingDocumentIDs", mock.Anything, mock.Anything).Return(map[string]bool{}, nil)
+	studentRepo.On("ExistingEmails", mock.Anything, mock.MatchedBy(func(emails []string) bool {
+		return len(emails) == 9
+	})).Return(map[string]bool{"viejo@uni.edu": true}, nil)
+
+	var created []*models.Student
+	studentRepo.On("CreateBatch", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		created = append(created, args.Get(1).([]*models.Student)...)
+	}).Return(nil, nil)
+
+	result, err := service.ImportFromFile(context.Background(), strings.NewReader(data), "csv", services.ImportOptions{}, nil)
+
+	require.NoError(t, err)
+	assert.Equal(t, 1, result.Created)
+	require.Len(t, created, 1)
+	assert.Equal(t, []string{"ana@uni.edu", "ana@mail.com"}, created[0].Emails)
+	assert.Equal(t, []string{"+57 300 1", "+57 300 2", "+57 300 3"}, created[0].Phones)
+
+	require.Len(t, result.Errors, 3)
+	assert.Equal(t, 3, result.Errors[0].Row)
+	assert.Contains(t, result.Errors[0].Message, "same email as row 2")
+	assert.Equal(t, 4, result.Errors[1].Row)
+	assert.Equal(t, "no-es-correo", result.Errors[1].Value)
+	assert.Equal(t, 5, result.Errors[2].Row)
+	assert.Contains(t, result.Errors[2].Message, "already exists")
+}
+
+func TestImportFromFile_ExistingEmailIgnoresCase(t *testing.T) {
+	studentRepo := new(mocks.StudentRepository)
+	service := newImportServiceWith(studentRepo, nil, nil)
+
+	countryID := uuid.New().String()
+	data := "first_names,last_names,nationality_country_id,status,cohort,enrollment_date,email\n" +
+		"Ana,Gomez," + countryID + ",activo,2026-1,2026-01-20,Ana.Gomez@Uni.edu\n"
+
+	studentRepo.On("ExistingDocumentIDs", mock.Anything, mock.Anything).Return(map[string]bool{}, nil)
+	// The repository matches case-insensitively and returns lowercase keys
+	studentRepo.On("ExistingEmails", mock.Anything, []string{"Ana.Gomez@Uni.edu"}).
+		Return(map[string]bool{"ana.gomez@uni.edu": true}, nil)
+
+	result, err := service.ImportFromFile(context.Background(), strings.NewReader(data), "csv", services.ImportOptions{}, nil)
+
+	require.NoError(t, err)
+	assert.Equal(t, 0, result.Created)
+	require.Len(t, result.Errors, 1)
+	assert.Equal(t, "Ana.Gomez@Uni.edu", result.Errors[0].Value)
+	assert.Contains(t, result.Errors[0].Message, "already exists")
+	studentRepo.AssertNotCalled(t, "CreateBatch", mock.Anything, mock.Anything)
+}
+
+// =============================================================================
+// Company resolution
+// =============================================================================
+
+func TestImportFromFile_ResolvesCompanyByName(t *testing.T) {
+	studentRepo := new(mocks.StudentRepository)
+	catalogRepo := new(mocks.CatalogRepository)
+	service := newImportServiceWith(studentRepo, catalogRepo, nil)
+
+	countryID := uuid.New().String()
+	companyID := uuid.New()
+	data := "first_names,last_names,nationality_country_id,status,cohort,enrollment_date,company_id\n" +
+		"Ana,Gomez," + countryID + ",activo,2026-1,2026-01-20,Ecopetrol S.A.\n" +
+		"Luis,Diaz," + countryID + ",activo,2026-1,2026-01-20, ecopetrol s.a. \n"
+
+	studentRepo.On("ExistingDocumentIDs", mock.Anything, mock.Anything).Return(map[string]bool{}, nil)
+	studentRepo.On("ExistingEmails", mock.Anything, mock.Anything).Return(map[string]bool{}, nil)
+	studentRepo.On("CreateBatch", mock.Anything, mock.MatchedBy(func(students []*models.Student) bool {
+		return len(students) == 2 && *students[0].CompanyID == companyID && *students[1].CompanyID == companyID
+	})).Return(nil, nil).Once()
+	catalogRepo.On("FindCompanyByName", mock.Anything, "Ecopetrol S.A.").Return(uuid.Nil, nil).Once()
+	catalogRepo.On("FindByAlias", mock.Anything, models.CatalogCompanies, "Ecopetrol S.A.", (*uuid.UUID)(nil)).Return(uuid.Nil, nil).Once()
+	catalogRepo.On("FindSimilar", mock.Anything, models.CatalogCompanies, "Ecopetrol S.A.", (*uuid.UUID)(nil), mock.Anything, mock.Anything).Return([]models.CatalogMatch{}, nil).Once()
+	catalogRepo.On("FindOrCreateCompany", mock.Anything, "Ecopetrol S.A.").Return(companyID, nil).Once()
+	catalogRepo.On("RefreshStudentsByCompany", mock.Anything).Return(nil).Once()
+
+	result, err := service.ImportFromFile(context.Background(), strings.NewReader(data), "csv", services.ImportOptions{}, nil)
+
+	require.NoError(t, err)
+	assert.Equal(t, 2, result.Created)
+	assert.Empty(t, result.Errors)
+	studentRepo.AssertExpectations(t)
+	catalogRepo.AssertExpectations(t)
+}
+
+func TestImportFromFile_SuggestsSimilarCountry(t *testing.T) {
+	studentRepo := new(mocks.StudentRepository)
+	catalogRepo := new(mocks.CatalogRepository)
+	service := newImportServiceWith(studentRepo, catalogRepo, nil)
+
+	data := "first_names,last_names,nationality_country_id,status,cohort,enrollment_date\n" +
+		"Ana,Gomez,Colombai,activo,2026-1,2026-01-20\n"
+
+	studentRepo.On("ExistingDocumentIDs", mock.Anything, mock.Anything).Return(map[string]bool{}, nil)
+	studentRepo.On("ExistingEmails", mock.Anything, mock.Anything).Return(map[string]bool{}, nil)
+	catalogRepo.On("FindCountryByName", mock.Anything, "Colombai").Return(uuid.Nil, nil)
+	catalogRepo.On("FindByAlias", mock.Anything, models.CatalogCountries, "Colombai", (*uuid.UUID)(nil)).Return(uuid.Nil, nil)
+	catalogRepo.On("FindSimilar", mock.Anything, models.CatalogCountries, "Colombai", (*uuid.UUID)(nil), mock.Anything, mock.Anything).
+		Return([]models.CatalogMatch{{ID: uuid.New(), Name: "Colombia", Similarity: 0.5}}, nil)
+
+	result, err := service.ImportFromFile(context.Background(), strings.NewReader(data), "csv", services.ImportOptions{}, nil)
+
+	require.NoError(t, err)
+	assert.Equal(t, 0, result.Created)
+	require.Len(t, result.Errors, 1)
+	assert.Equal(t, "nationality_country_id", result.Errors[0].Field)
+	assert.Equal(t, `unknown country "Colombai", did you mean Colombia?`, result.Errors[0].Message)
+	assert.Equal(t, []string{"Colombia"}, result.Errors[0].Suggestions)
+	catalogRepo.AssertNotCalled(t, "FindOrCreateCountry", mock.Anything, mock.Anything, mock.Anything)
+}
+
+// =============================================================================
+// Streaming
+// =============================================================================
+
+func TestImportFromFile_StreamsRowsInBatches(t *testing.T) {
+	studentRepo := new(mocks.StudentRepository)
+	service := newImportServiceWith(studentRepo, nil, nil)
+
+	countryID := uuid.New().String()
+	var data strings.Builder
+	data.WriteString("first_names,last_names,document_id,nationality_country_id,status,cohort,enrollment_date\n")
+	for i := 0; i < 1200; i++ {
+		if i == 10 {
+			data.WriteString(",,,,,,\n") // blank rows are skipped but keep the numbering
+		}
+		fmt.Fprintf(&data, "Ana,Gomez,%d,%s,activo,2026-1,2026-01-20\n", 1000+i, countryID)
+	}
+	// Duplicate of the first row, detected in a later batch
+	fmt.Fprintf(&data, "Luis,Diaz,1000,%s,activo,2026-1,2026-01-20\n", countryID)
+
+	studentRepo.On("ExistingDocumentIDs", mock.Anything, mock.Anything).Return(map[string]bool{}, nil).Times(3)
+	studentRepo.On("ExistingEmails", mock.Anything, mock.Anything).Return(map[string]bool{}, nil).Times(3)
+	studentRepo.On("CreateBatch", mock.Anything, mock.Anything).Return(nil, nil).Times(3)
+
+	result, err := service.ImportFromFile(context.Background(), strings.NewReader(data.String()), "csv", services.ImportOptions{}, nil)
+
+	require.NoError(t, err)
+	assert.Equal(t, 1201, result.TotalRows)
+	assert.Equal(t, 1200, result.Created)
+	require.Len(t, result.Errors, 1)
+	assert.Equal(t, 1203, result.Errors[0].Row)
+	assert.Equal(t, "document_id", result.Errors[0].Field)
+	studentRepo.AssertExpectations(t)
+}
+
+func TestImportFromFile_BatchInsertErrorsKeepRowNumbers(t *testing.T) {
+	studentRepo := new(mocks.StudentRepository)
+	service := newImportServiceWith(studentRepo, nil, nil)
+
+	countryID := uuid.New().String()
+	data := "first_names,last_names,document_id,nationality_country_id,status,cohort,enrollment_date\n" +
+		"Ana,Gomez,100," + countryID + ",activo,2026-1,2026-01-20\n" +
+		",Diaz,101," + countryID + ",activo,2026-1,2026-01-20\n" +
+		"Eva,Ruiz,102," + countryID + ",activo,2026-1,2026-01-20\n" +
+		"Sol,Paz,103," + countryID + ",activo,2026-1,2026-01-20\n"
+
+	studentRepo.On("ExistingDocumentIDs", mock.Anything, mock.Anything).Return(map[string]bool{}, nil)
+	studentRepo.On("ExistingEmails", mock.Anything, mock.Anything).Return(map[string]bool{}, nil)
+	studentRepo.On("CreateBatch", mock.Anything, mock.MatchedBy(func(students []*models.Student) bool {
+		return len(students) == 3
+	})).Return([]error{nil, fmt.Errorf("failed to create student: duplicate key"), nil}, nil).Once()
+
+	result, err := service.ImportFromFile(context.Background(), strings.NewReader(data), "csv", services.ImportOptions{}, nil)
+
+	require.NoError(t, err)
+	assert.Equal(t, 4, result.TotalRows)
+	assert.Equal(t, 2, result.Created)
+	require.Len(t, result.Errors, 2)
+	assert.Equal(t, 3, result.Errors[0].Row)
+	assert.Equal(t, "first_names", result.Errors[0].Field)
+	assert.Equal(t, 4, result.Errors[1].Row)
+	assert.Equal(t, "_row", result.Errors[1].Field)
+	assert.Contains(t, result.Errors[1].Message, "duplicate key")
+	studentRepo.AssertExpectations(t)
+}
+
+func TestImportFromFile_ErrorsInRowOrder(t *testing.T) {
+	studentRepo := new(mocks.StudentRepository)
+	service := newImportServiceWith(studentRepo, nil, nil)
+
+	countryID := uuid.New().String()
+	data := "first_names,last_names,document_id,nationality_country_id,status,cohort,enrollment_date\n" +
+		"Ana,Gomez,100," + countryID + ",activo,2026-1,2026-01-20\n" +
+		"Eva,Ruiz,102," + countryID + ",activo,2026-1,2026-01-20\n" +
+		",Diaz,101," + countryID + ",activo,2026-1,2026-01-20\n"
+
+	studentRepo.On("ExistingDocumentIDs", mock.Anything, mock.Anything).Return(map[string]bool{}, nil)
+	studentRepo.On("ExistingEmails", mock.Anything, mock.Anything).Return(map[string]bool{}, nil)
+	studentRepo.On("CreateBatch", mock.Anything, mock.Anything).
+		Return([]error{fmt.Errorf("failed to create student: duplicate key"), nil}, nil).Once()
+
+	result, err := service.ImportFromFile(context.Background(), strings.NewReader(data), "csv", services.ImportOptions{}, nil)
+
+	require.NoError(t, err)
+	require.Len(t, result.Errors, 2)
+	assert.Equal(t, 2, result.Errors[0].Row)
+	assert.Equal(t, "_row", result.Errors[0].Field)
+	assert.Equal(t, 4, result.Errors[1].Row)
+	assert.Equal(t, "first_names", result.Errors[1].Field)
+}
+
+func TestImportFromFile_BatchFailureAbortsImport(t *testing.T) {
+	studentRepo := new(mocks.StudentRepository)
+	service := newImportServiceWith(studentRepo, nil, nil)
+
+	countryID := uuid.New().String()
+	data := "first_names,last_names,nationality_country_id,status,cohort,enrollment_date\n" +
+		"Ana,Gomez," + countryID + ",activo,2026-1,2026-01-20\n"
+
+	studentRepo.On("ExistingDocumentIDs", mock.Anything, mock.Anything).Return(map[string]bool{}, nil)
+	studentRepo.On("ExistingEmails", mock.Anything, mock.Anything).Return(map[string]bool{}, nil)
+	studentRepo.On("CreateBatch", mock.Anything, mock.Anything).Return(nil, context.Canceled).Once()
+
+	result, err := service.ImportFromFile(context.Background(), strings.NewReader(data), "csv", services.ImportOptions{}, nil)
+
+	assert.ErrorIs(t, err, context.Canceled)
+	require.NotNil(t, result)
+	assert.Equal(t, 0, result.Created)
+	require.NotNil(t, result.Fatal)
+	assert.Equal(t, 2, result.Fatal.Row)
+}
+
+func TestImportFromFile_BatchFailureKeepsEarlierBatches(t *testing.T) {
+	studentRepo := new(mocks.StudentRepository)
+	service := newImportServiceWith(studentRepo, nil, nil)
+
+	countryID := uuid.New().String()
+	var data strings.Builder
+	data.WriteString("first_names,last_names,document_id,nationality_country_id,status,cohort,enrollment_date\n")
+	for i := 0; i < 502; i++ {
+		fmt.Fprintf(&data, "Ana,Gomez,%d,%s,activo,2026-1,2026-01-20\n", 1000+i, countryID)
+	}
+
+	studentRepo.On("ExistingDocumentIDs", mock.Anything, mock.Anything).Return(map[string]bool{}, nil)
+	studentRepo.On("ExistingEmails", mock.Anything, mock.Anything).Return(map[string]bool{}, nil)
+	studentRepo.On("CreateBatch", mock.Anything, mock.Anything).Return(nil, nil).Once()
+	studentRepo.On("CreateBatch", mock.Anything, mock.Anything).Return(nil, fmt.Errorf("conn closed")).Once()
+
+	result, err := service.ImportFromFile(context.Background(), strings.NewReader(data.String()), "csv", services.ImportOptions{}, nil)
+
+	assert.ErrorContains(t, err, "conn closed")
+	require.NotNil(t, result)
+	assert.Equal(t, 502, result.TotalRows)
+	assert.Equal(t, 500, result.Created)
+	require.NotNil(t, result.Fatal)
+	assert.Equal(t, 502, result.Fatal.Row)
+	assert.Contains(t, result.Fatal.Message, "conn closed")
+	require.Len(t, result.Errors, 2)
+	assert.Equal(t, 502, result.Errors[0].Row)
+	assert.Equal(t, 503, result.Errors[1].Row)
+	studentRepo.AssertExpectations(t)
+}
+
+func TestImportFromFile_GraduatedNeedsGraduationDate(t *testing.T) {
+	studentRepo := new(mocks.StudentRepository)
+	service := newImportServiceWith(studentRepo, nil, nil)
+
+	countryID := uuid.New().String()
+	data := "first_names,last_names,nationality_country_id,status,cohort,enrollment_date,graduation_date\n" +
+		"Ana,Gomez," + countryID + ",graduado,2024-1,2024-01-20,\n" +
+		"Luis,Mora," + countryID + ",graduado,2024-1,2024-01-20,05/12/2025\n"
+
+	studentRepo.On("ExistingDocumentIDs", mock.Anything, mock.Anything).Return(map[string]bool{}, nil)
+	studentRepo.On("ExistingEmails", mock.Anything, mock.Anything).Return(map[string]bool{}, nil)
+	var created []*models.Student
+	studentRepo.On("CreateBatch", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		created = args.Get(1).([]*models.Student)
+	}).Return([]error{nil}, nil)
+
+	result, err := service.ImportFromFile(context.Background(), strings.NewReader(data), "csv", services.ImportOptions{}, nil)
+
+	require.NoError(t, err)
+	assert.Equal(t, 1, result.Created)
+	require.Len(t, result.Errors, 1)
+	assert.Equal(t, models.ImportRowError{Row: 2, Field: "graduation_date", Message: "required for graduated students"}, result.Errors[0])
+	require.Len(t, created, 1)
+	require.NotNil(t, created[0].GraduationDate)
+	assert.Equal(t, time.Date(2025, 12, 5, 0, 0, 0, 0, time.UTC), *created[0].GraduationDate)
+}
+
+// =============================================================================
+// Universities on the student sheet
+// =============================================================================
+
+func TestImportFromFile_MultipleUniversitiesPerStudent(t *testing.T) {
+	studentRepo := new(mocks.StudentRepository)
+	catalogRepo := new(mocks.CatalogRepository)
+	service := newImportServiceWith(studentRepo, catalogRepo, nil)
+
+	countryID := uuid.New()
+	andesID := uuid.New()
+	nacionalID := uuid.New()
+	header := "first_names,last_names,nationality_country_id,status,cohort,enrollment_date," +
+		"universidad,universidad-titulo,universidad-anio,universidad_2,universidad-titulo_2,universidad-anio_2\n"
+	data := header +
+		"Ana,Gomez," + countryID.String() + ",activo,2026-1,2026-01-20,Andes,Economía,2015,Nacional,Maestría en Finanzas,2019\n" +
+		"Luis,Diaz," + countryID.String() + ",activo,2026-1,2026-01-20,Andes,Derecho,1949,,,\n" +
+		"Eva,Ruiz," + countryID.String() + ",activo,2026-1,2026-01-20,,,,,Ingeniería,2020\n"
+
+	studentRepo.On("ExistingDocumentIDs", mock.Anything, mock.Anything).Return(map[string]bool{}, nil)
+	studentRepo.On("ExistingEmails", mock.Anything, mock.Anything).Return(map[string]bool{}, nil)
+	studentRepo.On("CreateBatch", mock.Anything, mock.MatchedBy(func(students []*models.Student) bool {
+		return len(students) == 1
+	})).Return(nil, nil).Once()
+	catalogRepo.On("FindUniversityByName", mock.Anything, "Andes", countryID).Return(andesID, nil)
+	catalogRepo.On("FindUniversityByName", mock.Anything, "Nacional", countryID).Return(nacionalID, nil)
+	catalogRepo.On("CreateStudentUniversity", mock.Anything, mock.MatchedBy(func(link *models.StudentUniversity) bool {
+		return link.UniversityID == andesID && *link.DegreeObtained == "Economía" && *link.GraduationYear == 2015
+	})).Return(nil).Once()
+	catalogRepo.On("CreateStudentUniversity", mock.Anything, mock.MatchedBy(func(link *models.StudentUniversity) bool {
+		return link.UniversityID == nacionalID && *link.DegreeObtained == "Maestría en Finanzas" && *link.GraduationYear == 2019
+	})).Return(nil).Once()
+
+	result, err := service.ImportFromFile(context.Background(), strings.NewReader(data), "csv", services.ImportOptions{}, nil)
+
+	require.NoError(t, err)
+	assert.Equal(t, 1, result.Created)
+	assert.Equal(t, 2, result.UniversitiesLinked)
+	require.Len(t, result.Errors, 2)
+	assert.Equal(t, 3, result.Errors[0].Row)
+	assert.Equal(t, "universidad-anio", result.Errors[0].Field)
+	assert.Contains(t, result.Errors[0].Message, "between 1950")
+	assert.Equal(t, 4, result.Errors[1].Row)
+	assert.Equal(t, "universidad_2", result.Errors[1].Field)
+	catalogRepo.AssertExpectations(t)
+}
+
+func TestImportFromFile_UniversityLinkFailureIsWarning(t *testing.T) {
+	studentRepo := new(mocks.StudentRepository)
+	catalogRepo := new(mocks.CatalogRepository)
+	service := newImportServiceWith(studentRepo, catalogRepo, nil)
+
+	countryID := uuid.New()
+	uniID := uuid.New()
+	data := "first_names,last_names,nationality_country_id,status,cohort,enrollment_date,universidad\n" +
+		"Ana,Gomez," + countryID.String() + ",activo,2026-1,2026-01-20,Andes\n"
+
+	studentRepo.On("ExistingDocumentIDs", mock.Anything, mock.Anything).Return(map[string]bool{}, nil)
+	studentRepo.On("ExistingEmails", mock.Anything, mock.Anything).Return(map[string]bool{}, nil)
+	studentRepo.On("CreateBatch", mock.Anything, mock.Anything).Return(nil, nil)
+	catalogRepo.On("FindUniversityByName", mock.Anything, "Andes", countryID).Return(uniID, nil)
+	catalogRepo.On("CreateStudentUniversity", mock.Anything, mock.Anything).Return(fmt.Errorf("failed to create student_university: connection reset"))
+
+	result, err := service.ImportFromFile(context.Background(), strings.NewReader(data), "csv", services.ImportOptions{}, nil)
+
+	require.NoError(t, err)
+	assert.Equal(t, 1, result.Created)
+	assert.Equal(t, 0, result.UniversitiesLinked)
+	assert.Empty(t, result.Errors)
+	require.Len(t, result.Warnings, 1)
+	assert.Equal(t, 2, result.Warnings[0].Row)
+	assert.Equal(t, "_row", result.Warnings[0].Field)
+	assert.Contains(t, result.Warnings[0].Message, "connection reset")
+}
+
+// =============================================================================
+// Dates
+// =============================================================================
+
+func TestImportFromFile_ExcelSerialAndDayMonthDates(t *testing.T) {
+	studentRepo := new(mocks.StudentRepository)
+	service := newImportServiceWith(studentRepo, nil, nil)
+
+	countryID := uuid.New().String()
+	data := xlsxWorkbook(t, map[string][][]interface{}{
+		"Estudiantes": {
+			{"first_names", "last_names", "nationality_country_id", "status", "cohort", "enrollment_date", "birth_date"},
+			{"Ana", "Gomez", countryID, "activo", "2024-1", 45311, "20/05/1992"},
+			{"Luis", "Diaz", countryID, "activo", "2024-1", "2024/01/20", ""},
+		},
+	}, "Estudiantes")
+
+	var created []*models.Student
+	studentRepo.On("ExistingDocumentIDs", mock.Anything, mock.Anything).Return(map[string]bool{}, nil)
+	studentRepo.On("ExistingEmails", mock.Anything, mock.Anything).Return(map[string]bool{}, nil)
+	studentRepo.On("CreateBatch", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		created = append(created, args.Get(1).([]*models.Student)...)
+	}).Return(nil, nil)
+
+	result, err := service.ImportFromFile(context.Background(), bytes.NewReader(data), "xlsx", services.ImportOptions{}, nil)
+
+	require.NoError(t, err)
+	require.Len(t, created, 1)
+	assert.Equal(t, time.Date(2024, 1, 20, 0, 0, 0, 0, time.UTC), created[0].EnrollmentDate)
+	assert.Equal(t, time.Date(1992, 5, 20, 0, 0, 0, 0, time.UTC), *created[0].BirthDate)
+	require.Len(t, result.Errors, 1)
+	assert.Equal(t, 3, result.Errors[0].Row)
+	assert.Equal(t, "enrollment_date", result.Errors[0].Field)
+	assert.Contains(t, result.Errors[0].Message, "DD/MM/YYYY")
+}
+
+// =============================================================================
+// CSV dialects
+// =============================================================================
+
+// windows1252 encodes s as Windows-1252, the way Excel in Spanish locales saves CSV.
+func windows1252(t *testing.T, s string) []byte {
+	t.Helper()
+	encoded, err := charmap.Windows1252.NewEncoder().String(s)
+	require.NoError(t, err)
+	return []byte(encoded)
+}
+
+func TestImportFromFile_DetectsWindows1252Semicolons(t *testing.T) {
+	studentRepo := new(mocks.StudentRepository)
+	service := newImportServiceWith(studentRepo, nil, nil)
+
+	countryID := uuid.New().String()
+	data := windows1252(t, "first_names;last_names;nationality_country_id;status;cohort;enrollment_date\r\n"+
+		"José Ñandú;Muñoz, Peña;"+countryID+";activo;2026-1;20/01/2026\r\n")
+
+	var created []*models.Student
+	studentRepo.On("ExistingDocumentIDs", mock.Anything, mock.Anything).Return(map[string]bool{}, nil)
+	studentRepo.On("ExistingEmails", mock.Anything, mock.Anything).Return(map[string]bool{}, nil)
+	studentRepo.On("CreateBatch", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		created = append(created, args.Get(1).([]*models.Student)...)
+	}).Return(nil, nil)
+
+	result, err := service.ImportFromFile(context.Background(), bytes.NewReader(data), "csv", services.ImportOptions{}, nil)
+
+	require.NoError(t, err)
+	assert.Empty(t, result.Errors)
+	require.Len(t, created, 1)
+	assert.Equal(t, "José Ñandú", created[0].FirstNames)
+	assert.Equal(t, "Muñoz, Peña", created[0].LastNames)
+}
+
+func TestListSheets_CSVDialects(t *testing.T) {
+	service := newImportService()
+
+	tests := []struct {
+		name string
+		data []byte
+		opts services.CSVOptions
+	}{
+		{"utf-8 with BOM and tabs", []byte("\xEF\xBB\xBFfirst_names\tlast_names\tcohort\nAna\tGómez\t2026-1\n"), services.CSVOptions{}},
+		{"windows-1252 with semicolons", windows1252(t, "first_names;last_names;cohort\nAna;Gómez;2026-1\n"), services.CSVOptions{}},
+		{"explicit override", []byte("first_names|last_names|cohort\nAna|Gómez, hija|2026-1\n"), services.CSVOptions{Encoding: "utf-8", Delimiter: "|"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sheets, err := service.ListSheets(bytes.NewReader(tt.data), "csv", tt.opts)
+
+			require.NoError(t, err)
+			require.Len(t, sheets, 1)
+			assert.Equal(t, []string{"first_names", "last_names", "cohort"}, sheets[0].Headers)
+			assert.Equal(t, 1, sheets[0].DataRows)
+		})
+	}
+}
+
+func TestListSheets_UnsupportedEncoding(t *testing.T) {
+	service := newImportService()
+
+	_, err := service.ListSheets(strings.NewReader(importCSV), "csv", services.CSVOptions{Encoding: "ebcdic"})
+
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "unsupported encoding")
+}
+
+func TestBuildErrorReport_KeepsCSVDialect(t *testing.T) {
+	service := newImportService()
+
+	data := windows1252(t, "first_names;last_names\nJosé;Peña\n")
+	rowErrors := []models.ImportRowError{{Row: 2, Field: "last_names", Message: "inválido"}}
+
+	report, err := service.BuildErrorReport(bytes.NewReader(data), "csv", services.CSVOptions{}, rowErrors)
+
+	require.NoError(t, err)
+	assert.Equal(t, windows1252(t, "first_names;last_names;errors\nJosé;Peña;last_names: inválido\n"), report)
+}
+
+// Run with -race: concurrent imports share the resolver's cache.
+func TestImportFromFile_ConcurrentImportsShareResolver(t *testing.T) {
+	studentRepo := new(mocks.StudentRepository)
+	catalogRepo := new(mocks.CatalogRepository)
+	service := newImportServiceWith(studentRepo, catalogRepo, nil)
+
+	countryID := uuid.New()
+	professionID := uuid.New()
+	data := "first_names,last_names,nationality_country_id,status,cohort,enrollment_date,profession_id\n" +
+		"Ana,Gomez,Colombia,activo,2026-1,2026-01-20,Economista\n" +
+		"Luis,Diaz,colombia,activo,2026-1,2026-01-20,economista\n"
+
+	studentRepo.On("ExistingDocumentIDs", mock.Anything, mock.Anything).Return(map[string]bool{}, nil)
+	studentRepo.On("ExistingEmails", mock.Anything, mock.Anything).Return(map[string]bool{}, nil)
+	studentRepo.On("CreateBatch", mock.Anything, mock.MatchedBy(func(students []*models.Student) bool {
+		return len(students) == 2 && students[0].NationalityCountryID == countryID && *students[1].ProfessionID == professionID
+	})).Return(nil, nil)
+	catalogRepo.On("FindCountryByName", mock.Anything, mock.Anything).Return(countryID, nil)
+	catalogRepo.On("FindProfessionByName", mock.Anything, mock.Anything).Return(professionID, nil)
+
+	var wg sync.WaitGroup
+	results := make([]*models.ImportResult, 8)
+	errs := make([]error, len(results))
+	for i := range results {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			results[i], errs[i] = service.ImportFromFile(context.Background(), strings.NewReader(data), "csv", services.ImportOptions{}, nil)
+		}(i)
+	}
+	wg.Wait()
+
+	for i := range results {
+		require.NoError(t, errs[i])
+		assert.Equal(t, 2, results[i].Created)
+		assert.Empty(t, results[i].Errors)
+	}
+}
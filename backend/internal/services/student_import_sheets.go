@@ -0,0 +1,305 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/google/uuid"
+
+	"github.com/dcorreal/coordinador/internal/models"
+)
+
+// importSheet describes a sheet of an import file: its header row and whether it
+// has any data rows. Name is empty for CSV files. Rows are read through importFile.
+type importSheet struct {
+	Name    string
+	Header  []string
+	HasData bool
+}
+
+// Headers identifying the linked sheets of a multi-sheet workbook.
+var (
+	universitySheetColumns = []string{"student_code", "universidad"}
+	enrollmentSheetColumns = []string{"student_code", "course_code", "period"}
+)
+
+// enrollmentStatusMap translates Spanish enrollment status values to English.
+var enrollmentStatusMap = map[string]string{
+	"inscrito":   "enrolled",
+	"aprobado":   "completed",
+	"completado": "completed",
+	"retirado":   "withdrawn",
+	"reprobado":  "failed",
+}
+
+// kind classifies the sheet by its header row.
+func (sh importSheet) kind() models.ImportSheetKind {
+	if len(sh.Header) == 0 {
+		return models.ImportSheetUnknown
+	}
+	headers := make(map[string]bool)
+	for _, h := range sh.Header {
+		headers[strings.ToLower(strings.TrimSpace(h))] = true
+	}
+	hasAll := func(cols []string) bool {
+		for _, c := range cols {
+			if !headers[c] {
+				return false
+			}
+		}
+		return true
+	}
+
+	switch {
+	case hasAll(requiredImportColumns()):
+		return models.ImportSheetStudents
+	case hasAll(enrollmentSheetColumns):
+		return models.ImportSheetEnrollments
+	case hasAll(universitySheetColumns):
+		return models.ImportSheetUniversities
+	default:
+		return models.ImportSheetUnknown
+	}
+}
+
+// label prefixes sheet-level errors with the sheet name, if any.
+func (sh importSheet) label() string {
+	if sh.Name == "" {
+		return ""
+	}
+	return fmt.Sprintf("sheet %q: ", sh.Name)
+}
+
+// tag sets the sheet name on row errors.
+func (sh importSheet) tag(rowErrors []models.ImportRowError) []models.ImportRowError {
+	for i := range rowErrors {
+		rowErrors[i].Sheet = sh.Name
+	}
+	return rowErrors
+}
+
+// selectSheets picks the sheets to import. An explicit selection is imported as-is
+// (unrecognized sheets are treated as student sheets so missing columns are reported);
+// otherwise every recognized sheet is used, falling back to the first sheet.
+func selectSheets(sheets []importSheet, names []string) ([]importSheet, error) {
+	if len(names) > 0 {
+		byName := make(map[string]importSheet, len(sheets))
+		for _, sheet := range sheets {
+			byName[sheet.Name] = sheet
+		}
+		selected := make([]importSheet, 0, len(names))
+		for _, name := range names {
+			sheet, ok := byName[name]
+			if !ok {
+				return nil, fmt.Errorf("sheet %q not found", name)
+			}
+			selected = append(selected, sheet)
+		}
+		return selected, nil
+	}
+
+	var selected []importSheet
+	hasStudents := false
+	for _, sheet := range sheets {
+		switch sheet.kind() {
+		case models.ImportSheetStudents:
+			hasStudents = true
+			selected = append(selected, sheet)
+		case models.ImportSheetUniversities, models.ImportSheetEnrollments:
+			selected = append(selected, sheet)
+		}
+	}
+	if !hasStudents {
+		// Keep the single-sheet behavior: the first sheet is the student list
+		selected = append([]importSheet{firstSheet(sheets)}, selected...)
+	}
+	return selected, nil
+}
+
+// firstSheet returns the first sheet with a header, skipping empty sheets such as
+// a cover, or the first sheet if all are empty.
+func firstSheet(sheets []importSheet) importSheet {
+	for _, sheet := range sheets {
+		if len(sheet.Header) > 0 {
+			return sheet
+		}
+	}
+	return sheets[0]
+}
+
+// linkedStudent returns the student referenced by student_code, looking first at the
+// students created in this import and then at the database.
+func (s *studentImportService) linkedStudent(ctx context.Context, code string, run *importRun) (studentRef, error) {
+	if student, ok := run.students[code]; ok {
+		return student, nil
+	}
+	student, err := s.studentRepo.GetByStudentCode(ctx, code)
+	if err != nil {
+		return studentRef{}, err
+	}
+	ref := studentRef{ID: student.ID, NationalityCountryID: student.NationalityCountryID}
+	run.students[code] = ref
+	return ref, nil
+}
+
+// eachRow calls fn for every data row of the sheet, counting it in the result total.
+func eachRow(f importFile, sheet importSheet, result *models.ImportResult, fn func(rowNum int, row []string)) error {
+	rows, err := f.Rows(sheet.Name)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for {
+		rowNum, row, err := rows.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("%sfailed to read row: %w", sheet.label(), err)
+		}
+		result.TotalRows++
+		fn(rowNum, row)
+	}
+}
+
+// importUniversitySheet links students to their universities, one row per student/university.
+func (s *studentImportService) importUniversitySheet(ctx context.Context, f importFile, sheet importSheet, run *importRun, result *models.ImportResult) error {
+	headerMap := headerIndex(sheet.Header)
+
+	return eachRow(f, sheet, result, func(rowNum int, row []string) {
+		studentCode := strings.TrimSpace(getField(row, headerMap, "student_code"))
+
+		rowError := func(field, value, message string) {
+			result.Errors = append(result.Errors, models.ImportRowError{
+				Sheet: sheet.Name, Row: rowNum, Field: field, Value: value, Message: message,
+			})
+		}
+
+		errorCount := len(result.Errors)
+		if studentCode == "" {
+			rowError("student_code", "", "required field is empty")
+		}
+		university, ok := readRowUniversity(row, headerMap, "", rowError)
+		if !ok {
+			rowError("universidad", "", "required field is empty")
+		}
+		if len(result.Errors) > errorCount {
+			return
+		}
+
+		student, err := s.linkedStudent(ctx, studentCode, run)
+		if err != nil {
+			rowError("student_code", studentCode, err.Error())
+			return
+		}
+
+		if field, value, err := s.linkUniversity(ctx, run.resolver, student, university); err != nil {
+			result.Errors = append(result.Errors, models.ImportRowError{
+				Sheet: sheet.Name, Row: rowNum, Field: field, Value: value, Message: err.Error(),
+				Suggestions: catalogSuggestions(err),
+			})
+			return
+		}
+		result.UniversitiesLinked++
+	})
+}
+
+// importEnrollmentSheet enrolls students in the courses scheduled for a period.
+func (s *studentImportService) importEnrollmentSheet(ctx context.Context, f importFile, sheet importSheet, run *importRun, result *models.ImportResult) error {
+	headerMap := headerIndex(sheet.Header)
+
+	return eachRow(f, sheet, result, func(rowNum int, row []string) {
+		studentCode := strings.TrimSpace(getField(row, headerMap, "student_code"))
+		courseCode := strings.TrimSpace(getField(row, headerMap, "course_code"))
+		period := strings.TrimSpace(getField(row, headerMap, "period"))
+		statusRaw := strings.TrimSpace(getField(row, headerMap, "status"))
+		gradeRaw := strings.TrimSpace(getField(row, headerMap, "final_grade"))
+
+		var rowErrors []models.ImportRowError
+		addError := func(field, value, message string) {
+			rowErrors = append(rowErrors, models.ImportRowError{
+				Sheet: sheet.Name, Row: rowNum, Field: field, Value: value, Message: message,
+			})
+		}
+
+		if studentCode == "" {
+			addError("student_code", "", "required field is empty")
+		}
+		if courseCode == "" {
+			addError("course_code", "", "required field is empty")
+		}
+		if period == "" {
+			addError("period", "", "required field is empty")
+		}
+
+		status := models.EnrollmentStatusEnrolled
+		if statusRaw != "" {
+			lower := strings.ToLower(statusRaw)
+			if mapped, ok := enrollmentStatusMap[lower]; ok {
+				lower = mapped
+			}
+			switch models.EnrollmentStatus(lower) {
+			case models.EnrollmentStatusEnrolled, models.EnrollmentStatusCompleted,
+				models.EnrollmentStatusWithdrawn, models.EnrollmentStatusFailed:
+				status = models.EnrollmentStatus(lower)
+			default:
+				addError("status", statusRaw, "must be enrolled, completed, withdrawn or failed")
+			}
+		}
+
+		var finalGrade *float64
+		if gradeRaw != "" {
+			grade, err := strconv.ParseFloat(strings.Replace(gradeRaw, ",", ".", 1), 64)
+			if err != nil || grade < 0 || grade > 5 {
+				addError("final_grade", gradeRaw, "must be a number between 0 and 5")
+			} else {
+				finalGrade = &grade
+			}
+		}
+
+		if len(rowErrors) > 0 {
+			result.Errors = append(result.Errors, rowErrors...)
+			return
+		}
+
+		student, err := s.linkedStudent(ctx, studentCode, run)
+		if err != nil {
+			addError("student_code", studentCode, err.Error())
+			result.Errors = append(result.Errors, rowErrors...)
+			return
+		}
+
+		scheduledCourseID, err := s.enrollmentRepo.FindScheduledCourse(ctx, courseCode, period)
+		if err == nil && scheduledCourseID == uuid.Nil {
+			err = fmt.Errorf("course %s is not scheduled in period %s", courseCode, period)
+		}
+		if err != nil {
+			addError("course_code", courseCode, err.Error())
+			result.Errors = append(result.Errors, rowErrors...)
+			return
+		}
+
+		enrollment := &models.Enrollment{
+			ID:                uuid.New(),
+			StudentID:         student.ID,
+			ScheduledCourseID: scheduledCourseID,
+			Status:            status,
+			FinalGrade:        finalGrade,
+			CreatedBy:         run.createdBy,
+		}
+		created, err := s.enrollmentRepo.Create(ctx, enrollment)
+		if err == nil && !created {
+			err = fmt.Errorf("duplicate: student is already enrolled in %s for %s", courseCode, period)
+		}
+		if err != nil {
+			addError("_row", "", err.Error())
+			result.Errors = append(result.Errors, rowErrors...)
+			return
+		}
+		result.EnrollmentsCreated++
+	})
+}
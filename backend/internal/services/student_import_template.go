@@ -0,0 +1,184 @@
+package services
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// importColumn describes a column accepted by the student importer.
+type importColumn struct {
+	Name        string
+	Required    bool
+	Description string
+}
+
+// importColumns lists every header the importer understands, in template order.
+var importColumns = []importColumn{
+	{"first_names", true, "Nombres del estudiante"},
+	{"last_names", true, "Apellidos del estudiante"},
+	{"document_id", false, "Documento de identidad (único)"},
+	{"birth_date", false, "Fecha de nacimiento (YYYY-MM-DD o DD/MM/YYYY, o celda de fecha). Mínimo 18 años"},
+	{"gender", false, "Género: M o F"},
+	{"email", false, "Correo electrónico (único). Varios correos se separan con ;"},
+	{"email_2", false, "Correo adicional. Se pueden agregar más columnas email_3, email_4, ..."},
+	{"phone", false, "Teléfono de contacto. Varios teléfonos se separan con ;"},
+	{"phone_2", false, "Teléfono adicional. Se pueden agregar más columnas phone_3, phone_4, ..."},
+	{"nationality_country_id", true, "País de nacionalidad (nombre o UUID)"},
+	{"residence_country_id", false, "País de residencia (nombre o UUID). Si se omite se usa la nacionalidad"},
+	{"residence_city_id", false, "Ciudad de residencia (nombre o UUID)"},
+	{"company_id", false, "Empresa (nombre o UUID)"},
+	{"job_title_category_id", false, "Categoría de cargo (nombre o UUID)"},
+	{"profession_id", false, "Profesión de pregrado (nombre o UUID)"},
+	{"student_code", false, "Código de estudiante de 9 dígitos (ej: 202620190)"},
+	{"status", true, "Estado: active, graduated, withdrawn, suspended (o activo, graduado, retirado, suspendido)"},
+	{"cohort", true, "Cohorte de ingreso (ej: 2024-1)"},
+	{"enrollment_date", true, "Fecha de ingreso (YYYY-MM-DD o DD/MM/YYYY, o celda de fecha)"},
+	{"graduation_date", false, "Fecha de grado. Obligatoria si el estado es graduado y solo se admite en ese caso"},
+	{"universidad", false, "Universidad de pregrado"},
+	{"universidad-ciudad", false, "Ciudad de la universidad"},
+	{"universidad-pais", false, "País de la universidad. Si se omite se usa la nacionalidad"},
+	{"universidad-titulo", false, "Título obtenido (ej: Ingeniería de Sistemas)"},
+	{"universidad-anio", false, "Año de graduación (desde 1950 hasta el año actual)"},
+	{"universidad_2", false, "Universidad adicional. Se pueden agregar universidad-ciudad_2, universidad-pais_2, universidad-titulo_2, universidad-anio_2 y más grupos _3, _4, ..."},
+}
+
+// templateExamples are sample rows keyed by column name.
+var templateExamples = []map[string]string{
+	{
+		"first_names": "María José", "last_names": "Rodríguez Pérez", "document_id": "1020304050",
+		"birth_date": "1992-05-20", "gender": "F", "email": "maria.rodriguez@example.com", "email_2": "mjrodriguez@empresa.com", "phone": "+57 300 1234567",
+		"nationality_country_id": "Colombia", "residence_country_id": "Colombia", "residence_city_id": "Bogotá",
+		"company_id": "Bancolombia", "job_title_category_id": "Analista", "profession_id": "Ingeniería de Sistemas", "student_code": "202610001",
+		"status": "activo", "cohort": "2026-1", "enrollment_date": "2026-01-20",
+		"universidad": "Universidad Nacional de Colombia", "universidad-ciudad": "Bogotá", "universidad-pais": "Colombia",
+		"universidad-titulo": "Ingeniería de Sistemas", "universidad-anio": "2015", "universidad_2": "Universidad de los Andes",
+	},
+	{
+		"first_names": "Carlos", "last_names": "Gómez", "gender": "M", "email": "carlos.gomez@example.com; cgomez@example.org",
+		"nationality_country_id": "México", "residence_country_id": "Colombia", "residence_city_id": "Medellín",
+		"profession_id": "Economía", "status": "active", "cohort": "2026-1", "enrollment_date": "2026-01-20",
+	},
+}
+
+const (
+	templateDataSheet         = "Estudiantes"
+	templateInstructionsSheet = "Instrucciones"
+	templateValidationRows    = 1000
+)
+
+func requiredImportColumns() []string {
+	var required []string
+	for _, col := range importColumns {
+		if col.Required {
+			required = append(required, col.Name)
+		}
+	}
+	return required
+}
+
+// statusOptions returns the accepted status values, English first, then Spanish.
+func statusOptions() []string {
+	options := []string{"active", "graduated", "withdrawn", "suspended"}
+	spanish := make([]string, 0, len(statusMap))
+	for es := range statusMap {
+		spanish = append(spanish, es)
+	}
+	sort.Strings(spanish)
+	return append(options, spanish...)
+}
+
+// BuildTemplate returns an import template with every supported header and example rows.
+// The xlsx variant also includes an instructions sheet and dropdowns for status and gender.
+func (s *studentImportService) BuildTemplate(format string) ([]byte, error) {
+	header := make([]string, len(importColumns))
+	for i, col := range importColumns {
+		header[i] = col.Name
+	}
+
+	rows := [][]string{header}
+	for _, example := range templateExamples {
+		row := make([]string, len(importColumns))
+		for i, col := range importColumns {
+			row[i] = example[col.Name]
+		}
+		rows = append(rows, row)
+	}
+
+	switch format {
+	case "csv":
+		return writeCSV(rows)
+	case "xlsx":
+		return buildXLSXTemplate(rows)
+	default:
+		return nil, fmt.Errorf("unsupported format: %s, expected csv or xlsx", format)
+	}
+}
+
+func buildXLSXTemplate(rows [][]string) ([]byte, error) {
+	f := excelize.NewFile()
+	defer f.Close()
+
+	if err := f.SetSheetName(f.GetSheetName(0), templateDataSheet); err != nil {
+		return nil, err
+	}
+	if err := setSheetRows(f, templateDataSheet, rows); err != nil {
+		return nil, err
+	}
+
+	// Dropdowns for the enumerated columns
+	dropdowns := map[string][]string{
+		"status": statusOptions(),
+		"gender": {"M", "F"},
+	}
+	for i, col := range importColumns {
+		options, ok := dropdowns[col.Name]
+		if !ok {
+			continue
+		}
+		colName, err := excelize.ColumnNumberToName(i + 1)
+		if err != nil {
+			return nil, err
+		}
+		dv := excelize.NewDataValidation(true)
+		dv.Sqref = fmt.Sprintf("%s2:%s%d", colName, colName, templateValidationRows)
+		if err := dv.SetDropList(options); err != nil {
+			return nil, fmt.Errorf("failed to build %s dropdown: %w", col.Name, err)
+		}
+		if err := f.AddDataValidation(templateDataSheet, dv); err != nil {
+			return nil, fmt.Errorf("failed to add %s dropdown: %w", col.Name, err)
+		}
+	}
+
+	if _, err := f.NewSheet(templateInstructionsSheet); err != nil {
+		return nil, err
+	}
+	instructions := [][]string{
+		{"Columna", "Obligatoria", "Descripción"},
+	}
+	for _, col := range importColumns {
+		required := "No"
+		if col.Required {
+			required = "Sí"
+		}
+		instructions = append(instructions, []string{col.Name, required, col.Description})
+	}
+	instructions = append(instructions,
+		[]string{},
+		[]string{"Notas"},
+		[]string{"Los catálogos (países, ciudades, empresas, profesiones, cargos, universidades) se pueden indicar por nombre; si no existen se crean automáticamente."},
+		[]string{"Elimine las filas de ejemplo antes de importar el archivo."},
+		[]string{"Opcional: una hoja con columnas student_code, universidad, universidad-ciudad, universidad-pais, universidad-titulo, universidad-anio vincula universidades adicionales."},
+		[]string{"Opcional: una hoja con columnas student_code, course_code, period, status, final_grade registra inscripciones a cursos programados."},
+	)
+	if err := setSheetRows(f, templateInstructionsSheet, instructions); err != nil {
+		return nil, err
+	}
+
+	buf, err := f.WriteToBuffer()
+	if err != nil {
+		return nil, fmt.Errorf("failed to write xlsx: %w", err)
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,133 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/google/uuid"
+
+	"github.com/dcorreal/coordinador/internal/models"
+)
+
+// rowUniversity holds one group of university columns of a row. A student row may
+// carry several groups: universidad, universidad-ciudad, ... and the same columns
+// with a numbered suffix (universidad_2, universidad-ciudad_2, ...).
+type rowUniversity struct {
+	suffix  string
+	name    string
+	city    string
+	country string
+	degree  string
+	year    *int
+}
+
+// field returns the column name of a field in this group.
+func (u rowUniversity) field(base string) string {
+	return base + u.suffix
+}
+
+// universitySuffixes returns the suffixes of the university groups present in the
+// header: "" for the base columns, then "_2", "_3", ... in numeric order.
+func universitySuffixes(headerMap map[string]int) []string {
+	var numbers []int
+	for header := range headerMap {
+		suffix, ok := strings.CutPrefix(header, "universidad_")
+		if !ok {
+			continue
+		}
+		if n, err := strconv.Atoi(suffix); err == nil && n > 1 {
+			numbers = append(numbers, n)
+		}
+	}
+	sort.Ints(numbers)
+
+	suffixes := []string{""}
+	for _, n := range numbers {
+		suffixes = append(suffixes, fmt.Sprintf("_%d", n))
+	}
+	return suffixes
+}
+
+// readRowUniversity reads and validates the university group with the given suffix.
+// It returns false when the group is empty.
+func readRowUniversity(row []string, headerMap map[string]int, suffix string, addError func(field, value, message string)) (rowUniversity, bool) {
+	u := rowUniversity{suffix: suffix}
+	u.name = strings.TrimSpace(getField(row, headerMap, u.field("universidad")))
+	u.city = strings.TrimSpace(getField(row, headerMap, u.field("universidad-ciudad")))
+	u.country = strings.TrimSpace(getField(row, headerMap, u.field("universidad-pais")))
+	u.degree = strings.TrimSpace(getField(row, headerMap, u.field("universidad-titulo")))
+	yearRaw := strings.TrimSpace(getField(row, headerMap, u.field("universidad-anio")))
+
+	if u.name == "" && u.city == "" && u.country == "" && u.degree == "" && yearRaw == "" {
+		return u, false
+	}
+	if u.name == "" {
+		addError(u.field("universidad"), "", "required field is empty")
+	}
+	if len(u.degree) > 255 {
+		addError(u.field("universidad-titulo"), u.degree, "must be at most 255 characters")
+	}
+	if yearRaw != "" {
+		year, err := strconv.Atoi(yearRaw)
+		if err != nil {
+			addError(u.field("universidad-anio"), yearRaw, "must be a year")
+		} else if err := validateGraduationYear(year); err != nil {
+			addError(u.field("universidad-anio"), yearRaw, err.Error())
+		} else {
+			u.year = &year
+		}
+	}
+	return u, true
+}
+
+// linkUniversity resolves the university of a group with resolver and links the
+// student to it.
+// The university country defaults to the student's nationality. On failure it
+// returns the column that caused it.
+func (s *studentImportService) linkUniversity(ctx context.Context, resolver *CatalogResolver, student studentRef, u rowUniversity) (string, string, error) {
+	countryID := student.NationalityCountryID
+	if u.country != "" {
+		resolved, err := resolver.ResolveCountry(ctx, u.country)
+		if err != nil {
+			return u.field("universidad-pais"), u.country, err
+		}
+		if resolved != uuid.Nil {
+			countryID = resolved
+		}
+	}
+
+	var cityID *uuid.UUID
+	if u.city != "" {
+		resolved, err := resolver.ResolveCity(ctx, u.city, countryID)
+		if err != nil {
+			return u.field("universidad-ciudad"), u.city, err
+		}
+		if resolved != uuid.Nil {
+			cityID = &resolved
+		}
+	}
+
+	universityID, err := resolver.ResolveUniversity(ctx, u.name, cityID, countryID)
+	if err == nil && universityID == uuid.Nil {
+		err = fmt.Errorf("university could not be resolved")
+	}
+	if err != nil {
+		return u.field("universidad"), u.name, err
+	}
+
+	link := &models.StudentUniversity{
+		StudentID:      student.ID,
+		UniversityID:   universityID,
+		GraduationYear: u.year,
+	}
+	if u.degree != "" {
+		link.DegreeObtained = &u.degree
+	}
+	if err := s.catalogRepo.CreateStudentUniversity(ctx, link); err != nil {
+		return "_row", "", err
+	}
+	return "", "", nil
+}
@@ -4,41 +4,98 @@ import (
 	"context"
 	"fmt"
 	"regexp"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
 
 	"github.com/dcorreal/coordinador/internal/models"
 	"github.com/dcorreal/coordinador/internal/repositories"
+	"github.com/dcorreal/coordinador/internal/shared"
 )
 
 // StudentService defines the business logic interface for students.
 type StudentService interface {
 	CreateStudent(ctx context.Context, req *models.CreateStudentRequest, createdBy *uuid.UUID) (*models.Student, error)
-	GetStudent(ctx context.Context, id uuid.UUID) (*models.Student, error)
-	ListStudents(ctx context.Context, filters repositories.StudentFilters) ([]*models.Student, int, error)
+	NewStudent(req *models.CreateStudentRequest, createdBy *uuid.UUID) (*models.Student, error)
+	GetStudent(ctx context.Context, id uuid.UUID, expand StudentExpand) (*models.Student, error)
+	ListStudents(ctx context.Context, filters repositories.StudentFilters, withTotal bool) (*StudentPage, error)
 	UpdateStudent(ctx context.Context, id uuid.UUID, req *models.UpdateStudentRequest, updatedBy *uuid.UUID) (*models.Student, error)
 	DeleteStudent(ctx context.Context, id uuid.UUID, deletedBy *uuid.UUID) error
+	ChangeStudentStatus(ctx context.Context, id uuid.UUID, req *models.ChangeStudentStatusRequest, changedBy *uuid.UUID) (*models.Student, error)
+	ListStatusHistory(ctx context.Context, id uuid.UUID) ([]models.StudentStatusChange, error)
 }
 
-var studentCodeRegex = regexp.MustCompile(`^[0-9]{9}$`)
+// StudentPage is one page of the student listing.
+type StudentPage struct {
+	Students []*models.Student
+	// NextCursor continues the listing after this page; empty on the last page
+	NextCursor string
+	// Total is only counted when requested
+	Total *int
+}
+
+// StudentExpand selects the related data embedded in a student. Universities
+// are always included.
+type StudentExpand struct {
+	Catalogs    bool // names of the referenced countries, city, company, profession and job title
+	Enrollments bool // enrollment summary
+}
+
+// ParseStudentExpand parses a comma-separated expand parameter, e.g.
+// "catalogs,enrollments". "all" expands everything.
+func ParseStudentExpand(s string) (StudentExpand, error) {
+	var expand StudentExpand
+	for _, name := range strings.Split(s, ",") {
+		switch strings.ToLower(strings.TrimSpace(name)) {
+		case "":
+		case "catalogs":
+			expand.Catalogs = true
+		case "enrollments":
+			expand.Enrollments = true
+		case "all":
+			expand = StudentExpand{Catalogs: true, Enrollments: true}
+		default:
+			return StudentExpand{}, fmt.Errorf("invalid expand %q, must be catalogs, enrollments or all", name)
+		}
+	}
+	return expand, nil
+}
+
+// studentCodeRegex matches YYYYS####, where S is the semester (1 or 2).
+var studentCodeRegex = regexp.MustCompile(`^[0-9]{4}[12][0-9]{4}$`)
 
 type studentService struct {
 	studentRepo repositories.StudentRepository
+	dates       *shared.DateParser
 }
 
-// NewStudentService creates a new StudentService.
-func NewStudentService(studentRepo repositories.StudentRepository) StudentService {
-	return &studentService{studentRepo: studentRepo}
+// NewStudentService creates a new StudentService. Dates in requests are parsed with dates.
+func NewStudentService(studentRepo repositories.StudentRepository, dates *shared.DateParser) StudentService {
+	return &studentService{studentRepo: studentRepo, dates: dates}
 }
 
 func (s *studentService) CreateStudent(ctx context.Context, req *models.CreateStudentRequest, createdBy *uuid.UUID) (*models.Student, error) {
+	student, err := s.NewStudent(req, createdBy)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.studentRepo.Create(ctx, student); err != nil {
+		return nil, fmt.Errorf("failed to create student: %w", err)
+	}
+
+	return student, nil
+}
+
+// NewStudent validates the request and builds the student without saving it.
+func (s *studentService) NewStudent(req *models.CreateStudentRequest, createdBy *uuid.UUID) (*models.Student, error) {
 	// Parse and validate birth date (optional)
 	var birthDate *time.Time
 	if req.BirthDate != "" {
-		parsed, err := time.Parse("2006-01-02", req.BirthDate)
+		parsed, err := s.dates.Parse(req.BirthDate)
 		if err != nil {
-			return nil, fmt.Errorf("invalid birth_date format, expected YYYY-MM-DD: %w", err)
+			return nil, fmt.Errorf("invalid birth_date: %w", err)
 		}
 		// Validate minimum age (18 years)
 		age := time.Now().Year() - parsed.Year()
@@ -52,9 +109,31 @@ func (s *studentService) CreateStudent(ctx context.Context, req *models.CreateSt
 	}
 
 	// Parse enrollment date
-	enrollmentDate, err := time.Parse("2006-01-02", req.EnrollmentDate)
+	enrollmentDate, err := s.dates.Parse(req.EnrollmentDate)
 	if err != nil {
-		return nil, fmt.Errorf("invalid enrollment_date format, expected YYYY-MM-DD: %w", err)
+		return nil, fmt.Errorf("invalid enrollment_date: %w", err)
+	}
+
+	// Graduated students need their graduation date, which status changes
+	// later use as the date of the last change
+	var graduationDate *time.Time
+	if req.GraduationDate != "" {
+		if models.StudentStatus(req.Status) != models.StudentStatusGraduated {
+			return nil, fmt.Errorf("graduation_date is only allowed for graduated students")
+		}
+		parsed, err := s.dates.Parse(req.GraduationDate)
+		if err != nil {
+			return nil, fmt.Errorf("invalid graduation_date: %w", err)
+		}
+		if parsed.Before(enrollmentDate) {
+			return nil, fmt.Errorf("graduation_date can't be before the enrollment date %s", enrollmentDate.Format(isoDateLayout))
+		}
+		if parsed.After(shared.Today()) {
+			return nil, fmt.Errorf("graduation_date can't be in the future")
+		}
+		graduationDate = &parsed
+	} else if models.StudentStatus(req.Status) == models.StudentStatusGraduated {
+		return nil, fmt.Errorf("graduation_date is required for graduated students")
 	}
 
 	// Parse nationality country ID (required)
@@ -113,6 +192,11 @@ func (s *studentService) CreateStudent(ctx context.Context, req *models.CreateSt
 		}
 	}
 
+	universities, err := parseStudentUniversities(req.Universities)
+	if err != nil {
+		return nil, err
+	}
+
 	student := &models.Student{
 		ID:                   uuid.New(),
 		FirstNames:           req.FirstNames,
@@ -133,32 +217,70 @@ func (s *studentService) CreateStudent(ctx context.Context, req *models.CreateSt
 		Status:               models.StudentStatus(req.Status),
 		Cohort:               req.Cohort,
 		EnrollmentDate:       enrollmentDate,
+		GraduationDate:       graduationDate,
+		Universities:         universities,
 		CreatedBy:            createdBy,
 	}
-
-	if err := s.studentRepo.Create(ctx, student); err != nil {
-		return nil, fmt.Errorf("failed to create student: %w", err)
+	for i := range student.Universities {
+		student.Universities[i].StudentID = student.ID
 	}
 
 	return student, nil
 }
 
-func (s *studentService) GetStudent(ctx context.Context, id uuid.UUID) (*models.Student, error) {
-	return s.studentRepo.GetByID(ctx, id)
-}
+func (s *studentService) GetStudent(ctx context.Context, id uuid.UUID, expand StudentExpand) (*models.Student, error) {
+	student, err := s.studentRepo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
 
-func (s *studentService) ListStudents(ctx context.Context, filters repositories.StudentFilters) ([]*models.Student, int, error) {
-	students, err := s.studentRepo.List(ctx, filters)
+	student.Universities, err = s.studentRepo.ListUniversities(ctx, id)
 	if err != nil {
-		return nil, 0, err
+		return nil, err
+	}
+
+	if expand.Catalogs {
+		if student.Catalogs, err = s.studentRepo.GetCatalogs(ctx, id); err != nil {
+			return nil, err
+		}
 	}
 
-	count, err := s.studentRepo.Count(ctx, filters)
+	if expand.Enrollments {
+		if student.EnrollmentSummary, err = s.studentRepo.GetEnrollmentSummary(ctx, id); err != nil {
+			return nil, err
+		}
+	}
+
+	return student, nil
+}
+
+// ListStudents returns a page of students. One extra row is read to know
+// whether another page follows.
+func (s *studentService) ListStudents(ctx context.Context, filters repositories.StudentFilters, withTotal bool) (*StudentPage, error) {
+	query := filters
+	if query.Limit > 0 {
+		query.Limit++
+	}
+	students, err := s.studentRepo.List(ctx, query)
 	if err != nil {
-		return nil, 0, err
+		return nil, err
+	}
+
+	page := &StudentPage{Students: students}
+	if filters.Limit > 0 && len(students) > filters.Limit {
+		page.Students = students[:filters.Limit]
+		page.NextCursor = repositories.NewStudentCursor(page.Students[filters.Limit-1], filters.Sort).Encode()
+	}
+
+	if withTotal {
+		count, err := s.studentRepo.Count(ctx, filters)
+		if err != nil {
+			return nil, err
+		}
+		page.Total = &count
 	}
 
-	return students, count, nil
+	return page, nil
 }
 
 func (s *studentService) UpdateStudent(ctx context.Context, id uuid.UUID, req *models.UpdateStudentRequest, updatedBy *uuid.UUID) (*models.Student, error) {
@@ -219,8 +341,17 @@ func (s *studentService) UpdateStudent(ctx context.Context, id uuid.UUID, req *m
 		}
 		student.StudentCode = req.StudentCode
 	}
-	if req.Status != nil {
-		student.Status = models.StudentStatus(*req.Status)
+	// Status changes follow the transition rules and are recorded by ChangeStudentStatus
+	if req.Status != nil && models.StudentStatus(*req.Status) != student.Status {
+		return nil, fmt.Errorf("status can't be changed here, use the status change endpoint to go from %s to %s", student.Status, *req.Status)
+	}
+
+	// Universities are replaced only when the request lists them
+	if req.Universities != nil {
+		student.Universities, err = parseStudentUniversities(req.Universities)
+		if err != nil {
+			return nil, err
+		}
 	}
 
 	student.UpdatedBy = updatedBy
@@ -232,6 +363,46 @@ func (s *studentService) UpdateStudent(ctx context.Context, id uuid.UUID, req *m
 	return student, nil
 }
 
+// parseStudentUniversities validates the universities of a create or update request.
+func parseStudentUniversities(reqs []models.StudentUniversityRequest) ([]models.StudentUniversity, error) {
+	universities := make([]models.StudentUniversity, 0, len(reqs))
+	seen := make(map[uuid.UUID]bool, len(reqs))
+	for _, req := range reqs {
+		universityID, err := uuid.Parse(req.UniversityID)
+		if err != nil {
+			return nil, fmt.Errorf("invalid university_id: %w", err)
+		}
+		if seen[universityID] {
+			return nil, fmt.Errorf("duplicate university_id: %s", universityID)
+		}
+		seen[universityID] = true
+
+		if req.GraduationYear != nil {
+			if err := validateGraduationYear(*req.GraduationYear); err != nil {
+				return nil, err
+			}
+		}
+		if req.DegreeObtained != nil && len(*req.DegreeObtained) > 255 {
+			return nil, fmt.Errorf("degree_obtained must be at most 255 characters")
+		}
+
+		universities = append(universities, models.StudentUniversity{
+			UniversityID:   universityID,
+			DegreeObtained: req.DegreeObtained,
+			GraduationYear: req.GraduationYear,
+		})
+	}
+	return universities, nil
+}
+
+// validateGraduationYear mirrors the chk_graduation_year constraint of student_universities.
+func validateGraduationYear(year int) error {
+	if year < 1950 || year > time.Now().Year() {
+		return fmt.Errorf("graduation_year must be between 1950 and %d", time.Now().Year())
+	}
+	return nil
+}
+
 func (s *studentService) DeleteStudent(ctx context.Context, id uuid.UUID, deletedBy *uuid.UUID) error {
 	return s.studentRepo.Delete(ctx, id, deletedBy)
 }
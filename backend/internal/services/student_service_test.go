@@ -9,11 +9,13 @@ import (
 	"github.com/google/uuid"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
 
 	"github.com/dcorreal/coordinador/internal/models"
 	"github.com/dcorreal/coordinador/internal/repositories"
 	"github.com/dcorreal/coordinador/internal/repositories/mocks"
 	"github.com/dcorreal/coordinador/internal/services"
+	"github.com/dcorreal/coordinador/internal/shared"
 )
 
 func validCreateRequest() *models.CreateStudentRequest {
@@ -31,11 +33,12 @@ func validCreateRequest() *models.CreateStudentRequest {
 }
 
 func sampleStudent() *models.Student {
+	birthDate := time.Date(1995, 3, 15, 0, 0, 0, 0, time.UTC)
 	return &models.Student{
 		ID:                   uuid.New(),
 		FirstNames:           "Juan Carlos",
 		LastNames:            "Perez",
-		BirthDate:            time.Date(1995, 3, 15, 0, 0, 0, 0, time.UTC),
+		BirthDate:            &birthDate,
 		NationalityCountryID: uuid.New(),
 		ResidenceCountryID:   uuid.New(),
 		Emails:               []string{"juan@test.com"},
@@ -53,7 +56,7 @@ func sampleStudent() *models.Student {
 
 func TestCreateStudent_Success(t *testing.T) {
 	mockRepo := new(mocks.StudentRepository)
-	service := services.NewStudentService(mockRepo)
+	service := services.NewStudentService(mockRepo, shared.DefaultDateParser())
 
 	req := validCreateRequest()
 	mockRepo.On("Create", mock.Anything, mock.Anything).Return(nil)
@@ -72,7 +75,7 @@ func TestCreateStudent_Success(t *testing.T) {
 
 func TestCreateStudent_InvalidBirthDateFormat(t *testing.T) {
 	mockRepo := new(mocks.StudentRepository)
-	service := services.NewStudentService(mockRepo)
+	service := services.NewStudentService(mockRepo, shared.DefaultDateParser())
 
 	req := validCreateRequest()
 	req.BirthDate = "15-03-1995" // wrong format
@@ -84,9 +87,42 @@ func TestCreateStudent_InvalidBirthDateFormat(t *testing.T) {
 	assert.Contains(t, err.Error(), "birth_date")
 }
 
+func TestCreateStudent_DayMonthYearDates(t *testing.T) {
+	mockRepo := new(mocks.StudentRepository)
+	service := services.NewStudentService(mockRepo, shared.DefaultDateParser())
+
+	req := validCreateRequest()
+	req.BirthDate = "15/03/1995"
+	req.EnrollmentDate = "5/1/2024"
+	mockRepo.On("Create", mock.Anything, mock.Anything).Return(nil)
+
+	student, err := service.CreateStudent(context.Background(), req, nil)
+
+	assert.NoError(t, err)
+	assert.Equal(t, time.Date(1995, 3, 15, 0, 0, 0, 0, time.UTC), *student.BirthDate)
+	assert.Equal(t, time.Date(2024, 1, 5, 0, 0, 0, 0, time.UTC), student.EnrollmentDate)
+}
+
+func TestCreateStudent_ConfiguredDateFormats(t *testing.T) {
+	mockRepo := new(mocks.StudentRepository)
+	dates, err := shared.NewDateParser("MM/DD/YYYY")
+	assert.NoError(t, err)
+	service := services.NewStudentService(mockRepo, dates)
+
+	req := validCreateRequest()
+	req.BirthDate = "15/03/1995" // day first is no longer accepted
+
+	student, err := service.CreateStudent(context.Background(), req, nil)
+
+	assert.Error(t, err)
+	assert.Nil(t, student)
+	assert.Contains(t, err.Error(), "invalid birth_date")
+	assert.Contains(t, err.Error(), "MM/DD/YYYY or YYYY-MM-DD")
+}
+
 func TestCreateStudent_UnderAge(t *testing.T) {
 	mockRepo := new(mocks.StudentRepository)
-	service := services.NewStudentService(mockRepo)
+	service := services.NewStudentService(mockRepo, shared.DefaultDateParser())
 
 	req := validCreateRequest()
 	req.BirthDate = time.Now().AddDate(-17, 0, 0).Format("2006-01-02") // 17 years old
@@ -100,7 +136,7 @@ func TestCreateStudent_UnderAge(t *testing.T) {
 
 func TestCreateStudent_InvalidEnrollmentDateFormat(t *testing.T) {
 	mockRepo := new(mocks.StudentRepository)
-	service := services.NewStudentService(mockRepo)
+	service := services.NewStudentService(mockRepo, shared.DefaultDateParser())
 
 	req := validCreateRequest()
 	req.EnrollmentDate = "not-a-date"
@@ -112,9 +148,56 @@ func TestCreateStudent_InvalidEnrollmentDateFormat(t *testing.T) {
 	assert.Contains(t, err.Error(), "enrollment_date")
 }
 
+func TestCreateStudent_GraduatedSetsGraduationDate(t *testing.T) {
+	mockRepo := new(mocks.StudentRepository)
+	service := services.NewStudentService(mockRepo, shared.DefaultDateParser())
+
+	req := validCreateRequest()
+	req.Status = "graduated"
+	req.GraduationDate = "2025-12-05"
+	mockRepo.On("Create", mock.Anything, mock.Anything).Return(nil)
+
+	student, err := service.CreateStudent(context.Background(), req, nil)
+
+	require.NoError(t, err)
+	require.NotNil(t, student.GraduationDate)
+	assert.Equal(t, time.Date(2025, 12, 5, 0, 0, 0, 0, time.UTC), *student.GraduationDate)
+}
+
+func TestCreateStudent_GraduationDateValidation(t *testing.T) {
+	tests := []struct {
+		name           string
+		status         string
+		graduationDate string
+		wantErr        string
+	}{
+		{"graduated without date", "graduated", "", "graduation_date is required"},
+		{"date on active student", "active", "2025-12-05", "only allowed for graduated students"},
+		{"before enrollment", "graduated", "2023-12-31", "before the enrollment date 2024-01-15"},
+		{"future date", "graduated", time.Now().AddDate(0, 0, 2).Format("2006-01-02"), "in the future"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := new(mocks.StudentRepository)
+			service := services.NewStudentService(mockRepo, shared.DefaultDateParser())
+
+			req := validCreateRequest()
+			req.Status = tt.status
+			req.GraduationDate = tt.graduationDate
+
+			student, err := service.CreateStudent(context.Background(), req, nil)
+
+			assert.Nil(t, student)
+			assert.ErrorContains(t, err, tt.wantErr)
+			mockRepo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+		})
+	}
+}
+
 func TestCreateStudent_InvalidNationalityCountryID(t *testing.T) {
 	mockRepo := new(mocks.StudentRepository)
-	service := services.NewStudentService(mockRepo)
+	service := services.NewStudentService(mockRepo, shared.DefaultDateParser())
 
 	req := validCreateRequest()
 	req.NationalityCountryID = "not-a-uuid"
@@ -128,7 +211,7 @@ func TestCreateStudent_InvalidNationalityCountryID(t *testing.T) {
 
 func TestCreateStudent_InvalidResidenceCountryID(t *testing.T) {
 	mockRepo := new(mocks.StudentRepository)
-	service := services.NewStudentService(mockRepo)
+	service := services.NewStudentService(mockRepo, shared.DefaultDateParser())
 
 	req := validCreateRequest()
 	req.ResidenceCountryID = "not-a-uuid"
@@ -142,7 +225,7 @@ func TestCreateStudent_InvalidResidenceCountryID(t *testing.T) {
 
 func TestCreateStudent_InvalidResidenceCityID(t *testing.T) {
 	mockRepo := new(mocks.StudentRepository)
-	service := services.NewStudentService(mockRepo)
+	service := services.NewStudentService(mockRepo, shared.DefaultDateParser())
 
 	req := validCreateRequest()
 	badID := "not-a-uuid"
@@ -157,7 +240,7 @@ func TestCreateStudent_InvalidResidenceCityID(t *testing.T) {
 
 func TestCreateStudent_InvalidCompanyID(t *testing.T) {
 	mockRepo := new(mocks.StudentRepository)
-	service := services.NewStudentService(mockRepo)
+	service := services.NewStudentService(mockRepo, shared.DefaultDateParser())
 
 	req := validCreateRequest()
 	badID := "not-a-uuid"
@@ -172,7 +255,7 @@ func TestCreateStudent_InvalidCompanyID(t *testing.T) {
 
 func TestCreateStudent_WithStudentCode(t *testing.T) {
 	mockRepo := new(mocks.StudentRepository)
-	service := services.NewStudentService(mockRepo)
+	service := services.NewStudentService(mockRepo, shared.DefaultDateParser())
 
 	req := validCreateRequest()
 	code := "202620190"
@@ -189,7 +272,7 @@ func TestCreateStudent_WithStudentCode(t *testing.T) {
 
 func TestCreateStudent_InvalidStudentCodeFormat(t *testing.T) {
 	mockRepo := new(mocks.StudentRepository)
-	service := services.NewStudentService(mockRepo)
+	service := services.NewStudentService(mockRepo, shared.DefaultDateParser())
 
 	req := validCreateRequest()
 	badCode := "ABC123456"
@@ -202,9 +285,24 @@ func TestCreateStudent_InvalidStudentCodeFormat(t *testing.T) {
 	assert.Contains(t, err.Error(), "student_code")
 }
 
+func TestCreateStudent_InvalidStudentCodeLength(t *testing.T) {
+	mockRepo := new(mocks.StudentRepository)
+	service := services.NewStudentService(mockRepo, shared.DefaultDateParser())
+
+	req := validCreateRequest()
+	badCode := "20263019" // 8 digits, must be exactly 9
+	req.StudentCode = &badCode
+
+	student, err := service.CreateStudent(context.Background(), req, nil)
+
+	assert.Error(t, err)
+	assert.Nil(t, student)
+	assert.Contains(t, err.Error(), "student_code")
+}
+
 func TestCreateStudent_InvalidStudentCodeSemester(t *testing.T) {
 	mockRepo := new(mocks.StudentRepository)
-	service := services.NewStudentService(mockRepo)
+	service := services.NewStudentService(mockRepo, shared.DefaultDateParser())
 
 	req := validCreateRequest()
 	badCode := "202630190" // semester 3 is invalid
@@ -219,7 +317,7 @@ func TestCreateStudent_InvalidStudentCodeSemester(t *testing.T) {
 
 func TestCreateStudent_RepositoryError(t *testing.T) {
 	mockRepo := new(mocks.StudentRepository)
-	service := services.NewStudentService(mockRepo)
+	service := services.NewStudentService(mockRepo, shared.DefaultDateParser())
 
 	req := validCreateRequest()
 	mockRepo.On("Create", mock.Anything, mock.Anything).Return(fmt.Errorf("db connection failed"))
@@ -232,33 +330,116 @@ func TestCreateStudent_RepositoryError(t *testing.T) {
 	mockRepo.AssertExpectations(t)
 }
 
+func TestCreateStudent_WithUniversities(t *testing.T) {
+	mockRepo := new(mocks.StudentRepository)
+	service := services.NewStudentService(mockRepo, shared.DefaultDateParser())
+
+	universityID := uuid.New()
+	degree := "Ingeniería de Sistemas"
+	year := 2018
+	req := validCreateRequest()
+	req.Universities = []models.StudentUniversityRequest{
+		{UniversityID: universityID.String(), DegreeObtained: &degree, GraduationYear: &year},
+	}
+	mockRepo.On("Create", mock.Anything, mock.Anything).Return(nil)
+
+	student, err := service.CreateStudent(context.Background(), req, nil)
+
+	assert.NoError(t, err)
+	assert.Len(t, student.Universities, 1)
+	assert.Equal(t, universityID, student.Universities[0].UniversityID)
+	assert.Equal(t, student.ID, student.Universities[0].StudentID)
+	assert.Equal(t, &year, student.Universities[0].GraduationYear)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestCreateStudent_InvalidGraduationYear(t *testing.T) {
+	mockRepo := new(mocks.StudentRepository)
+	service := services.NewStudentService(mockRepo, shared.DefaultDateParser())
+
+	year := time.Now().Year() + 1
+	req := validCreateRequest()
+	req.Universities = []models.StudentUniversityRequest{
+		{UniversityID: uuid.New().String(), GraduationYear: &year},
+	}
+
+	student, err := service.CreateStudent(context.Background(), req, nil)
+
+	assert.Error(t, err)
+	assert.Nil(t, student)
+	assert.Contains(t, err.Error(), "graduation_year")
+	mockRepo.AssertNotCalled(t, "Create")
+}
+
 // =============================================================================
 // GetStudent
 // =============================================================================
 
 func TestGetStudent_Success(t *testing.T) {
 	mockRepo := new(mocks.StudentRepository)
-	service := services.NewStudentService(mockRepo)
+	service := services.NewStudentService(mockRepo, shared.DefaultDateParser())
 
 	expected := sampleStudent()
+	universities := []models.StudentUniversity{{StudentID: expected.ID, UniversityID: uuid.New(), UniversityName: "Universidad de los Andes"}}
 	mockRepo.On("GetByID", mock.Anything, expected.ID).Return(expected, nil)
+	mockRepo.On("ListUniversities", mock.Anything, expected.ID).Return(universities, nil)
 
-	student, err := service.GetStudent(context.Background(), expected.ID)
+	student, err := service.GetStudent(context.Background(), expected.ID, services.StudentExpand{})
 
 	assert.NoError(t, err)
 	assert.Equal(t, expected.ID, student.ID)
 	assert.Equal(t, expected.FirstNames, student.FirstNames)
+	assert.Equal(t, universities, student.Universities)
 	mockRepo.AssertExpectations(t)
 }
 
+func TestGetStudent_Expanded(t *testing.T) {
+	mockRepo := new(mocks.StudentRepository)
+	service := services.NewStudentService(mockRepo, shared.DefaultDateParser())
+
+	expected := sampleStudent()
+	catalogs := &models.StudentCatalogs{Nationality: &models.CatalogEntry{ID: expected.NationalityCountryID, Name: "Colombia"}}
+	summary := &models.EnrollmentSummary{Total: 3, Completed: 2, Enrolled: 1, CreditsEarned: 6}
+	mockRepo.On("GetByID", mock.Anything, expected.ID).Return(expected, nil)
+	mockRepo.On("ListUniversities", mock.Anything, expected.ID).Return([]models.StudentUniversity{}, nil)
+	mockRepo.On("GetCatalogs", mock.Anything, expected.ID).Return(catalogs, nil)
+	mockRepo.On("GetEnrollmentSummary", mock.Anything, expected.ID).Return(summary, nil)
+
+	expand, err := services.ParseStudentExpand("catalogs, enrollments")
+	require.NoError(t, err)
+	student, err := service.GetStudent(context.Background(), expected.ID, expand)
+
+	require.NoError(t, err)
+	assert.Equal(t, catalogs, student.Catalogs)
+	assert.Equal(t, summary, student.EnrollmentSummary)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestParseStudentExpand(t *testing.T) {
+	expand, err := services.ParseStudentExpand("")
+	require.NoError(t, err)
+	assert.Equal(t, services.StudentExpand{}, expand)
+
+	expand, err = services.ParseStudentExpand("all")
+	require.NoError(t, err)
+	assert.Equal(t, services.StudentExpand{Catalogs: true, Enrollments: true}, expand)
+
+	_, err = services.ParseStudentExpand("catalogs,courses")
+	assert.ErrorContains(t, err, `invalid expand "courses"`)
+
+	// Universities are always included, so they are not an expand option
+	_, err = services.ParseStudentExpand("universities")
+	assert.ErrorContains(t, err, `invalid expand "universities"`)
+}
+
 func TestGetStudent_NotFound(t *testing.T) {
 	mockRepo := new(mocks.StudentRepository)
-	service := services.NewStudentService(mockRepo)
+	service := services.NewStudentService(mockRepo, shared.DefaultDateParser())
 
 	id := uuid.New()
 	mockRepo.On("GetByID", mock.Anything, id).Return(nil, fmt.Errorf("student not found"))
 
-	student, err := service.GetStudent(context.Background(), id)
+	student, err := service.GetStudent(context.Background(), id, services.StudentExpand{})
 
 	assert.Error(t, err)
 	assert.Nil(t, student)
@@ -271,51 +452,179 @@ func TestGetStudent_NotFound(t *testing.T) {
 
 func TestListStudents_Success(t *testing.T) {
 	mockRepo := new(mocks.StudentRepository)
-	service := services.NewStudentService(mockRepo)
+	service := services.NewStudentService(mockRepo, shared.DefaultDateParser())
 
 	filters := repositories.StudentFilters{Limit: 20, Offset: 0}
 	expected := []*models.Student{sampleStudent(), sampleStudent()}
 
-	mockRepo.On("List", mock.Anything, filters).Return(expected, nil)
+	mockRepo.On("List", mock.Anything, repositories.StudentFilters{Limit: 21}).Return(expected, nil)
 	mockRepo.On("Count", mock.Anything, filters).Return(2, nil)
 
-	students, total, err := service.ListStudents(context.Background(), filters)
+	page, err := service.ListStudents(context.Background(), filters, true)
 
 	assert.NoError(t, err)
-	assert.Len(t, students, 2)
-	assert.Equal(t, 2, total)
+	assert.Len(t, page.Students, 2)
+	assert.Empty(t, page.NextCursor)
+	require.NotNil(t, page.Total)
+	assert.Equal(t, 2, *page.Total)
 	mockRepo.AssertExpectations(t)
 }
 
 func TestListStudents_Empty(t *testing.T) {
 	mockRepo := new(mocks.StudentRepository)
-	service := services.NewStudentService(mockRepo)
+	service := services.NewStudentService(mockRepo, shared.DefaultDateParser())
 
 	filters := repositories.StudentFilters{Limit: 20, Offset: 0}
 
-	mockRepo.On("List", mock.Anything, filters).Return([]*models.Student{}, nil)
+	mockRepo.On("List", mock.Anything, repositories.StudentFilters{Limit: 21}).Return([]*models.Student{}, nil)
 	mockRepo.On("Count", mock.Anything, filters).Return(0, nil)
 
-	students, total, err := service.ListStudents(context.Background(), filters)
+	page, err := service.ListStudents(context.Background(), filters, true)
 
 	assert.NoError(t, err)
-	assert.Empty(t, students)
-	assert.Equal(t, 0, total)
+	assert.Empty(t, page.Students)
+	require.NotNil(t, page.Total)
+	assert.Equal(t, 0, *page.Total)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestListStudents_NextCursor(t *testing.T) {
+	mockRepo := new(mocks.StudentRepository)
+	service := services.NewStudentService(mockRepo, shared.DefaultDateParser())
+
+	students := []*models.Student{sampleStudent(), sampleStudent(), sampleStudent()}
+	mockRepo.On("List", mock.Anything, repositories.StudentFilters{Limit: 3}).Return(students, nil).Once()
+
+	page, err := service.ListStudents(context.Background(), repositories.StudentFilters{Limit: 2}, false)
+
+	require.NoError(t, err)
+	assert.Equal(t, students[:2], page.Students)
+	assert.Nil(t, page.Total)
+	mockRepo.AssertNotCalled(t, "Count", mock.Anything, mock.Anything)
+
+	// The cursor points at the last student of the page
+	after, err := repositories.DecodeStudentCursor(page.NextCursor)
+	require.NoError(t, err)
+	assert.Equal(t, students[1].ID, after.ID)
+	assert.Equal(t, "-enrollment_date", after.Sort)
+	assert.Equal(t, "2024-01-15", after.Value)
+
+	rest := []*models.Student{students[2]}
+	mockRepo.On("List", mock.Anything, repositories.StudentFilters{After: after, Limit: 3}).Return(rest, nil).Once()
+
+	page, err = service.ListStudents(context.Background(), repositories.StudentFilters{After: after, Limit: 2}, false)
+
+	require.NoError(t, err)
+	assert.Equal(t, rest, page.Students)
+	assert.Empty(t, page.NextCursor)
 	mockRepo.AssertExpectations(t)
 }
 
+func TestListStudents_CursorFollowsSort(t *testing.T) {
+	mockRepo := new(mocks.StudentRepository)
+	service := services.NewStudentService(mockRepo, shared.DefaultDateParser())
+
+	sort, err := repositories.ParseStudentSort("last_names")
+	require.NoError(t, err)
+	students := []*models.Student{sampleStudent(), sampleStudent()}
+	mockRepo.On("List", mock.Anything, repositories.StudentFilters{Sort: sort, Limit: 2}).Return(students, nil).Once()
+
+	page, err := service.ListStudents(context.Background(), repositories.StudentFilters{Sort: sort, Limit: 1}, false)
+
+	require.NoError(t, err)
+	after, err := repositories.DecodeStudentCursor(page.NextCursor)
+	require.NoError(t, err)
+	assert.Equal(t, "last_names", after.Sort)
+	assert.Equal(t, students[0].LastNames, after.Value)
+
+	// A cursor can't continue a listing in another order
+	assert.Error(t, repositories.StudentFilters{After: after}.Validate())
+	assert.NoError(t, repositories.StudentFilters{Sort: sort, After: after}.Validate())
+}
+
+func TestParseStudentSort(t *testing.T) {
+	sort, err := repositories.ParseStudentSort("")
+	require.NoError(t, err)
+	assert.Equal(t, "-enrollment_date", sort.String())
+
+	sort, err = repositories.ParseStudentSort("-cohort")
+	require.NoError(t, err)
+	assert.Equal(t, repositories.StudentSort{Column: "cohort", Desc: true}, sort)
+
+	_, err = repositories.ParseStudentSort("document_id")
+	assert.ErrorContains(t, err, "must be one of cohort, enrollment_date, first_names, last_names, relevance, status")
+}
+
+func TestListStudents_CursorByRelevance(t *testing.T) {
+	mockRepo := new(mocks.StudentRepository)
+	service := services.NewStudentService(mockRepo, shared.DefaultDateParser())
+
+	search := "jose perez"
+	sort, err := repositories.ParseStudentSort("relevance")
+	require.NoError(t, err)
+	assert.True(t, sort.Desc)
+
+	relevance := 0.8333333
+	first, second := sampleStudent(), sampleStudent()
+	first.Relevance = &relevance
+	filters := repositories.StudentFilters{Search: &search, Sort: sort, Limit: 1}
+	mockRepo.On("List", mock.Anything, repositories.StudentFilters{Search: &search, Sort: sort, Limit: 2}).
+		Return([]*models.Student{first, second}, nil).Once()
+
+	page, err := service.ListStudents(context.Background(), filters, false)
+
+	require.NoError(t, err)
+	after, err := repositories.DecodeStudentCursor(page.NextCursor)
+	require.NoError(t, err)
+	assert.Equal(t, "0.8333333", after.Value)
+
+	// Relevance is only defined for searches
+	assert.ErrorContains(t, repositories.StudentFilters{Sort: sort}.Validate(), "requires search")
+}
+
+func TestStudentFiltersValidate(t *testing.T) {
+	male, unknown := "M", "X"
+	from, to := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC), time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	minAge, maxAge := 40, 30
+
+	assert.NoError(t, repositories.StudentFilters{Statuses: []string{"active", "graduated"}, Gender: &male}.Validate())
+
+	tests := []struct {
+		name    string
+		filters repositories.StudentFilters
+		wantErr string
+	}{
+		{"unknown status", repositories.StudentFilters{Statuses: []string{"active", "expelled"}}, `invalid status "expelled"`},
+		{"unknown gender", repositories.StudentFilters{Gender: &unknown}, "invalid gender"},
+		{"inverted enrollment range", repositories.StudentFilters{EnrollmentFrom: &from, EnrollmentTo: &to}, "enrollment_from"},
+		{"inverted graduation range", repositories.StudentFilters{GraduationFrom: &from, GraduationTo: &to}, "graduation_from"},
+		{"inverted age range", repositories.StudentFilters{MinAge: &minAge, MaxAge: &maxAge}, "min_age"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.ErrorContains(t, tt.filters.Validate(), tt.wantErr)
+		})
+	}
+}
+
+func TestDecodeStudentCursor_Invalid(t *testing.T) {
+	for _, cursor := range []string{"not base64!", "bm90IGpzb24", "e30"} {
+		_, err := repositories.DecodeStudentCursor(cursor)
+		assert.Error(t, err, cursor)
+	}
+}
+
 func TestListStudents_ListError(t *testing.T) {
 	mockRepo := new(mocks.StudentRepository)
-	service := services.NewStudentService(mockRepo)
+	service := services.NewStudentService(mockRepo, shared.DefaultDateParser())
 
 	filters := repositories.StudentFilters{}
 	mockRepo.On("List", mock.Anything, filters).Return(nil, fmt.Errorf("db error"))
 
-	students, total, err := service.ListStudents(context.Background(), filters)
+	page, err := service.ListStudents(context.Background(), filters, true)
 
 	assert.Error(t, err)
-	assert.Nil(t, students)
-	assert.Equal(t, 0, total)
+	assert.Nil(t, page)
 	mockRepo.AssertExpectations(t)
 }
 
@@ -325,7 +634,7 @@ func TestListStudents_ListError(t *testing.T) {
 
 func TestUpdateStudent_Success(t *testing.T) {
 	mockRepo := new(mocks.StudentRepository)
-	service := services.NewStudentService(mockRepo)
+	service := services.NewStudentService(mockRepo, shared.DefaultDateParser())
 
 	existing := sampleStudent()
 	newFirstNames := "Juan Actualizado"
@@ -343,9 +652,31 @@ func TestUpdateStudent_Success(t *testing.T) {
 	mockRepo.AssertExpectations(t)
 }
 
+func TestUpdateStudent_ReplacesUniversities(t *testing.T) {
+	mockRepo := new(mocks.StudentRepository)
+	service := services.NewStudentService(mockRepo, shared.DefaultDateParser())
+
+	existing := sampleStudent()
+	universityID := uuid.New()
+	req := &models.UpdateStudentRequest{
+		Universities: []models.StudentUniversityRequest{{UniversityID: universityID.String()}},
+	}
+
+	mockRepo.On("GetByID", mock.Anything, existing.ID).Return(existing, nil)
+	mockRepo.On("Update", mock.Anything, mock.MatchedBy(func(s *models.Student) bool {
+		return len(s.Universities) == 1 && s.Universities[0].UniversityID == universityID
+	})).Return(nil)
+
+	student, err := service.UpdateStudent(context.Background(), existing.ID, req, nil)
+
+	assert.NoError(t, err)
+	assert.Len(t, student.Universities, 1)
+	mockRepo.AssertExpectations(t)
+}
+
 func TestUpdateStudent_NotFound(t *testing.T) {
 	mockRepo := new(mocks.StudentRepository)
-	service := services.NewStudentService(mockRepo)
+	service := services.NewStudentService(mockRepo, shared.DefaultDateParser())
 
 	id := uuid.New()
 	newName := "Inexistente"
@@ -362,7 +693,7 @@ func TestUpdateStudent_NotFound(t *testing.T) {
 
 func TestUpdateStudent_EmptyEmails(t *testing.T) {
 	mockRepo := new(mocks.StudentRepository)
-	service := services.NewStudentService(mockRepo)
+	service := services.NewStudentService(mockRepo, shared.DefaultDateParser())
 
 	existing := sampleStudent()
 	req := &models.UpdateStudentRequest{
@@ -380,7 +711,7 @@ func TestUpdateStudent_EmptyEmails(t *testing.T) {
 
 func TestUpdateStudent_WithStudentCode(t *testing.T) {
 	mockRepo := new(mocks.StudentRepository)
-	service := services.NewStudentService(mockRepo)
+	service := services.NewStudentService(mockRepo, shared.DefaultDateParser())
 
 	existing := sampleStudent()
 	code := "202510001"
@@ -400,7 +731,7 @@ func TestUpdateStudent_WithStudentCode(t *testing.T) {
 
 func TestUpdateStudent_InvalidStudentCode(t *testing.T) {
 	mockRepo := new(mocks.StudentRepository)
-	service := services.NewStudentService(mockRepo)
+	service := services.NewStudentService(mockRepo, shared.DefaultDateParser())
 
 	existing := sampleStudent()
 	badCode := "12345"
@@ -419,12 +750,12 @@ func TestUpdateStudent_InvalidStudentCode(t *testing.T) {
 
 func TestUpdateStudent_PartialFields(t *testing.T) {
 	mockRepo := new(mocks.StudentRepository)
-	service := services.NewStudentService(mockRepo)
+	service := services.NewStudentService(mockRepo, shared.DefaultDateParser())
 
 	existing := sampleStudent()
-	newStatus := "graduated"
+	sameStatus := "active"
 	req := &models.UpdateStudentRequest{
-		Status: &newStatus,
+		Status: &sameStatus,
 		Emails: []string{"new@email.com", "other@email.com"},
 	}
 
@@ -434,7 +765,7 @@ func TestUpdateStudent_PartialFields(t *testing.T) {
 	student, err := service.UpdateStudent(context.Background(), existing.ID, req, nil)
 
 	assert.NoError(t, err)
-	assert.Equal(t, models.StudentStatus("graduated"), student.Status)
+	assert.Equal(t, models.StudentStatusActive, student.Status)
 	assert.Equal(t, []string{"new@email.com", "other@email.com"}, student.Emails)
 	// Names should remain unchanged
 	assert.Equal(t, "Juan Carlos", student.FirstNames)
@@ -442,13 +773,31 @@ func TestUpdateStudent_PartialFields(t *testing.T) {
 	mockRepo.AssertExpectations(t)
 }
 
+func TestUpdateStudent_RejectsStatusChange(t *testing.T) {
+	mockRepo := new(mocks.StudentRepository)
+	service := services.NewStudentService(mockRepo, shared.DefaultDateParser())
+
+	existing := sampleStudent()
+	newStatus := "graduated"
+	req := &models.UpdateStudentRequest{Status: &newStatus}
+
+	mockRepo.On("GetByID", mock.Anything, existing.ID).Return(existing, nil)
+
+	student, err := service.UpdateStudent(context.Background(), existing.ID, req, nil)
+
+	assert.Error(t, err)
+	assert.Nil(t, student)
+	assert.Contains(t, err.Error(), "status change endpoint")
+	mockRepo.AssertNotCalled(t, "Update", mock.Anything, mock.Anything)
+}
+
 // =============================================================================
 // DeleteStudent
 // =============================================================================
 
 func TestDeleteStudent_Success(t *testing.T) {
 	mockRepo := new(mocks.StudentRepository)
-	service := services.NewStudentService(mockRepo)
+	service := services.NewStudentService(mockRepo, shared.DefaultDateParser())
 
 	id := uuid.New()
 	mockRepo.On("Delete", mock.Anything, id, (*uuid.UUID)(nil)).Return(nil)
@@ -461,7 +810,7 @@ func TestDeleteStudent_Success(t *testing.T) {
 
 func TestDeleteStudent_NotFound(t *testing.T) {
 	mockRepo := new(mocks.StudentRepository)
-	service := services.NewStudentService(mockRepo)
+	service := services.NewStudentService(mockRepo, shared.DefaultDateParser())
 
 	id := uuid.New()
 	mockRepo.On("Delete", mock.Anything, id, (*uuid.UUID)(nil)).Return(fmt.Errorf("student not found"))
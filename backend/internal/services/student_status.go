@@ -0,0 +1,132 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/dcorreal/coordinador/internal/models"
+	"github.com/dcorreal/coordinador/internal/shared"
+)
+
+// studentStatusTransitions lists the statuses each status can change to.
+// Withdrawn students can be readmitted and suspended ones reinstated; a
+// graduation can only be reverted, e.g. to correct a mistake.
+var studentStatusTransitions = map[models.StudentStatus][]models.StudentStatus{
+	models.StudentStatusActive:    {models.StudentStatusGraduated, models.StudentStatusSuspended, models.StudentStatusWithdrawn},
+	models.StudentStatusSuspended: {models.StudentStatusActive, models.StudentStatusWithdrawn},
+	models.StudentStatusWithdrawn: {models.StudentStatusActive},
+	models.StudentStatusGraduated: {models.StudentStatusActive},
+}
+
+// StudentStatusTransitionError reports a status change that the transition
+// rules don't allow.
+type StudentStatusTransitionError struct {
+	From models.StudentStatus
+	To   models.StudentStatus
+}
+
+func (e *StudentStatusTransitionError) Error() string {
+	allowed := make([]string, 0, len(studentStatusTransitions[e.From]))
+	for _, to := range studentStatusTransitions[e.From] {
+		allowed = append(allowed, string(to))
+	}
+	return fmt.Sprintf("a %s student can't become %s, only %s", e.From, e.To, strings.Join(allowed, ", "))
+}
+
+// canChangeStatus reports whether a student can go from one status to another.
+func canChangeStatus(from, to models.StudentStatus) bool {
+	for _, allowed := range studentStatusTransitions[from] {
+		if allowed == to {
+			return true
+		}
+	}
+	return false
+}
+
+// statusChangeNeedsReason reports whether a change must be justified: leaving
+// the program, a suspension and reverting a graduation.
+func statusChangeNeedsReason(from, to models.StudentStatus) bool {
+	return to == models.StudentStatusWithdrawn || to == models.StudentStatusSuspended || from == models.StudentStatusGraduated
+}
+
+// ChangeStudentStatus moves a student to another status following the
+// transition rules and records the change in the status history. Graduating
+// sets the graduation date to the effective date; reverting it clears it. The
+// repository rejects changes dated before the last one.
+func (s *studentService) ChangeStudentStatus(ctx context.Context, id uuid.UUID, req *models.ChangeStudentStatusRequest, changedBy *uuid.UUID) (*models.Student, error) {
+	student, err := s.studentRepo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	to := models.StudentStatus(req.Status)
+	if _, ok := studentStatusTransitions[to]; !ok {
+		return nil, fmt.Errorf("invalid status %q, must be active, graduated, withdrawn or suspended", req.Status)
+	}
+	if to == student.Status {
+		return nil, fmt.Errorf("student is already %s", to)
+	}
+	if !canChangeStatus(student.Status, to) {
+		return nil, &StudentStatusTransitionError{From: student.Status, To: to}
+	}
+
+	var reason *string
+	if req.Reason != nil {
+		if trimmed := strings.TrimSpace(*req.Reason); trimmed != "" {
+			reason = &trimmed
+		}
+	}
+	if reason == nil && statusChangeNeedsReason(student.Status, to) {
+		return nil, fmt.Errorf("a reason is required to change a %s student to %s", student.Status, to)
+	}
+
+	today := shared.Today()
+	effectiveDate := today
+	if req.EffectiveDate != "" {
+		effectiveDate, err = s.dates.Parse(req.EffectiveDate)
+		if err != nil {
+			return nil, fmt.Errorf("invalid effective_date: %w", err)
+		}
+	}
+	if effectiveDate.After(today) {
+		return nil, fmt.Errorf("effective_date can't be in the future")
+	}
+	if effectiveDate.Before(student.EnrollmentDate) {
+		return nil, fmt.Errorf("effective_date can't be before the enrollment date %s", student.EnrollmentDate.Format(isoDateLayout))
+	}
+
+	var graduationDate *time.Time
+	if to == models.StudentStatusGraduated {
+		graduationDate = &effectiveDate
+	}
+
+	change := &models.StudentStatusChange{
+		ID:            uuid.New(),
+		StudentID:     id,
+		FromStatus:    student.Status,
+		ToStatus:      to,
+		Reason:        reason,
+		EffectiveDate: effectiveDate,
+		CreatedBy:     changedBy,
+	}
+	if err := s.studentRepo.ChangeStatus(ctx, change, graduationDate); err != nil {
+		return nil, fmt.Errorf("failed to change student status: %w", err)
+	}
+
+	student.Status = to
+	student.GraduationDate = graduationDate
+	student.UpdatedBy = changedBy
+	return student, nil
+}
+
+// ListStatusHistory returns the status changes of a student, latest first.
+func (s *studentService) ListStatusHistory(ctx context.Context, id uuid.UUID) ([]models.StudentStatusChange, error) {
+	if _, err := s.studentRepo.GetByID(ctx, id); err != nil {
+		return nil, err
+	}
+	return s.studentRepo.ListStatusHistory(ctx, id)
+}
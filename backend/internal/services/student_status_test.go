@@ -0,0 +1,149 @@
+package services_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dcorreal/coordinador/internal/models"
+	"github.com/dcorreal/coordinador/internal/repositories/mocks"
+	"github.com/dcorreal/coordinador/internal/services"
+	"github.com/dcorreal/coordinador/internal/shared"
+)
+
+func TestChangeStudentStatus_GraduationSetsDate(t *testing.T) {
+	mockRepo := new(mocks.StudentRepository)
+	service := services.NewStudentService(mockRepo, shared.DefaultDateParser())
+
+	existing := sampleStudent()
+	graduated := time.Date(2025, 12, 5, 0, 0, 0, 0, time.UTC)
+	req := &models.ChangeStudentStatusRequest{Status: "graduated", EffectiveDate: "2025-12-05"}
+
+	mockRepo.On("GetByID", mock.Anything, existing.ID).Return(existing, nil)
+	mockRepo.On("ChangeStatus", mock.Anything, mock.MatchedBy(func(c *models.StudentStatusChange) bool {
+		return c.StudentID == existing.ID && c.FromStatus == models.StudentStatusActive &&
+			c.ToStatus == models.StudentStatusGraduated && c.EffectiveDate.Equal(graduated) && c.Reason == nil
+	}), &graduated).Return(nil)
+
+	student, err := service.ChangeStudentStatus(context.Background(), existing.ID, req, nil)
+
+	require.NoError(t, err)
+	assert.Equal(t, models.StudentStatusGraduated, student.Status)
+	require.NotNil(t, student.GraduationDate)
+	assert.True(t, student.GraduationDate.Equal(graduated))
+	mockRepo.AssertExpectations(t)
+}
+
+func TestChangeStudentStatus_RevertGraduationClearsDate(t *testing.T) {
+	mockRepo := new(mocks.StudentRepository)
+	service := services.NewStudentService(mockRepo, shared.DefaultDateParser())
+
+	existing := sampleStudent()
+	graduated := time.Date(2025, 12, 5, 0, 0, 0, 0, time.UTC)
+	existing.Status = models.StudentStatusGraduated
+	existing.GraduationDate = &graduated
+	reason := "  Graduado por error  "
+	req := &models.ChangeStudentStatusRequest{Status: "active", Reason: &reason}
+
+	mockRepo.On("GetByID", mock.Anything, existing.ID).Return(existing, nil)
+	mockRepo.On("ChangeStatus", mock.Anything, mock.MatchedBy(func(c *models.StudentStatusChange) bool {
+		return c.Reason != nil && *c.Reason == "Graduado por error"
+	}), (*time.Time)(nil)).Return(nil)
+
+	student, err := service.ChangeStudentStatus(context.Background(), existing.ID, req, nil)
+
+	require.NoError(t, err)
+	assert.Equal(t, models.StudentStatusActive, student.Status)
+	assert.Nil(t, student.GraduationDate)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestChangeStudentStatus_DisallowedTransition(t *testing.T) {
+	mockRepo := new(mocks.StudentRepository)
+	service := services.NewStudentService(mockRepo, shared.DefaultDateParser())
+
+	existing := sampleStudent()
+	existing.Status = models.StudentStatusWithdrawn
+	req := &models.ChangeStudentStatusRequest{Status: "graduated"}
+
+	mockRepo.On("GetByID", mock.Anything, existing.ID).Return(existing, nil)
+
+	student, err := service.ChangeStudentStatus(context.Background(), existing.ID, req, nil)
+
+	assert.Nil(t, student)
+	var transition *services.StudentStatusTransitionError
+	require.True(t, errors.As(err, &transition))
+	assert.Equal(t, models.StudentStatusWithdrawn, transition.From)
+	assert.EqualError(t, err, "a withdrawn student can't become graduated, only active")
+	mockRepo.AssertNotCalled(t, "ChangeStatus", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestChangeStudentStatus_Validation(t *testing.T) {
+	blank := "   "
+	tests := []struct {
+		name    string
+		req     models.ChangeStudentStatusRequest
+		wantErr string
+	}{
+		{"same status", models.ChangeStudentStatusRequest{Status: "active"}, "already active"},
+		{"unknown status", models.ChangeStudentStatusRequest{Status: "expelled"}, `invalid status "expelled"`},
+		{"withdrawal without reason", models.ChangeStudentStatusRequest{Status: "withdrawn", Reason: &blank}, "a reason is required"},
+		{"future date", models.ChangeStudentStatusRequest{Status: "graduated", EffectiveDate: time.Now().AddDate(0, 0, 2).Format("2006-01-02")}, "in the future"},
+		{"before enrollment", models.ChangeStudentStatusRequest{Status: "graduated", EffectiveDate: "2023-12-31"}, "before the enrollment date 2024-01-15"},
+		{"invalid date", models.ChangeStudentStatusRequest{Status: "graduated", EffectiveDate: "mañana"}, "invalid effective_date"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := new(mocks.StudentRepository)
+			service := services.NewStudentService(mockRepo, shared.DefaultDateParser())
+
+			existing := sampleStudent()
+			mockRepo.On("GetByID", mock.Anything, existing.ID).Return(existing, nil)
+
+			student, err := service.ChangeStudentStatus(context.Background(), existing.ID, &tt.req, nil)
+
+			assert.Nil(t, student)
+			assert.ErrorContains(t, err, tt.wantErr)
+			mockRepo.AssertNotCalled(t, "ChangeStatus", mock.Anything, mock.Anything, mock.Anything)
+		})
+	}
+}
+
+func TestChangeStudentStatus_BeforeLastChange(t *testing.T) {
+	mockRepo := new(mocks.StudentRepository)
+	service := services.NewStudentService(mockRepo, shared.DefaultDateParser())
+
+	existing := sampleStudent()
+	req := &models.ChangeStudentStatusRequest{Status: "graduated", EffectiveDate: "2025-02-01"}
+
+	mockRepo.On("GetByID", mock.Anything, existing.ID).Return(existing, nil)
+	mockRepo.On("ChangeStatus", mock.Anything, mock.Anything, mock.Anything).
+		Return(errors.New("effective_date can't be before the last status change on 2025-03-01"))
+
+	student, err := service.ChangeStudentStatus(context.Background(), existing.ID, req, nil)
+
+	assert.Nil(t, student)
+	assert.ErrorContains(t, err, "before the last status change on 2025-03-01")
+	mockRepo.AssertNotCalled(t, "ListStatusHistory", mock.Anything, mock.Anything)
+}
+
+func TestListStatusHistory_NotFound(t *testing.T) {
+	mockRepo := new(mocks.StudentRepository)
+	service := services.NewStudentService(mockRepo, shared.DefaultDateParser())
+
+	id := uuid.New()
+	mockRepo.On("GetByID", mock.Anything, id).Return(nil, errors.New("student not found"))
+
+	history, err := service.ListStatusHistory(context.Background(), id)
+
+	assert.Nil(t, history)
+	assert.ErrorContains(t, err, "student not found")
+	mockRepo.AssertNotCalled(t, "ListStatusHistory", mock.Anything, mock.Anything)
+}
@@ -0,0 +1,69 @@
+package shared
+
+import (
+	_ "embed"
+	"encoding/csv"
+	"fmt"
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// ISOCountry is a country of ISO 3166-1.
+type ISOCountry struct {
+	Alpha2 string
+	Alpha3 string
+	NameEN string
+	NameES string
+}
+
+//go:embed iso3166.csv
+var iso3166CSV string
+
+// isoCountries indexes the ISO 3166-1 countries by normalized Spanish and English
+// name, common alternative names and alpha-2 and alpha-3 codes.
+var isoCountries = loadISOCountries()
+
+func loadISOCountries() map[string]ISOCountry {
+	records, err := csv.NewReader(strings.NewReader(iso3166CSV)).ReadAll()
+	if err != nil {
+		panic(fmt.Sprintf("invalid iso3166.csv: %v", err))
+	}
+
+	index := make(map[string]ISOCountry, len(records)*4)
+	for _, record := range records[1:] {
+		country := ISOCountry{Alpha2: record[0], Alpha3: record[1], NameEN: record[2], NameES: record[3]}
+		keys := []string{country.Alpha2, country.Alpha3, country.NameEN, country.NameES}
+		if record[4] != "" {
+			keys = append(keys, strings.Split(record[4], "|")...)
+		}
+		for _, key := range keys {
+			key = countryKey(key)
+			if other, ok := index[key]; ok && other != country {
+				panic(fmt.Sprintf("invalid iso3166.csv: %q names both %s and %s", key, other.Alpha3, country.Alpha3))
+			}
+			index[key] = country
+		}
+	}
+	return index
+}
+
+// LookupCountry finds an ISO 3166-1 country by its Spanish or English name, a
+// common alternative name (e.g. "EE.UU.") or its alpha-2 or alpha-3 code. Case,
+// accents, spaces and punctuation are ignored.
+func LookupCountry(name string) (ISOCountry, bool) {
+	country, ok := isoCountries[countryKey(name)]
+	return country, ok
+}
+
+// countryKey keeps only the unaccented letters and digits of name, in lower case.
+func countryKey(name string) string {
+	var b strings.Builder
+	for _, r := range norm.NFD.String(name) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			b.WriteRune(unicode.ToLower(r))
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,157 @@
+package shared
+
+import (
+	"fmt"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultDateFormats are the date formats accepted when none are configured:
+// ISO dates and the dd/mm/yyyy format used by registrar exports.
+var DefaultDateFormats = []string{"YYYY-MM-DD", "DD/MM/YYYY"}
+
+// excelEpoch is day zero of Excel's 1900 date system. Starting on 1899-12-30
+// compensates for Excel treating 1900 as a leap year, so serials from 61
+// (1900-03-01) onwards map to the right day.
+var excelEpoch = time.Date(1899, 12, 30, 0, 0, 0, 0, time.UTC)
+
+const (
+	minExcelSerial = 61      // 1900-03-01
+	maxExcelSerial = 2958465 // 9999-12-31
+)
+
+// DateParser parses calendar dates written in any of a set of formats.
+// Formats use YYYY, YY, MM, M, DD and D tokens (e.g. "DD/MM/YYYY"); day and
+// month accept one or two digits whichever token is used.
+type DateParser struct {
+	formats []string
+	layouts []string
+}
+
+// NewDateParser creates a DateParser for the given formats, or DefaultDateFormats
+// when none are given (blank entries are ignored). ISO dates (YYYY-MM-DD) are always accepted.
+func NewDateParser(formats ...string) (*DateParser, error) {
+	var cleaned []string
+	for _, format := range formats {
+		if format = strings.ToUpper(strings.TrimSpace(format)); format != "" {
+			cleaned = append(cleaned, format)
+		}
+	}
+	if len(cleaned) == 0 {
+		cleaned = DefaultDateFormats
+	}
+
+	p := &DateParser{}
+	for _, format := range cleaned {
+		layout, err := dateLayout(format)
+		if err != nil {
+			return nil, err
+		}
+		p.formats = append(p.formats, format)
+		p.layouts = append(p.layouts, layout)
+	}
+	if !slices.Contains(p.formats, "YYYY-MM-DD") {
+		p.formats = append(p.formats, "YYYY-MM-DD")
+		p.layouts = append(p.layouts, "2006-1-2")
+	}
+	return p, nil
+}
+
+// Today returns the current date in UTC as midnight UTC, the same form in which
+// DateParser returns calendar dates, so they can be compared directly.
+func Today() time.Time {
+	now := time.Now().UTC()
+	return time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// DefaultDateParser returns a DateParser for DefaultDateFormats.
+func DefaultDateParser() *DateParser {
+	p, err := NewDateParser()
+	if err != nil {
+		panic(err)
+	}
+	return p
+}
+
+// Formats returns the accepted formats, for help texts and error messages.
+func (p *DateParser) Formats() []string {
+	return p.formats
+}
+
+// Parse parses value with the first accepted format that matches it.
+func (p *DateParser) Parse(value string) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	for _, layout := range p.layouts {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid date %q, expected %s", value, strings.Join(p.formats, " or "))
+}
+
+// ParseCell parses a spreadsheet cell: either a date in an accepted format or an
+// Excel serial date number (e.g. 45310 for 2024-01-20).
+func (p *DateParser) ParseCell(value string) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	if serial, err := strconv.ParseFloat(value, 64); err == nil {
+		return excelSerialDate(serial)
+	}
+	return p.Parse(value)
+}
+
+func excelSerialDate(serial float64) (time.Time, error) {
+	days := math.Floor(serial)
+	if days < minExcelSerial || days > maxExcelSerial {
+		return time.Time{}, fmt.Errorf("invalid date %v, Excel serial dates must be between %d and %d", serial, minExcelSerial, maxExcelSerial)
+	}
+	return excelEpoch.AddDate(0, 0, int(days)), nil
+}
+
+// dateLayout converts a format such as "DD/MM/YYYY" into a time.Parse layout.
+func dateLayout(format string) (string, error) {
+	tokens := map[string]string{
+		"YYYY": "2006",
+		"YY":   "06",
+		"MM":   "1",
+		"M":    "1",
+		"DD":   "2",
+		"D":    "2",
+	}
+
+	var layout strings.Builder
+	seen := map[byte]bool{}
+	for i := 0; i < len(format); {
+		c := format[i]
+		if c != 'Y' && c != 'M' && c != 'D' {
+			if strings.IndexByte("/-. ", c) < 0 {
+				return "", fmt.Errorf("invalid date format %q: unexpected %q", format, c)
+			}
+			layout.WriteByte(c)
+			i++
+			continue
+		}
+
+		j := i
+		for j < len(format) && format[j] == c {
+			j++
+		}
+		replacement, ok := tokens[format[i:j]]
+		if !ok {
+			return "", fmt.Errorf("invalid date format %q: unknown token %q", format, format[i:j])
+		}
+		if seen[c] {
+			return "", fmt.Errorf("invalid date format %q: %c appears twice", format, c)
+		}
+		seen[c] = true
+		layout.WriteString(replacement)
+		i = j
+	}
+
+	if !seen['Y'] || !seen['M'] || !seen['D'] {
+		return "", fmt.Errorf("invalid date format %q: expected year, month and day", format)
+	}
+	return layout.String(), nil
+}
@@ -10,12 +10,14 @@ type APIResponse struct {
 	Error   *string     `json:"error,omitempty"`
 }
 
-// PaginatedData wraps a list with pagination metadata.
+// PaginatedData wraps a list with pagination metadata. Lists paged by cursor
+// return NextCursor, and may leave Total out when it wasn't counted.
 type PaginatedData struct {
-	Items  interface{} `json:"items"`
-	Total  int         `json:"total"`
-	Limit  int         `json:"limit"`
-	Offset int         `json:"offset"`
+	Items      interface{} `json:"items"`
+	Total      *int        `json:"total,omitempty"`
+	Limit      int         `json:"limit"`
+	Offset     int         `json:"offset"`
+	NextCursor string      `json:"next_cursor,omitempty"`
 }
 
 // SuccessResponse sends a successful JSON response.
@@ -44,7 +46,7 @@ func PaginatedResponse(c *fiber.Ctx, status int, message string, items interface
 		Message: message,
 		Data: PaginatedData{
 			Items:  items,
-			Total:  total,
+			Total:  &total,
 			Limit:  limit,
 			Offset: offset,
 		},
@@ -0,0 +1,2 @@
+-- Migration 014: Agregar extensión pg_trgm para sugerir entradas de catálogo similares
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
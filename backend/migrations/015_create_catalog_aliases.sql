@@ -0,0 +1,22 @@
+-- Migration 015: Alias de catálogo para consolidar variantes de nombre
+-- (ej: "EE.UU." y "USA" -> Estados Unidos, "Bogota" -> Bogotá D.C.)
+
+BEGIN;
+
+CREATE TABLE catalog_aliases (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    catalog VARCHAR(50) NOT NULL CHECK (catalog IN ('countries', 'cities', 'professions', 'job_title_categories', 'companies', 'universities')),
+    entry_id UUID NOT NULL,
+    country_id UUID REFERENCES countries(id) ON DELETE CASCADE,
+    alias VARCHAR(255) NOT NULL,
+    created_at TIMESTAMP DEFAULT NOW()
+);
+
+COMMENT ON TABLE catalog_aliases IS 'Nombres alternativos que resuelven a una entrada canónica de un catálogo';
+COMMENT ON COLUMN catalog_aliases.entry_id IS 'ID de la entrada en la tabla del catálogo';
+COMMENT ON COLUMN catalog_aliases.country_id IS 'País de la entrada para ciudades y universidades, cuyos nombres son únicos por país';
+
+CREATE UNIQUE INDEX uk_catalog_alias ON catalog_aliases (catalog, country_id, LOWER(TRIM(alias))) NULLS NOT DISTINCT;
+CREATE INDEX idx_catalog_aliases_entry ON catalog_aliases (catalog, entry_id);
+
+COMMIT;
@@ -0,0 +1,68 @@
+-- Migration 016: Unicidad de nombres de catálogo sin distinguir mayúsculas, acentos ni espacios
+-- Permite crear entradas con INSERT ... ON CONFLICT sin que dos importaciones
+-- simultáneas creen "Bogotá" y "bogota" o fallen con uk_city_country.
+-- Si una tabla ya tiene duplicados bajo la nueva clave, su índice no se crea y los
+-- duplicados se reportan como WARNING, para que la migración no falle. Después de
+-- fusionarlos (POST /api/v1/catalogs/:catalog/merge) se crean los índices pendientes con
+-- SELECT ensure_catalog_name_key_indexes(); (ver README de migraciones).
+
+BEGIN;
+
+-- unaccent() no es IMMUTABLE porque depende del diccionario por defecto; fijarlo permite indexarla
+CREATE OR REPLACE FUNCTION catalog_name_key(name TEXT)
+RETURNS TEXT AS $$
+    SELECT LOWER(public.unaccent('public.unaccent'::regdictionary, TRIM(name)))
+$$ LANGUAGE sql IMMUTABLE STRICT PARALLEL SAFE;
+
+COMMENT ON FUNCTION catalog_name_key(TEXT) IS 'Clave de comparación de nombres de catálogo: sin espacios extremos, acentos ni mayúsculas';
+
+-- Crea los índices únicos que falten en las tablas sin duplicados y devuelve
+-- cuántas tablas quedan pendientes por tener duplicados
+CREATE OR REPLACE FUNCTION ensure_catalog_name_key_indexes()
+RETURNS INTEGER AS $$
+DECLARE
+    spec RECORD;
+    dup RECORD;
+    has_duplicates BOOLEAN;
+    pending INTEGER := 0;
+BEGIN
+    FOR spec IN
+        SELECT * FROM (VALUES
+            ('countries', 'uk_countries_name_key', 'catalog_name_key(name)'),
+            ('cities', 'uk_cities_name_key', 'catalog_name_key(name), country_id'),
+            ('professions', 'uk_professions_name_key', 'catalog_name_key(name)'),
+            ('job_title_categories', 'uk_job_title_categories_name_key', 'catalog_name_key(name)'),
+            ('companies', 'uk_companies_name_key', 'catalog_name_key(name)'),
+            ('universities', 'uk_universities_name_key', 'catalog_name_key(name), country_id')
+        ) AS t(table_name, index_name, columns)
+    LOOP
+        IF to_regclass(spec.index_name) IS NOT NULL THEN
+            CONTINUE;
+        END IF;
+
+        has_duplicates := FALSE;
+        FOR dup IN EXECUTE format(
+            'SELECT string_agg(name || '' ('' || id || '')'', '', '' ORDER BY name) AS entries
+             FROM %I GROUP BY %s HAVING COUNT(*) > 1', spec.table_name, spec.columns)
+        LOOP
+            has_duplicates := TRUE;
+            RAISE WARNING 'Duplicados en %: %', spec.table_name, dup.entries;
+        END LOOP;
+
+        IF has_duplicates THEN
+            RAISE WARNING 'Índice % pendiente: fusionar los duplicados de % y volver a ejecutar ensure_catalog_name_key_indexes()',
+                spec.index_name, spec.table_name;
+            pending := pending + 1;
+        ELSE
+            EXECUTE format('CREATE UNIQUE INDEX %I ON %I (%s)', spec.index_name, spec.table_name, spec.columns);
+        END IF;
+    END LOOP;
+    RETURN pending;
+END;
+$$ LANGUAGE plpgsql;
+
+COMMENT ON FUNCTION ensure_catalog_name_key_indexes() IS 'Crea los índices únicos por catalog_name_key de los catálogos sin duplicados; devuelve las tablas pendientes';
+
+SELECT ensure_catalog_name_key_indexes();
+
+COMMIT;
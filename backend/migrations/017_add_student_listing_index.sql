@@ -0,0 +1,4 @@
+-- Migration 017: Índice para paginar el listado de estudiantes por cursor
+-- El listado ordena por enrollment_date DESC, id DESC y continúa desde el último
+-- estudiante de la página anterior en lugar de usar OFFSET.
+CREATE INDEX idx_students_enrollment_date_id ON students(enrollment_date DESC, id DESC) WHERE deleted_at IS NULL;
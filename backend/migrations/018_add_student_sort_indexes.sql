@@ -0,0 +1,3 @@
+-- Migration 018: Índices para ordenar y filtrar el listado de estudiantes
+CREATE INDEX idx_students_last_names_id ON students(last_names, id) WHERE deleted_at IS NULL;
+CREATE INDEX idx_students_graduation_date ON students(graduation_date) WHERE deleted_at IS NULL;
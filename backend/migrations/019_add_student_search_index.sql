@@ -0,0 +1,18 @@
+-- Migration 019: Búsqueda de estudiantes insensible a acentos y mayúsculas
+-- Cada palabra buscada debe aparecer en el nombre completo, documento, código o
+-- correos; el índice de trigramas acelera los LIKE '%palabra%' y ordena por relevancia.
+
+BEGIN;
+
+CREATE OR REPLACE FUNCTION student_search_document(first_names TEXT, last_names TEXT, document_id TEXT, student_code TEXT, emails TEXT[])
+RETURNS TEXT AS $$
+    SELECT catalog_name_key(concat_ws(' ', first_names, last_names, document_id, student_code, array_to_string(emails, ' ')))
+$$ LANGUAGE sql IMMUTABLE PARALLEL SAFE;
+
+COMMENT ON FUNCTION student_search_document(TEXT, TEXT, TEXT, TEXT, TEXT[]) IS 'Texto normalizado (sin acentos ni mayúsculas) sobre el que se buscan estudiantes';
+
+CREATE INDEX idx_students_search_trgm ON students
+    USING gin (student_search_document(first_names, last_names, document_id, student_code, emails) gin_trgm_ops)
+    WHERE deleted_at IS NULL;
+
+COMMIT;
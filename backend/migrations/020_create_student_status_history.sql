@@ -0,0 +1,24 @@
+-- Migration 020: Historial de cambios de estado de los estudiantes
+-- Cada transición (ej: active -> suspended) queda registrada con su motivo y la
+-- fecha en que tiene efecto, que puede ser anterior a la fecha de registro.
+
+BEGIN;
+
+CREATE TABLE student_status_history (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    student_id UUID NOT NULL REFERENCES students(id) ON DELETE CASCADE,
+    from_status VARCHAR(20) NOT NULL CHECK (from_status IN ('active', 'graduated', 'withdrawn', 'suspended')),
+    to_status VARCHAR(20) NOT NULL CHECK (to_status IN ('active', 'graduated', 'withdrawn', 'suspended')),
+    reason TEXT,
+    effective_date DATE NOT NULL,
+    created_at TIMESTAMP DEFAULT NOW(),
+    created_by UUID REFERENCES system_users(id) ON DELETE SET NULL,
+    CONSTRAINT chk_status_changed CHECK (from_status <> to_status)
+);
+
+COMMENT ON TABLE student_status_history IS 'Transiciones de estado de los estudiantes con motivo y fecha efectiva';
+COMMENT ON COLUMN student_status_history.effective_date IS 'Fecha en que el cambio tiene efecto; al graduarse es la fecha de grado';
+
+CREATE INDEX idx_student_status_history_student ON student_status_history(student_id, effective_date DESC, created_at DESC);
+
+COMMIT;
@@ -0,0 +1,36 @@
+-- Migration 021: Unicidad de alias con la misma clave que los nombres de catálogo
+-- uk_catalog_alias comparaba LOWER(TRIM(alias)), pero las búsquedas ignoran acentos,
+-- así que "Bogota" y "Bogotá" podían apuntar a entradas distintas. Se usa
+-- catalog_name_key (migración 016) en el índice y en todas las comparaciones.
+
+BEGIN;
+
+-- Los alias repetidos bajo la nueva clave se reportan y se conserva el más antiguo
+DO $$
+DECLARE
+    dup RECORD;
+BEGIN
+    FOR dup IN
+        SELECT a.catalog, a.alias, b.alias AS kept
+        FROM catalog_aliases a
+        JOIN catalog_aliases b
+          ON b.catalog = a.catalog
+         AND b.country_id IS NOT DISTINCT FROM a.country_id
+         AND catalog_name_key(b.alias) = catalog_name_key(a.alias)
+         AND (b.created_at, b.id) < (a.created_at, a.id)
+    LOOP
+        RAISE NOTICE 'Alias % "%" eliminado: repite "%"', dup.catalog, dup.alias, dup.kept;
+    END LOOP;
+END $$;
+
+DELETE FROM catalog_aliases a
+USING catalog_aliases b
+WHERE b.catalog = a.catalog
+  AND b.country_id IS NOT DISTINCT FROM a.country_id
+  AND catalog_name_key(b.alias) = catalog_name_key(a.alias)
+  AND (b.created_at, b.id) < (a.created_at, a.id);
+
+DROP INDEX uk_catalog_alias;
+CREATE UNIQUE INDEX uk_catalog_alias ON catalog_aliases (catalog, country_id, catalog_name_key(alias)) NULLS NOT DISTINCT;
+
+COMMIT;